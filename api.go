@@ -0,0 +1,143 @@
+package symb
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// IterateExportedDecls calls visitf for the declaration of each name in
+// the exported API of the package made up of files, following the go doc
+// convention: exported constants, variables, types, and functions;
+// exported methods of exported types; and the exported fields and
+// interface methods of exported types (including those of struct and
+// interface types nested in their fields). Methods of unexported types and
+// unexported methods and fields of exported types are not part of the API.
+// References, the package clause, parameters, and everything in function
+// bodies are not emitted, and function bodies are neither type-checked
+// nor walked, so the cost depends on the size of the API rather than of
+// the package. If visitf returns false, the iteration stops.
+//
+// The symbs are emitted in order of filename and position, like those of
+// IterateSymbs, and have the same fields as they would have there.
+func (ctxt *Context) IterateExportedDecls(importPath string, files []*ast.File, visitf func(symb *Symb) bool) error {
+	return ctxt.iterate(&checkJob{importPath: importPath, files: ctxt.sortFiles(files), exportedOnly: true}, visitf)
+}
+
+// visitExportedDecls emits the exported declarations of file for
+// IterateExportedDecls. It returns false if visitf stopped the iteration.
+func (ctxt *Context) visitExportedDecls(file *ast.File, visitf func(*Symb) bool) bool {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !ast.IsExported(decl.Name.Name) {
+				continue
+			}
+			var e ast.Expr = decl.Name
+			if decl.Recv != nil {
+				if len(decl.Recv.List) != 1 {
+					ctxt.errorf(Unsupported, decl.Pos(), "expected one receiver only!")
+					continue
+				}
+				recv := decl.Recv.List[0].Type
+				if star, isStar := recv.(*ast.StarExpr); isStar {
+					recv = star.X
+				}
+				if id, isIdent := recv.(*ast.Ident); !isIdent || !ast.IsExported(id.Name) {
+					continue
+				}
+				e = &ast.SelectorExpr{X: decl.Recv.List[0].Type, Sel: decl.Name}
+			}
+			if !ctxt.visitExpr(e, false, visitf) {
+				return false
+			}
+
+		case *ast.GenDecl:
+			switch decl.Tok {
+			case token.CONST:
+				ctxt.addConstSpecs(decl)
+			case token.VAR:
+				for _, spec := range decl.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						ctxt.declForms[name] = VarKeyword
+					}
+				}
+			}
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if ast.IsExported(name.Name) && !ctxt.visitExpr(name, false, visitf) {
+							return false
+						}
+					}
+				case *ast.TypeSpec:
+					if !ast.IsExported(spec.Name.Name) {
+						continue
+					}
+					if !ctxt.visitExpr(spec.Name, false, visitf) || !ctxt.visitExportedMembers(spec.Type, visitf) {
+						return false
+					}
+				}
+			}
+		}
+	}
+	return true
+}
+
+// visitExportedMembers emits the declarations of the exported fields and
+// methods of the struct and interface types in t, a type expression that
+// is part of an exported API, including those in the types of the fields
+// themselves. It returns false if visitf stopped the iteration.
+func (ctxt *Context) visitExportedMembers(t ast.Expr, visitf func(*Symb) bool) bool {
+	var fields *ast.FieldList
+	switch t := t.(type) {
+	case *ast.ParenExpr:
+		return ctxt.visitExportedMembers(t.X, visitf)
+	case *ast.StarExpr:
+		return ctxt.visitExportedMembers(t.X, visitf)
+	case *ast.ArrayType:
+		return ctxt.visitExportedMembers(t.Elt, visitf)
+	case *ast.ChanType:
+		return ctxt.visitExportedMembers(t.Value, visitf)
+	case *ast.MapType:
+		return ctxt.visitExportedMembers(t.Key, visitf) && ctxt.visitExportedMembers(t.Value, visitf)
+	case *ast.StructType:
+		ctxt.addFieldTags(t)
+		ctxt.addEmbeds(t.Fields)
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields = t.Methods
+	default:
+		return true
+	}
+	for _, field := range fields.List {
+		if len(field.Names) == 0 {
+			// An embedded struct field declares a field named after
+			// its type; an embedded interface declares nothing.
+			if _, isStruct := t.(*ast.StructType); !isStruct {
+				continue
+			}
+			e := field.Type
+			if star, isStar := e.(*ast.StarExpr); isStar {
+				e = star.X
+			}
+			if id := embeddedFieldName(e); id != nil && ast.IsExported(id.Name) && !ctxt.visitExpr(e, false, visitf) {
+				return false
+			}
+			continue
+		}
+		exported := false
+		for _, name := range field.Names {
+			if ast.IsExported(name.Name) {
+				exported = true
+				if !ctxt.visitExpr(name, false, visitf) {
+					return false
+				}
+			}
+		}
+		if exported && !ctxt.visitExportedMembers(field.Type, visitf) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package symb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestIterateExportedDecls(t *testing.T) {
+	pkg, err := parseTestPkg("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	var buf bytes.Buffer
+	err = c.IterateExportedDecls("api", pkgFiles(pkg), func(x *Symb) bool {
+		if !x.IsDecl() {
+			t.Errorf("got reference %s at %s", x.Ident.Name, shortPosition(x.Ident.Pos()))
+		}
+		fmt.Fprintf(&buf, "%s\t%s\t%s\n", shortPosition(x.Ident.Pos()), x.QualifiedName(), x.ExprType)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("got error %v, want none because function bodies are not checked", err)
+	}
+
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "src", "api", "api_expected"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Errorf("got API:\n%s\nwant:\n%s", got, want)
+	}
+
+	// A full iteration over the same files checks the bodies again.
+	if err := c.IterateSymbsPkg("api", pkg, func(*Symb) bool { return true }); err == nil {
+		t.Error("got no error from IterateSymbsPkg for a function body that does not type-check")
+	}
+}
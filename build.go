@@ -0,0 +1,57 @@
+package symb
+
+import (
+	"bytes"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// buildContext returns the go/build context used to select files, as
+// configured by ctxt's GOOS, GOARCH, and BuildTags.
+func (ctxt *Context) buildContext() *build.Context {
+	bctxt := build.Default
+	bctxt.GOOS = ctxt.GOOS
+	bctxt.GOARCH = ctxt.GOARCH
+	bctxt.BuildTags = ctxt.BuildTags
+	if len(ctxt.Overlay) > 0 {
+		bctxt.OpenFile = func(path string) (io.ReadCloser, error) {
+			if src, present := ctxt.Overlay[path]; present {
+				return ioutil.NopCloser(bytes.NewReader(src)), nil
+			}
+			return os.Open(path)
+		}
+	}
+	return &bctxt
+}
+
+// SelectFiles returns the paths of the Go source files in dir that would
+// be analyzed by IteratePackageDir, sorted by name. Files are selected
+// according to their names (e.g., file_windows.go), their build
+// constraints, and ctxt's GOOS, GOARCH, BuildTags, and IncludeTests. The
+// files of dir in ctxt.Overlay are considered along with those on disk.
+func (ctxt *Context) SelectFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos = ctxt.addOverlayFiles(dir, infos)
+
+	bctxt := ctxt.buildContext()
+	var filenames []string
+	for _, info := range infos {
+		if !ctxt.isSourceFile(info) {
+			continue
+		}
+		match, err := bctxt.MatchFile(dir, info.Name())
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			filenames = append(filenames, filepath.Join(dir, info.Name()))
+		}
+	}
+	return filenames, nil
+}
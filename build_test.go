@@ -0,0 +1,63 @@
+package symb
+
+import (
+	"go/build"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var platformTests = []struct {
+	goos      string
+	buildTags []string
+	files     []string
+	nameDecl  string
+}{
+	{"linux", nil, []string{"name_linux.go", "platform.go"}, "name_linux.go"},
+	{"windows", nil, []string{"name_windows.go", "platform.go"}, "name_windows.go"},
+	{"linux", []string{"custom"}, []string{"custom.go", "name_linux.go", "platform.go"}, "name_linux.go"},
+}
+
+func TestSelectFiles(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+	dir := filepath.Join(build.Default.GOPATH, "src", "platform")
+
+	for _, test := range platformTests {
+		c := NewContext()
+		c.GOOS, c.GOARCH, c.BuildTags = test.goos, "amd64", test.buildTags
+		filenames, err := c.SelectFiles(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var files []string
+		for _, filename := range filenames {
+			files = append(files, filepath.Base(filename))
+		}
+		if !reflect.DeepEqual(files, test.files) {
+			t.Errorf("GOOS=%s tags=%v: got files %v, want %v", test.goos, test.buildTags, files, test.files)
+		}
+	}
+}
+
+func TestIterateImportPath_buildConstraints(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+
+	for _, test := range platformTests {
+		c := NewContext()
+		c.GOOS, c.GOARCH, c.BuildTags = test.goos, "amd64", test.buildTags
+		var nameDecls []string
+		err := c.IterateImportPath("platform", func(symb *Symb) bool {
+			if symb.Ident.Name == "Name" && symb.IsDecl() {
+				nameDecls = append(nameDecls, filepath.Base(c.FileSet.Position(symb.Ident.Pos()).Filename))
+			}
+			return true
+		})
+		if err != nil {
+			t.Errorf("GOOS=%s tags=%v: %s", test.goos, test.buildTags, err)
+			continue
+		}
+		if want := []string{test.nameDecl}; !reflect.DeepEqual(nameDecls, want) {
+			t.Errorf("GOOS=%s tags=%v: got Name declared in %v, want %v", test.goos, test.buildTags, nameDecls, want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Command gosymb prints the symbols of Go packages.
+//
+// Usage:
+//
+//	gosymb [flags] package|dir...
+//
+// Each argument is an import path, located using the GOPATH, or a
+// directory (an argument that names an existing directory, or that starts
+// with "." or "/"). By default gosymb prints one JSON object per line for
+// each symbol, encoded as by Symb.MarshalJSON; with -format=ctags, it
+// prints a tags file for the declarations instead. Errors are reported on
+// stderr, for each package; gosymb exits with a non-zero status only if no
+// package could be analyzed.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-symb"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	os.Exit(Run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Run runs gosymb with the command-line arguments args (without the
+// program name), writing its output to stdout and its errors to stderr,
+// and returns the exit status.
+func Run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("gosymb", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	declsOnly := fs.Bool("decls-only", false, "print only declarations")
+	exportedOnly := fs.Bool("exported-only", false, "print only symbols that refer to exported objects")
+	noTests := fs.Bool("no-tests", false, "ignore test files")
+	format := fs.String("format", "json", "output format: json or ctags")
+	relativeTo := fs.String("relative-to", "", "print filenames relative to `dir`")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: gosymb [flags] package|dir...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || *format != "json" && *format != "ctags" {
+		fs.Usage()
+		return 2
+	}
+
+	ctxt := symb.NewContext()
+	ctxt.IncludeTests = !*noTests
+	if *relativeTo != "" {
+		dir, err := filepath.Abs(*relativeTo)
+		if err != nil {
+			fmt.Fprintf(stderr, "gosymb: %s\n", err)
+			return 2
+		}
+		ctxt.BaseDir = dir
+	}
+	ctxt.Filter = func(x *symb.Symb) bool {
+		return (!*declsOnly || symb.DeclsOnly(x)) && (!*exportedOnly || symb.ExportedOnly(x))
+	}
+
+	var symbs []symb.Symb
+	var encodeErr error
+	enc := json.NewEncoder(stdout)
+	visit := func(x *symb.Symb) bool {
+		if *format == "ctags" {
+			symbs = append(symbs, *x)
+			return true
+		}
+		encodeErr = enc.Encode(x)
+		return encodeErr == nil
+	}
+	// The symbs are encoded as they are emitted, unless they are
+	// collected for ctags.
+	ctxt.ReuseSymbs = *format == "json"
+
+	analyzed := 0
+	for _, arg := range fs.Args() {
+		n := 0
+		count := func(x *symb.Symb) bool {
+			n++
+			return visit(x)
+		}
+		var err error
+		if isDir(arg) {
+			err = ctxt.IteratePackageDir(arg, count)
+		} else {
+			err = ctxt.IterateImportPath(arg, count)
+		}
+		if encodeErr != nil {
+			fmt.Fprintf(stderr, "gosymb: %s\n", encodeErr)
+			return 1
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "gosymb: %s: %s\n", arg, err)
+		}
+		if err == nil || n > 0 {
+			analyzed++
+		}
+	}
+	if *format == "ctags" {
+		if err := symb.WriteCtags(stdout, ctxt.FileSet, symbs); err != nil {
+			fmt.Fprintf(stderr, "gosymb: %s\n", err)
+			return 1
+		}
+	}
+	if analyzed == 0 {
+		return 1
+	}
+	return 0
+}
+
+// isDir reports whether arg names a directory rather than an import path.
+func isDir(arg string) bool {
+	if strings.HasPrefix(arg, ".") || filepath.IsAbs(arg) {
+		return true
+	}
+	fi, err := os.Stat(arg)
+	return err == nil && fi.IsDir()
+}
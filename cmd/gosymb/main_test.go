@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	defer func(gopath string) { build.Default.GOPATH = gopath }(build.Default.GOPATH)
+	build.Default.GOPATH, _ = filepath.Abs("../../testdata")
+	src := filepath.Join(build.Default.GOPATH, "src")
+
+	tests := []struct {
+		args   []string
+		golden string
+	}{
+		{[]string{"-decls-only", "-relative-to", src, "index/shapes"}, "shapes_decls.json"},
+		{[]string{"-exported-only", "-relative-to", src, filepath.Join(src, "index", "shapes")}, "shapes_exported.json"},
+		{[]string{"-format=ctags", "-relative-to", src, "index/shapes", filepath.Join(src, "index", "other")}, "index.tags"},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		if status := Run(test.args, &stdout, &stderr); status != 0 {
+			t.Errorf("%v: got exit status %d (stderr %q), want 0", test.args, status, stderr.String())
+			continue
+		}
+		golden := filepath.Join("testdata", test.golden)
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(stdout.Bytes(), want) {
+			actual := strings.TrimSuffix(golden, filepath.Ext(golden)) + "_actual" + filepath.Ext(golden)
+			ioutil.WriteFile(actual, stdout.Bytes(), 0666)
+			t.Errorf("%v: output differs from %s (see %s)", test.args, golden, actual)
+		}
+	}
+}
+
+func TestRun_errors(t *testing.T) {
+	defer func(gopath string) { build.Default.GOPATH = gopath }(build.Default.GOPATH)
+	build.Default.GOPATH, _ = filepath.Abs("../../testdata")
+
+	// A package that cannot be found is reported, but does not fail the
+	// run if another package is analyzed.
+	var stdout, stderr bytes.Buffer
+	if status := Run([]string{"-decls-only", "nonexistent", "index/other"}, &stdout, &stderr); status != 0 {
+		t.Errorf("got exit status %d, want 0", status)
+	}
+	if !strings.Contains(stderr.String(), "nonexistent") {
+		t.Errorf("got stderr %q, want an error for nonexistent", stderr.String())
+	}
+	if stdout.Len() == 0 {
+		t.Errorf("got no output for index/other")
+	}
+
+	stderr.Reset()
+	if status := Run([]string{"nonexistent"}, &stdout, &stderr); status != 1 {
+		t.Errorf("got exit status %d when nothing was analyzed, want 1", status)
+	}
+	if status := Run([]string{"-format=xml", "index/other"}, &stdout, &stderr); status != 2 {
+		t.Errorf("got exit status %d for an unknown format, want 2", status)
+	}
+}
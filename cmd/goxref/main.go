@@ -0,0 +1,206 @@
+// Command goxref finds the references to a Go declaration.
+//
+// Usage:
+//
+//	goxref [flags] target package|dir...
+//
+// The target is the qualified name of a declaration, as returned by
+// Symb.QualifiedName: "importpath.Name" for a package-level object, or
+// "importpath.Type.Method" (or "importpath.Type.Field") for a member of a
+// type. goxref searches the packages given as the other arguments (import
+// paths, located using the GOPATH, or directories) and prints the
+// declaration of the target and each reference to it, in order of file
+// and position, as "file:line:col: line-text", like grep -n. Filenames
+// are relative to the current directory if they are under it.
+//
+// Each package is type-checked separately, so the target is matched by
+// qualified name rather than by object. A target that names more than one
+// declaration (such as the init functions of a package, or a local
+// variable declared twice in one function) is ambiguous: goxref lists the
+// declarations on stderr and exits with status 1.
+package main
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-symb"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func main() {
+	os.Exit(Run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// A location is a declaration of or reference to the target.
+type location struct {
+	Filename     string
+	Offset       int `json:"-"`
+	Line, Column int
+	Text         string // the text of the line, without its newline
+	IsDecl       bool
+	Kind         string `json:",omitempty"` // kind of the declared object, for declarations
+}
+
+// Run runs goxref with the command-line arguments args (without the
+// program name), writing its output to stdout and its errors to stderr,
+// and returns the exit status.
+func Run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("goxref", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	defsOnly := fs.Bool("defs", false, "print only the declaration of the target")
+	jsonOutput := fs.Bool("json", false, "print one JSON object per location")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: goxref [flags] target package|dir...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return 2
+	}
+	target := fs.Arg(0)
+
+	ctxt := symb.NewContext()
+	ctxt.IncludeTests = true
+	ctxt.BaseDir, _ = os.Getwd()
+	unreadable := make(map[string]bool)
+	var locs []location
+	seen := make(map[token.Position]bool)
+	visit := func(x *symb.Symb) bool {
+		if x.Synthetic || x.ReferObj == nil || x.QualifiedName() != target {
+			return true
+		}
+		if *defsOnly && !x.IsDecl() {
+			return true
+		}
+		pos := x.Position(nil)
+		if seen[pos] {
+			return true
+		}
+		seen[pos] = true
+		loc := location{Filename: pos.Filename, Offset: pos.Offset, Line: pos.Line, Column: pos.Column, IsDecl: x.IsDecl()}
+		if loc.IsDecl {
+			loc.Kind = objectKind(x)
+		}
+		text, err := ctxt.SourceLine(x.Ident.Pos())
+		if err != nil {
+			// Report each unreadable file once.
+			if filename := ctxt.FileSet.File(x.Ident.Pos()).Name(); !unreadable[filename] {
+				unreadable[filename] = true
+				fmt.Fprintf(stderr, "goxref: %s\n", err)
+			}
+		}
+		loc.Text = text
+		locs = append(locs, loc)
+		return true
+	}
+
+	analyzed := 0
+	for _, arg := range fs.Args()[1:] {
+		n := 0
+		count := func(x *symb.Symb) bool {
+			n++
+			return visit(x)
+		}
+		var err error
+		if isDir(arg) {
+			err = ctxt.IteratePackageDir(arg, count)
+		} else {
+			err = ctxt.IterateImportPath(arg, count)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "goxref: %s: %s\n", arg, err)
+		}
+		if err == nil || n > 0 {
+			analyzed++
+		}
+	}
+	if analyzed == 0 {
+		return 1
+	}
+
+	sort.Sort(locationsByPos(locs))
+	var decls []location
+	for _, loc := range locs {
+		if loc.IsDecl {
+			decls = append(decls, loc)
+		}
+	}
+	if len(decls) > 1 {
+		fmt.Fprintf(stderr, "goxref: %s is ambiguous; it names %d declarations:\n", target, len(decls))
+		for _, d := range decls {
+			fmt.Fprintf(stderr, "%s:%d:%d: %s\n", d.Filename, d.Line, d.Column, d.Kind)
+		}
+		return 1
+	}
+	if len(locs) == 0 {
+		fmt.Fprintf(stderr, "goxref: no declaration of or reference to %s found\n", target)
+		return 1
+	}
+
+	enc := json.NewEncoder(stdout)
+	for _, loc := range locs {
+		if *jsonOutput {
+			if err := enc.Encode(loc); err != nil {
+				fmt.Fprintf(stderr, "goxref: %s\n", err)
+				return 1
+			}
+			continue
+		}
+		fmt.Fprintf(stdout, "%s:%d:%d: %s\n", loc.Filename, loc.Line, loc.Column, loc.Text)
+	}
+	return 0
+}
+
+// objectKind returns the kind of object x declares: func, method, type,
+// var, field, const, or label.
+func objectKind(x *symb.Symb) string {
+	switch obj := x.ReferObj.(type) {
+	case *types.Func:
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			return "method"
+		}
+		return "func"
+	case *types.TypeName:
+		return "type"
+	case *types.Var:
+		if obj.IsField() {
+			return "field"
+		}
+		return "var"
+	case *types.Const:
+		return "const"
+	case *types.Label:
+		return "label"
+	}
+	return "object"
+}
+
+type locationsByPos []location
+
+func (l locationsByPos) Len() int      { return len(l) }
+func (l locationsByPos) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l locationsByPos) Less(i, j int) bool {
+	if l[i].Filename != l[j].Filename {
+		return l[i].Filename < l[j].Filename
+	}
+	return l[i].Offset < l[j].Offset
+}
+
+// isDir reports whether arg names a directory rather than an import path.
+func isDir(arg string) bool {
+	if strings.HasPrefix(arg, ".") || filepath.IsAbs(arg) {
+		return true
+	}
+	fi, err := os.Stat(arg)
+	return err == nil && fi.IsDir()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"go/build"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runXref runs goxref with args against the testdata GOPATH and returns
+// its exit status and output lines, with the GOPATH's src directory
+// trimmed from filenames.
+func runXref(t *testing.T, args ...string) (status int, stdout, stderr []string) {
+	build.Default.GOPATH, _ = filepath.Abs("../../testdata")
+	src := filepath.Join(build.Default.GOPATH, "src") + string(filepath.Separator)
+	var out, errOut bytes.Buffer
+	status = Run(args, &out, &errOut)
+	lines := func(b bytes.Buffer) []string {
+		s := strings.TrimSuffix(strings.Replace(b.String(), src, "", -1), "\n")
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, "\n")
+	}
+	return status, lines(out), lines(errOut)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{"xref/lib.Count", "xref/lib", "xref/use"},
+			[]string{
+				"xref/lib/lib.go:4:6: func Count(s []string) int {",
+				"xref/lib/lib.go:12:8: \t\tn += Count(s)",
+				"xref/use/use.go:6:13: \treturn lib.Count(a) + lib.Count(b)",
+				"xref/use/use.go:6:28: \treturn lib.Count(a) + lib.Count(b)",
+			},
+		},
+		{
+			[]string{"xref/lib.Counter.Count", "xref/lib", "xref/use"},
+			[]string{
+				"xref/lib/lib.go:21:19: func (c *Counter) Count() int {",
+				"xref/use/use.go:10:11: \treturn c.Count()",
+			},
+		},
+		{
+			[]string{"-defs", "xref/lib.Count", "xref/lib", "xref/use"},
+			[]string{"xref/lib/lib.go:4:6: func Count(s []string) int {"},
+		},
+		{
+			// Only the references are found if the declaring package
+			// is not searched.
+			[]string{"xref/lib.Count", "xref/use"},
+			[]string{
+				"xref/use/use.go:6:13: \treturn lib.Count(a) + lib.Count(b)",
+				"xref/use/use.go:6:28: \treturn lib.Count(a) + lib.Count(b)",
+			},
+		},
+		{
+			[]string{"-json", "-defs", "xref/lib.Counter", "xref/lib"},
+			[]string{`{"Filename":"xref/lib/lib.go","Line":17,"Column":6,"Text":"type Counter struct {","IsDecl":true,"Kind":"type"}`},
+		},
+		{
+			// Positions follow //line directives, but the text comes from
+			// the file that was read.
+			[]string{"linedir.base", "linedir"},
+			[]string{
+				"linedir/calc.y:11:0: \treturn len(s) + base",
+				"linedir/calc.y:20:0: var base = 1",
+			},
+		},
+	}
+	for _, test := range tests {
+		status, stdout, stderr := runXref(t, test.args...)
+		if status != 0 {
+			t.Errorf("%v: got exit status %d (stderr %q), want 0", test.args, status, stderr)
+			continue
+		}
+		if !reflect.DeepEqual(stdout, test.want) {
+			t.Errorf("%v: got\n%s\nwant\n%s", test.args, strings.Join(stdout, "\n"), strings.Join(test.want, "\n"))
+		}
+	}
+}
+
+func TestRun_ambiguous(t *testing.T) {
+	status, stdout, stderr := runXref(t, "xref/lib.init", "xref/lib")
+	if status != 1 {
+		t.Errorf("got exit status %d, want 1", status)
+	}
+	if stdout != nil {
+		t.Errorf("got output %q, want none", stdout)
+	}
+	want := []string{
+		"goxref: xref/lib.init is ambiguous; it names 2 declarations:",
+		"xref/lib/lib.go:26:6: func",
+		"xref/lib/lib.go:28:6: func",
+	}
+	if !reflect.DeepEqual(stderr, want) {
+		t.Errorf("got stderr %q, want %q", stderr, want)
+	}
+}
+
+func TestRun_notFound(t *testing.T) {
+	status, _, stderr := runXref(t, "xref/lib.Nope", "xref/lib")
+	if status != 1 || len(stderr) != 1 {
+		t.Errorf("got exit status %d and stderr %q, want 1 and one error", status, stderr)
+	}
+}
@@ -0,0 +1,96 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+	"go/token"
+	"regexp"
+)
+
+// commentWordRx matches the words of a comment that may name identifiers:
+// a name, or a qualified name like pkg.Name or Type.Method.
+var commentWordRx = regexp.MustCompile(`[\pL_][\pL\pN_]*(?:\.[\pL_][\pL\pN_]*)?`)
+
+// visitDoc emits a symb for each word of doc, a doc comment, that names an
+// object of the package scope, a member of one of those objects, or an
+// exported object of a package imported by the current file, if
+// ctxt.CommentRefs is set. The symbs have InComment set and synthesized
+// Idents at the words' positions in the comment. A qualified name yields a
+// symb for each part that resolves, like the selector expression it
+// resembles. It returns false if visitf stopped the iteration.
+func (ctxt *Context) visitDoc(doc *ast.CommentGroup, visitf func(*Symb) bool) bool {
+	if !ctxt.CommentRefs || doc == nil {
+		return true
+	}
+	for _, c := range doc.List {
+		for _, loc := range commentWordRx.FindAllStringIndex(c.Text, -1) {
+			pos := c.Slash + token.Pos(loc[0])
+			word := c.Text[loc[0]:loc[1]]
+			if !ctxt.visitCommentWord(pos, word, visitf) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// visitCommentWord emits the symbs for word, at pos in a doc comment.
+func (ctxt *Context) visitCommentWord(pos token.Pos, word string, visitf func(*Symb) bool) bool {
+	name, member := word, ""
+	for i := 0; i < len(word); i++ {
+		if word[i] == '.' {
+			name, member = word[:i], word[i+1:]
+			break
+		}
+	}
+	obj := ctxt.currentPackage.Scope().Lookup(name)
+	if obj == nil {
+		pkgName := ctxt.fileImport(name)
+		if pkgName == nil {
+			return true
+		}
+		obj = pkgName
+	}
+	x := &ast.Ident{NamePos: pos, Name: name}
+	ctxt.commentIdents[x] = obj
+	var sel types.Object
+	if member != "" {
+		switch obj := obj.(type) {
+		case *types.PkgName:
+			if ast.IsExported(member) {
+				sel = obj.Imported().Scope().Lookup(member)
+			}
+		case *types.TypeName:
+			sel, _, _ = types.LookupFieldOrMethod(obj.Type(), ctxt.currentPackage, member)
+		}
+	}
+	if !ctxt.visitExpr(x, false, visitf) {
+		return false
+	}
+	if sel == nil {
+		return true
+	}
+	id := &ast.Ident{NamePos: pos + token.Pos(len(name)+1), Name: member}
+	ctxt.commentIdents[id] = sel
+	return ctxt.visitExpr(id, false, visitf)
+}
+
+// fileImport returns the name of the package that the current file
+// imports as name, or nil if there is none.
+func (ctxt *Context) fileImport(name string) *types.PkgName {
+	if ctxt.currentFile == nil {
+		return nil
+	}
+	for _, spec := range ctxt.currentFile.Imports {
+		var obj types.Object
+		if spec.Name != nil {
+			obj = ctxt.info.Defs[spec.Name]
+		} else {
+			obj = ctxt.info.Implicits[spec]
+		}
+		if pkgName, ok := obj.(*types.PkgName); ok && pkgName.Name() == name {
+			return pkgName
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package symb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContext_CommentRefs(t *testing.T) {
+	pkg, err := parseTestPkg("docrefs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, commentRefs := range []bool{false, true} {
+		c := NewContext()
+		c.FileSet = fset
+		c.CommentRefs = commentRefs
+		var mentions []string
+		err = c.IterateSymbsPkg("docrefs", pkg, func(x *Symb) bool {
+			if x.InComment {
+				pos := fset.Position(x.Ident.Pos())
+				mentions = append(mentions, fmt.Sprintf("%d:%d %s %s", pos.Line, pos.Column, x.Ident.Name, x.ReferObj))
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want []string
+		if commentRefs {
+			want = []string{
+				"1:29 Config type docrefs.Config struct{Name string}",
+				"6:4 Config type docrefs.Config struct{Name string}",
+				"6:31 New func docrefs.New() *docrefs.Config",
+				"6:52 Config type docrefs.Config struct{Name string}",
+				"6:59 Describe func (*docrefs.Config).Describe() string",
+				"7:4 strings package strings",
+				"7:12 ToUpper func strings.ToUpper(s string) string",
+				"9:20 New func docrefs.New() *docrefs.Config",
+				"19:4 New func docrefs.New() *docrefs.Config",
+				"19:18 Config type docrefs.Config struct{Name string}",
+			}
+		}
+		if !reflect.DeepEqual(mentions, want) {
+			t.Errorf("CommentRefs=%v: got mentions\n%s\nwant\n%s", commentRefs, strings.Join(mentions, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+	"go/token"
+)
+
+// ContainerName returns the name of x.Container, or "" if x has no
+// container.
+func (x *Symb) ContainerName() string {
+	if x.Container == nil {
+		return ""
+	}
+	return x.Container.Name()
+}
+
+// container returns the Container of symb: for a field or method declared
+// in a struct or interface type literal, the variable, field, or type
+// whose type expression contains the literal (see typeLitOwner); for
+// another method or field, the named type it belongs to (see memberOwner
+// and universeOwner); for a function-local object, the function
+// declaration or literal that most closely encloses it; and nil otherwise.
+// Results are cached by object, because finding the owner of a field or
+// interface method means searching the syntax or the package scope.
+func (ctxt *Context) container(symb *Symb) types.Object {
+	obj := symb.ReferObj
+	if obj == nil {
+		return nil
+	}
+	if symb.Universe {
+		if owner := universeOwner(obj); owner != nil {
+			return owner
+		}
+		return nil
+	}
+	if c, present := ctxt.containers[obj]; present {
+		return c
+	}
+
+	var c types.Object
+	if owner := ctxt.typeLitOwner(obj); owner != nil {
+		c = owner
+	} else if owner := memberOwner(obj); owner != nil {
+		c = owner
+	} else if v, isVar := obj.(*types.Var); isVar && v.IsField() {
+		// A field of an anonymous struct type in an expression, such as
+		// a composite literal.
+	} else if symb.Local {
+		if f := enclosingFuncDecl(symb.File, obj.Pos()); f != nil {
+			c = ctxt.info.Defs[f.Name]
+		}
+		if lit := enclosingFuncLit(symb.File, obj.Pos()); lit != nil && ctxt.litFuncs[lit] != nil {
+			c = ctxt.litFuncs[lit]
+		}
+	}
+	ctxt.containers[obj] = c
+	return c
+}
+
+// typeLitOwner returns the object whose declaration has the type
+// expression containing the struct or interface type literal in which obj,
+// a field or interface method of the package being walked, is declared:
+// the variable (of a var declaration or a parameter or result), the field
+// of an enclosing struct type, or the named type. If several variables or
+// fields are declared with the literal's type, it is the first exported
+// one, or else the first one (see ownerName). It returns nil if obj is
+// not declared in a type literal of one of the package's files, or if the
+// literal is not part of such a type expression, as in a composite
+// literal.
+func (ctxt *Context) typeLitOwner(obj types.Object) types.Object {
+	switch obj := obj.(type) {
+	case *types.Var:
+		if !obj.IsField() {
+			return nil
+		}
+	case *types.Func:
+		if sig, ok := obj.Type().(*types.Signature); !ok || sig.Recv() == nil || !types.IsInterface(sig.Recv().Type()) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if obj.Pkg() != ctxt.currentPackage || !obj.Pos().IsValid() {
+		return nil
+	}
+	pos := obj.Pos()
+	var path []ast.Node // the nodes enclosing pos, outermost first
+	for _, file := range ctxt.currentFiles {
+		if file.Pos() <= pos && pos < file.End() {
+			ast.Inspect(file, func(n ast.Node) bool {
+				if n == nil || !(n.Pos() <= pos && pos < n.End()) {
+					return false
+				}
+				path = append(path, n)
+				return true
+			})
+			break
+		}
+	}
+
+	// Find the field that declares obj (a method of an interface is a
+	// field too) and the type literal whose field list holds it.
+	i := len(path) - 1
+	for i >= 0 {
+		if _, isField := path[i].(*ast.Field); isField {
+			break
+		}
+		i--
+	}
+	if i < 2 {
+		return nil
+	}
+	switch path[i-2].(type) {
+	case *ast.StructType, *ast.InterfaceType:
+	default:
+		return nil
+	}
+
+	// Skip the rest of the type expression around the literal.
+	for i -= 3; i >= 0; i-- {
+		switch n := path[i].(type) {
+		case *ast.StarExpr, *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.Ellipsis, *ast.ParenExpr, *ast.FieldList, *ast.StructType, *ast.InterfaceType:
+			continue
+		case *ast.Field:
+			if len(n.Names) > 0 {
+				return ctxt.info.Defs[ownerName(n.Names)]
+			}
+		case *ast.ValueSpec:
+			return ctxt.info.Defs[ownerName(n.Names)]
+		case *ast.TypeSpec:
+			return ctxt.info.Defs[n.Name]
+		}
+		return nil
+	}
+	return nil
+}
+
+// ownerName returns the name, among the names declared with a type
+// expression, that owns the members of the type literals in it: the first
+// exported one, through which other packages can reach the members, or
+// else the first one.
+func ownerName(names []*ast.Ident) *ast.Ident {
+	for _, name := range names {
+		if ast.IsExported(name.Name) {
+			return name
+		}
+	}
+	return names[0]
+}
+
+// enclosingFuncLit returns the innermost function literal in file that
+// strictly encloses pos, or nil if there is none. A literal does not enclose
+// its own position.
+func enclosingFuncLit(file *ast.File, pos token.Pos) *ast.FuncLit {
+	if file == nil {
+		return nil
+	}
+	var lit *ast.FuncLit
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || !(n.Pos() <= pos && pos < n.End()) {
+			return false
+		}
+		if l, ok := n.(*ast.FuncLit); ok && l.Pos() < pos {
+			lit = l
+		}
+		return true
+	})
+	return lit
+}
@@ -0,0 +1,96 @@
+package symb
+
+import (
+	"testing"
+)
+
+func TestSymb_Container(t *testing.T) {
+	pkg, err := parseTestPkg("container")
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers := make(map[string]string)
+	c := NewContext()
+	c.FileSet = fset
+	err = c.IterateSymbsPkg("container", pkg, func(symb *Symb) bool {
+		if symb.IsDecl() {
+			containers[symb.Ident.Name] = symb.ContainerName()
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Describe", "Config"}, // method
+		{"Name", "Config"},     // field
+		{"Verbose", "Options"}, // field of nested anonymous struct
+		{"Loose", "anon"},      // field of anonymous struct outside any named type
+		{"prefix", "Describe"}, // local
+		{"c", "Describe"},      // receiver
+		{"Default", ""},        // package-level var
+		{"Config", ""},         // package-level type
+	}
+	for _, test := range tests {
+		got, present := containers[test.name]
+		if !present {
+			t.Errorf("%s: no declaration found", test.name)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got container %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSymb_Container_typeLits(t *testing.T) {
+	pkg, err := parseTestPkg("anontypes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decls := make(map[string]string)
+	refs := make(map[string]string)
+	c := NewContext()
+	c.FileSet = fset
+	err = c.IterateSymbsPkg("anontypes", pkg, func(symb *Symb) bool {
+		if symb.IsDecl() {
+			decls[symb.Ident.Name] = symb.ContainerName()
+		} else {
+			refs[symb.Ident.Name] = symb.ContainerName()
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"N", "x"},         // field of an anonymous struct var
+		{"Opts", "Config"}, // field of a named struct
+		{"Debug", "Opts"},  // field of an anonymous struct field
+		{"Trace", "Opts"},  // field of an anonymous struct field
+		{"Level", "Trace"}, // field of an anonymous struct nested twice
+		{"Write", "w"},     // method of an anonymous interface parameter
+	}
+	for _, test := range tests {
+		got, present := decls[test.name]
+		if !present {
+			t.Errorf("%s: no declaration found", test.name)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got container %q, want %q", test.name, got, test.want)
+		}
+		// References have the container of the declaration.
+		if got, present := refs[test.name]; !present || got != test.want {
+			t.Errorf("%s: got reference container %q (found: %v), want %q", test.name, got, present, test.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"sort"
+)
+
+// WriteCtags writes a tags file in the exuberant ctags extended format for
+// the declarations among symbs to w, resolving positions through fset
+// (with filenames relative to the BaseDir of the Context that emitted the
+// symbs, if it is set). References are ignored. Each tag is addressed by
+// line number and carries its kind (f for functions, m for methods, t for
+// types, v for variables, w for struct fields, and c for constants).
+// Methods also carry their receiver type as ctype, and unexported and
+// function-local symbols are marked with file scope. Tags are sorted by
+// name, file, and line, as ctags requires.
+func WriteCtags(w io.Writer, fset *token.FileSet, symbs []Symb) error {
+	var tags []ctag
+	for i := range symbs {
+		x := &symbs[i]
+		if !x.IsDecl() || x.Synthetic || x.Anonymous {
+			continue
+		}
+		kind := ctagKind(x)
+		if kind == 0 {
+			continue
+		}
+		pos := x.Position(fset)
+		tags = append(tags, ctag{
+			name:      x.Ident.Name,
+			filename:  pos.Filename,
+			line:      pos.Line,
+			kind:      kind,
+			recv:      recvTypeName(x),
+			fileScope: x.Local || !ast.IsExported(x.Ident.Name),
+		})
+	}
+	sort.Sort(ctagsByName(tags))
+
+	if _, err := io.WriteString(w, "!_TAG_FILE_FORMAT\t2\t/extended format; --format=1 will not append ;\" to lines/\n!_TAG_FILE_SORTED\t1\t/0=unsorted, 1=sorted, 2=foldcase/\n"); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%d;\"\t%c", tag.name, tag.filename, tag.line, tag.kind); err != nil {
+			return err
+		}
+		if tag.recv != "" {
+			if _, err := fmt.Fprintf(w, "\tctype:%s", tag.recv); err != nil {
+				return err
+			}
+		}
+		if tag.fileScope {
+			if _, err := io.WriteString(w, "\tfile:"); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type ctag struct {
+	name      string
+	filename  string
+	line      int
+	kind      byte
+	recv      string
+	fileScope bool
+}
+
+type ctagsByName []ctag
+
+func (t ctagsByName) Len() int      { return len(t) }
+func (t ctagsByName) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t ctagsByName) Less(i, j int) bool {
+	if t[i].name != t[j].name {
+		return t[i].name < t[j].name
+	}
+	if t[i].filename != t[j].filename {
+		return t[i].filename < t[j].filename
+	}
+	return t[i].line < t[j].line
+}
+
+// ctagKind returns the ctags kind letter for the declaration x, or 0 if x
+// should not be tagged.
+func ctagKind(x *Symb) byte {
+	switch o := x.ReferObj.(type) {
+	case *types.Func:
+		if recvTypeName(x) != "" {
+			return 'm'
+		}
+		return 'f'
+	case *types.TypeName:
+		return 't'
+	case *types.Var:
+		if o.IsField() {
+			return 'w'
+		}
+		return 'v'
+	case *types.Const:
+		return 'c'
+	}
+	return 0
+}
+
+// recvTypeName returns the name of the receiver's base type if x is a
+// method declaration, and "" otherwise.
+func recvTypeName(x *Symb) string {
+	sel, ok := x.Expr.(*ast.SelectorExpr)
+	if !ok || !x.IsDecl() {
+		return ""
+	}
+	if _, isFunc := x.ReferObj.(*types.Func); !isFunc {
+		return ""
+	}
+	return recvExprName(sel.X)
+}
+
+// recvExprName returns the name of the type in the receiver type
+// expression e, such as T for *T.
+func recvExprName(e ast.Expr) string {
+	for {
+		switch t := e.(type) {
+		case *ast.StarExpr:
+			e = t.X
+		case *ast.ParenExpr:
+			e = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package symb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteCtags(t *testing.T) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteCtags(&buf, fset, collectSymbs("foo", pkg)); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "src", "foo", "tags_expected"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Errorf("got tags:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"sort"
+)
+
+// A DiffResult describes how the declarations of one set of symbs differ
+// from those of another. Each list is sorted by qualified name (see
+// Symb.QualifiedName). A DiffResult marshals to JSON as an object with the
+// same field names, with symbs encoded as by Symb.MarshalJSON.
+type DiffResult struct {
+	Added   []Symb       // declarations in new with no counterpart in old
+	Removed []Symb       // declarations in old with no counterpart in new
+	Changed []SymbChange // declarations whose fingerprints differ
+}
+
+// A SymbChange pairs the old and new declarations of a name whose
+// fingerprint changed.
+type SymbChange struct {
+	Old, New Symb
+}
+
+// Diff compares the declarations in old with those in new, matching them
+// by qualified name: a name declared only in new is Added, one declared
+// only in old is Removed, and one declared in both with different
+// fingerprints (see Symb.Fingerprint) is Changed. References, declarations
+// of function-local objects, and package names are ignored. Declarations
+// that share a qualified name (such as init functions) are matched in the
+// order they appear in old and new.
+//
+// Renames cannot be told apart from removals and additions: renaming a
+// method, or the receiver type of a method (which changes the qualified
+// name of every method and field of the type), is reported as the removal
+// of the old names and the addition of the new ones.
+func Diff(old, new []Symb) DiffResult {
+	oldDecls, newDecls := declsByName(old), declsByName(new)
+	var r DiffResult
+	for _, name := range sortedNames(oldDecls, newDecls) {
+		o, n := oldDecls[name], newDecls[name]
+		for i := 0; i < len(o) || i < len(n); i++ {
+			switch {
+			case i >= len(n):
+				r.Removed = append(r.Removed, o[i])
+			case i >= len(o):
+				r.Added = append(r.Added, n[i])
+			case o[i].Fingerprint() != n[i].Fingerprint():
+				r.Changed = append(r.Changed, SymbChange{o[i], n[i]})
+			}
+		}
+	}
+	return r
+}
+
+// declsByName returns the declarations in symbs that Diff compares, by
+// qualified name.
+func declsByName(symbs []Symb) map[string][]Symb {
+	decls := make(map[string][]Symb)
+	for _, x := range symbs {
+		if x.ReferObj == nil || x.Local || !x.IsDecl() {
+			continue
+		}
+		if _, isPkg := x.ReferObj.(*types.PkgName); isPkg {
+			continue
+		}
+		name := x.QualifiedName()
+		decls[name] = append(decls[name], x)
+	}
+	return decls
+}
+
+// sortedNames returns the keys of a and b, sorted and without duplicates.
+func sortedNames(a, b map[string][]Symb) []string {
+	var names []string
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, present := a[name]; !present {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
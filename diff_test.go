@@ -0,0 +1,57 @@
+package symb
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// sourceSymbs returns the symbs of the file named by filename, iterated
+// by IterateSource.
+func sourceSymbs(t *testing.T, filename string) []Symb {
+	var symbs []Symb
+	err := IterateSource(filename, nil, func(x *Symb) bool {
+		symbs = append(symbs, *x)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return symbs
+}
+
+func TestDiff(t *testing.T) {
+	dir := filepath.Join("testdata", "src", "diff")
+	old := sourceSymbs(t, filepath.Join(dir, "diff.go"))
+	new := sourceSymbs(t, filepath.Join(dir, "modified", "diff.go"))
+
+	names := func(symbs []Symb) []string {
+		var names []string
+		for _, x := range symbs {
+			names = append(names, x.QualifiedName())
+		}
+		return names
+	}
+	r := Diff(old, new)
+	if got, want := names(r.Added), []string{"diff.Save"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got added %v, want %v", got, want)
+	}
+	if got, want := names(r.Removed), []string{"diff.Config.Verbose"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got removed %v, want %v", got, want)
+	}
+	var changed []string
+	for _, c := range r.Changed {
+		changed = append(changed, c.Old.QualifiedName())
+	}
+	if want := []string{"diff.Config", "diff.Load"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("got changed %v, want %v", changed, want)
+	}
+
+	if _, err := json.Marshal(r); err != nil {
+		t.Errorf("marshalling the diff: %s", err)
+	}
+	if r := Diff(old, old); r.Added != nil || r.Removed != nil || r.Changed != nil {
+		t.Errorf("got diff %+v of a set against itself, want none", r)
+	}
+}
@@ -0,0 +1,173 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// ErrorKind classifies Errors.
+type ErrorKind int
+
+const (
+	TypeError   ErrorKind = iota // the type checker rejected the package
+	Unresolved                   // an identifier has no object (reported only if Context.Strict is set)
+	Unsupported                  // the package uses a construct that is not supported, such as a dot import
+	Redeclared                   // a name is declared twice in the same scope
+	SyntaxError                  // the parser rejected part of a file, which is otherwise checked and walked
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case TypeError:
+		return "TypeError"
+	case Unresolved:
+		return "Unresolved"
+	case Unsupported:
+		return "Unsupported"
+	case Redeclared:
+		return "Redeclared"
+	case SyntaxError:
+		return "SyntaxError"
+	}
+	return "ErrorKind(?)"
+}
+
+// An Error is a problem found while type-checking or walking a package.
+type Error struct {
+	Pos  token.Pos // position of the problem, if known
+	Kind ErrorKind
+	Msg  string
+
+	// For Redeclared errors, the redeclared name and the position of its
+	// other declaration (Pos is that of the later one).
+	Name string
+	Prev token.Pos
+}
+
+func (e Error) Error() string {
+	return e.Kind.String() + ": " + e.Msg
+}
+
+// Errors is the list of Errors returned by IterateSymbs and the other
+// iteration methods for a package, sorted by position.
+type Errors []Error
+
+func (e Errors) Error() string {
+	switch len(e) {
+	case 0:
+		return "no errors"
+	case 1:
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e[0], len(e)-1)
+}
+
+// AsErrors returns the Errors that err consists of: the elements of an
+// Errors, the Error itself, or, for a PackageErrors, the Errors of each of
+// its packages in turn. It returns nil for other errors, such as parse
+// errors.
+func AsErrors(err error) []Error {
+	switch err := err.(type) {
+	case Errors:
+		return err
+	case Error:
+		return []Error{err}
+	case *PackageError:
+		return AsErrors(err.Err)
+	case PackageErrors:
+		var errs []Error
+		for _, e := range err {
+			errs = append(errs, AsErrors(e)...)
+		}
+		return errs
+	}
+	return nil
+}
+
+// typeError converts an error reported by the type checker to an Error.
+func typeError(err error) Error {
+	if te, ok := err.(types.Error); ok {
+		return Error{Pos: te.Pos, Kind: TypeError, Msg: te.Msg}
+	}
+	return Error{Kind: TypeError, Msg: err.Error()}
+}
+
+// addTypeError appends the Error for err, reported by the type checker,
+// to errs. The checker reports a redeclaration as an error at the later
+// declaration followed by one at the other declaration; these are
+// combined into one Redeclared Error.
+func addTypeError(errs []Error, err error) []Error {
+	e := typeError(err)
+	if n := len(errs); n > 0 {
+		if prev := &errs[n-1]; prev.Kind == Redeclared && !prev.Prev.IsValid() && strings.TrimSpace(e.Msg) == "other declaration of "+prev.Name {
+			prev.Prev = e.Pos
+			return errs
+		}
+	}
+	if name := strings.TrimSuffix(e.Msg, " redeclared in this block"); name != e.Msg {
+		e.Kind, e.Name = Redeclared, name
+	}
+	return append(errs, e)
+}
+
+// errorf records an Error of the given kind for the package being walked,
+// and logs it at the kind's level.
+func (ctxt *Context) errorf(kind ErrorKind, pos token.Pos, f string, a ...interface{}) {
+	ctxt.errs = append(ctxt.errs, Error{Pos: pos, Kind: kind, Msg: fmt.Sprintf(f, a...)})
+	ctxt.logf(kind.logLevel(), pos, f, a...)
+}
+
+// joinErrors returns the Errors of the parser, the type checker, and the
+// walk, sorted by position, or nil if there are none.
+func joinErrors(lists ...[]Error) error {
+	var errs Errors
+	for _, list := range lists {
+		errs = append(errs, list...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Stable(errorsByPos(errs))
+	return errs
+}
+
+// syntaxErrors converts err, returned by the parser along with a partial
+// file, to SyntaxErrors, positioned in the Context's FileSet.
+func (ctxt *Context) syntaxErrors(err error) []Error {
+	list, ok := err.(scanner.ErrorList)
+	if !ok {
+		if e, isError := err.(*scanner.Error); isError {
+			list = scanner.ErrorList{e}
+		} else {
+			return []Error{{Kind: SyntaxError, Msg: err.Error()}}
+		}
+	}
+	errs := make([]Error, len(list))
+	for i, e := range list {
+		errs[i] = Error{Pos: ctxt.filePos(e.Pos), Kind: SyntaxError, Msg: e.Msg}
+	}
+	return errs
+}
+
+// filePos returns the Pos of position p in the file of the Context's
+// FileSet that most recently had p's filename, or NoPos if there is none.
+func (ctxt *Context) filePos(p token.Position) token.Pos {
+	pos := token.NoPos
+	ctxt.FileSet.Iterate(func(f *token.File) bool {
+		if f.Name() == p.Filename && p.Offset <= f.Size() {
+			pos = f.Pos(p.Offset)
+		}
+		return true
+	})
+	return pos
+}
+
+type errorsByPos Errors
+
+func (e errorsByPos) Len() int           { return len(e) }
+func (e errorsByPos) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e errorsByPos) Less(i, j int) bool { return e[i].Pos < e[j].Pos }
@@ -0,0 +1,178 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIterateSymbs_errors(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		c := NewContext()
+		c.Strict = strict
+		var files []*ast.File
+		for _, name := range []string{"a.go", "b.go"} {
+			file, err := c.ParseFile(filepath.Join("testdata", "src", "errs", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, file)
+		}
+		err := c.IterateSymbs("errs", files, func(*Symb) bool { return true })
+		if err == nil {
+			t.Fatalf("strict=%v: got no error", strict)
+		}
+
+		var got []string
+		for _, e := range AsErrors(err) {
+			got = append(got, fmt.Sprintf("%s %s", filepath.Base(c.FileSet.Position(e.Pos).String()), e.Kind))
+		}
+		want := []string{"a.go:4:9 TypeError"}
+		if strict {
+			want = append(want, "a.go:4:9 Unresolved")
+		}
+		want = append(want, "b.go:3:8 Unsupported")
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("strict=%v: got errors %v, want %v", strict, got, want)
+		}
+	}
+}
+
+func TestAsErrors(t *testing.T) {
+	e1 := Error{Kind: TypeError, Msg: "a"}
+	e2 := Error{Kind: Unsupported, Msg: "b"}
+	pkgErrs := PackageErrors{
+		{ImportPath: "p", Err: Errors{e1}},
+		{ImportPath: "q", Err: errors.New("parse error")},
+		{ImportPath: "r", Err: e2},
+	}
+	if got, want := AsErrors(pkgErrs), []Error{e1, e2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := AsErrors(errors.New("x")); got != nil {
+		t.Errorf("got %v for a plain error, want nil", got)
+	}
+}
+
+func TestIterateSymbs_redeclared(t *testing.T) {
+	pkg, err := parseTestPkg("redecl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	var names []string
+	err = c.IterateSymbsPkg("redecl", pkg, func(x *Symb) bool {
+		names = append(names, x.Ident.Name)
+		return true
+	})
+
+	errs := AsErrors(err)
+	if len(errs) != 1 {
+		t.Fatalf("got errors %v, want one", errs)
+	}
+	e := errs[0]
+	if got, want := fmt.Sprintf("%s %s %s prev %s", shortPosition(e.Pos), e.Kind, e.Name, shortPosition(e.Prev)), "b.go:3 Redeclared Dup prev a.go:4"; got != want {
+		t.Errorf("got error %q, want %q", got, want)
+	}
+
+	// Everything but the name of the second Dup is still emitted.
+	want := []string{
+		"redecl", "Dup", "int", "n", "len", "n", "A", "int", "Dup",
+		"redecl", "int", "m", "cap", "int", "m", "B", "int", "Dup", "A",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got symbs %v, want %v", names, want)
+	}
+}
+
+func TestIterateSymbs_emitUnresolved(t *testing.T) {
+	pkg, err := parseTestPkg("unresolved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, emit := range []bool{false, true} {
+		c := NewContext()
+		c.FileSet = fset
+		c.EmitUnresolved = emit
+		c.TrackReferences = true
+		var unresolved []string
+		err = c.IterateSymbsPkg("unresolved", pkg, func(x *Symb) bool {
+			if x.Unresolved {
+				if x.ReferObj != nil {
+					t.Errorf("%s: got ReferObj %s for an unresolved symb", shortPosition(x.Ident.Pos()), x.ReferObj)
+				}
+				unresolved = append(unresolved, fmt.Sprintf("%s %s", shortPosition(x.Ident.Pos()), x.Ident.Name))
+			}
+			return true
+		})
+		if len(AsErrors(err)) != 2 {
+			t.Errorf("emit=%v: got errors %v, want two type errors", emit, err)
+		}
+
+		var want []string
+		if emit {
+			want = []string{
+				"unresolved.go:6 Missing",
+				"unresolved.go:7 undefinedFunc",
+			}
+		}
+		if !reflect.DeepEqual(unresolved, want) {
+			t.Errorf("emit=%v: got unresolved symbs %v, want %v", emit, unresolved, want)
+		}
+	}
+}
+
+// TestIterateSymbs_lostConstPos checks that a reference to a constant
+// whose position the checker lost (as older checkers did; see issue 5143)
+// is emitted as unresolved, without a ReferPos.
+func TestIterateSymbs_lostConstPos(t *testing.T) {
+	pkg, err := parseTestPkg("consts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := pkgFiles(pkg)
+	c := NewContext()
+	c.FileSet = fset
+	c.RetainChecks = true
+	if err := c.IterateSymbs("consts", files, func(*Symb) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the object of the reference to Green in favorite with a
+	// copy that has no position.
+	info := c.checked["consts"].info
+	var ref *ast.Ident
+	for id, obj := range info.Uses {
+		if id.Name == "Green" {
+			ref = id
+			c := obj.(*types.Const)
+			info.Uses[id] = types.NewConst(token.NoPos, c.Pkg(), c.Name(), c.Type(), c.Val())
+		}
+	}
+	if ref == nil {
+		t.Fatal("no reference to Green")
+	}
+
+	var got *Symb
+	err = c.IterateSymbs("consts", files, func(x *Symb) bool {
+		if x.Ident == ref {
+			got = x
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("got no symb for the reference to Green, want an unresolved one")
+	}
+	if !got.Unresolved || got.ReferPos.IsValid() || got.ReferObj == nil {
+		t.Errorf("got symb with Unresolved %v, ReferPos %v, ReferObj %v; want unresolved, with no position", got.Unresolved, got.ReferPos, got.ReferObj)
+	}
+}
@@ -0,0 +1,102 @@
+package symb
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+)
+
+// WriteEtags writes an Emacs TAGS file for the declarations among symbs,
+// which must have been emitted by ctxt, to w. References are ignored, as
+// are the declarations WriteCtags would not tag. The file has one section
+// per source file, in order of filename. Each tag's text is the prefix of
+// its declaration's line up to and including the declared name; the text
+// is taken from the contents of the files as they were parsed (see
+// SourceOf), so files analyzed from memory are tagged too. Sections are
+// named like the filenames of WriteCtags, relative to ctxt.BaseDir if it
+// is set. Methods are tagged both as Method and as Recv.Method so that
+// either name finds them.
+func (ctxt *Context) WriteEtags(w io.Writer, symbs []Symb) error {
+	tagsByFile := make(map[string][]etag)
+	names := make(map[string]string)      // section names by filename
+	filePos := make(map[string]token.Pos) // a position in each file, for ctxt.source
+	for i := range symbs {
+		x := &symbs[i]
+		if !x.IsDecl() || x.Synthetic || x.Anonymous || ctagKind(x) == 0 {
+			continue
+		}
+		// The tags address the files as parsed, whose text they
+		// include, so //line directives are ignored.
+		pos := ctxt.FileSet.PositionFor(x.Ident.Pos(), false)
+		tag := etag{
+			name:   x.Ident.Name,
+			line:   pos.Line,
+			offset: pos.Offset,
+			col:    pos.Column,
+		}
+		tagsByFile[pos.Filename] = append(tagsByFile[pos.Filename], tag)
+		names[pos.Filename] = relativeTo(x.baseDir, pos.Filename)
+		filePos[pos.Filename] = x.Ident.Pos()
+		if recv := recvTypeName(x); recv != "" {
+			tag.name = recv + "." + x.Ident.Name
+			tag.textLen = len(x.Ident.Name)
+			tagsByFile[pos.Filename] = append(tagsByFile[pos.Filename], tag)
+		}
+	}
+
+	filenames := make([]string, 0, len(tagsByFile))
+	for filename := range tagsByFile {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		src, err := ctxt.source(filePos[filename])
+		if err != nil {
+			return err
+		}
+		tags := tagsByFile[filename]
+		sort.Sort(etagsByOffset(tags))
+
+		var section bytes.Buffer
+		for _, tag := range tags {
+			lineStart := tag.offset - (tag.col - 1)
+			textLen := tag.textLen
+			if textLen == 0 {
+				textLen = len(tag.name)
+			}
+			if tag.offset+textLen > len(src) {
+				return fmt.Errorf("%s: tag %s is beyond the end of the file", filename, tag.name)
+			}
+			fmt.Fprintf(&section, "%s\x7f%s\x01%d,%d\n", src[lineStart:tag.offset+textLen], tag.name, tag.line, lineStart)
+		}
+		if _, err := fmt.Fprintf(w, "\x0c\n%s,%d\n", names[filename], section.Len()); err != nil {
+			return err
+		}
+		if _, err := section.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type etag struct {
+	name    string
+	line    int
+	offset  int // byte offset of the declared name
+	col     int
+	textLen int // length of the declared name, if it differs from len(name)
+}
+
+type etagsByOffset []etag
+
+func (t etagsByOffset) Len() int      { return len(t) }
+func (t etagsByOffset) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t etagsByOffset) Less(i, j int) bool {
+	if t[i].offset != t[j].offset {
+		return t[i].offset < t[j].offset
+	}
+	return t[i].name < t[j].name
+}
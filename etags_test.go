@@ -0,0 +1,51 @@
+package symb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteEtags(t *testing.T) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	var buf bytes.Buffer
+	if err := c.WriteEtags(&buf, collectSymbs("foo", pkg)); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "src", "foo", "TAGS_expected"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Errorf("got TAGS:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestWriteEtags_inMemory(t *testing.T) {
+	c := NewContext()
+	sources := map[string][]byte{
+		"/mem/a.go": []byte("package mem\n\nconst A = 1\n\nfunc F() int { return A }\n"),
+	}
+	var symbs []Symb
+	if err := c.ParseAndIterate("mem", sources, func(x *Symb) bool {
+		symbs = append(symbs, *x)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c.WriteEtags(&buf, symbs); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x0c\n/mem/a.go,29\nconst A\x7fA\x013,13\nfunc F\x7fF\x015,26\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got TAGS:\n%q\nwant:\n%q", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+)
+
+// DeclsOnly is a Context.Filter that keeps only declarations.
+func DeclsOnly(x *Symb) bool {
+	return x.IsDecl()
+}
+
+// ExportedOnly is a Context.Filter that keeps only the symbs that refer to
+// exported objects: package-level objects, fields, and methods whose names
+// are exported. Symbs that refer to function-local objects (whatever their
+// names), to universe objects, or to no object are dropped.
+func ExportedOnly(x *Symb) bool {
+	if x.ReferObj == nil || x.Local || x.Universe {
+		return false
+	}
+	return ast.IsExported(x.ReferObj.Name())
+}
+
+// NonLocal is a Context.Filter that drops the symbs that refer to
+// function-local objects.
+func NonLocal(x *Symb) bool {
+	return !x.Local
+}
+
+// refersToPackage reports whether x refers to an object of one of the
+// packages with the given import paths, as described for
+// Context.OnlyRefsTo.
+func refersToPackage(x *Symb, paths []string) bool {
+	if x.ReferObj == nil {
+		return false
+	}
+	var path string
+	switch {
+	case x.Local:
+		path = ""
+	case x.Universe:
+		path = "builtin"
+	default:
+		pkg := x.ReferObj.Pkg()
+		if pn, isPkgName := x.ReferObj.(*types.PkgName); isPkgName {
+			pkg = pn.Imported()
+		}
+		if pkg == nil {
+			return false
+		}
+		path = pkg.Path()
+	}
+	for _, p := range paths {
+		if p == path || p == "" && x.Universe {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package symb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContext_filter(t *testing.T) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(x *Symb) string {
+		return shortPosition(x.Ident.Pos()) + " " + x.Ident.Name
+	}
+
+	var all []string
+	for _, x := range collectSymbs("foo", pkg) {
+		all = append(all, key(&x))
+	}
+
+	// The filter sees every symb once, in emission order, and visitf sees
+	// those it keeps.
+	c := NewContext()
+	c.FileSet = fset
+	var seen, kept, visited []string
+	c.Filter = func(x *Symb) bool {
+		seen = append(seen, key(x))
+		if len(seen)%2 == 0 {
+			return false
+		}
+		kept = append(kept, key(x))
+		return true
+	}
+	err = c.IterateSymbsPkg("foo", pkg, func(x *Symb) bool {
+		visited = append(visited, key(x))
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(seen, all) {
+		t.Errorf("filter got symbs %v, want %v", seen, all)
+	}
+	if !reflect.DeepEqual(visited, kept) {
+		t.Errorf("visitf got symbs %v, want those kept by the filter %v", visited, kept)
+	}
+
+	// Stopping the iteration still works.
+	c = NewContext()
+	c.FileSet = fset
+	c.Filter = NonLocal
+	var n int
+	err = c.IterateSymbsPkg("foo", pkg, func(x *Symb) bool {
+		if x.Local {
+			t.Errorf("got local symb %s", key(x))
+		}
+		n++
+		return n < 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d symbs after visitf returned false, want 2", n)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		filter func(*Symb) bool
+		want   []string
+	}{
+		{"DeclsOnly", DeclsOnly, []string{"foo", "A", "b", "c", "d", "e", "f", "g", "bb", "NonLocalVar", "NonLocalType", "localRecv", "NonLocalFunc", "localParam", "localResult", "localVar", "main", "main.func1", "B", "eB", "fB"}},
+		{"ExportedOnly", ExportedOnly, []string{"A", "NonLocalVar", "NonLocalType", "NonLocalType", "NonLocalFunc", "NonLocalVar", "NonLocalFunc", "NonLocalType", "Usage", "Parse", "Println", "B", "A"}},
+	}
+	for _, test := range tests {
+		c := NewContext()
+		c.FileSet = fset
+		c.Filter = test.filter
+		var names []string
+		err := c.IterateSymbsPkg("foo", pkg, func(x *Symb) bool {
+			names = append(names, x.Ident.Name)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("%s: got symbs %v, want %v", test.name, names, test.want)
+		}
+	}
+
+	// A Graph built with a filter observes only the kept symbs.
+	c := NewContext()
+	c.FileSet = fset
+	c.Filter = DeclsOnly
+	g, err := BuildGraph(c, "foo", pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range g.Defs() {
+		if n := g.RefCount(obj); n != 0 {
+			t.Errorf("got %d refs to %s in a graph of declarations, want 0", n, obj.Name())
+		}
+	}
+}
+
+func TestContext_onlyRefsTo(t *testing.T) {
+	tests := []struct {
+		pkgPath    string
+		onlyRefsTo []string
+		want       []string
+	}{
+		{"bar", []string{"foo"}, []string{"foo", "A"}},
+		{"bar", []string{"fmt"}, nil},
+		{"builtins", []string{"builtin"}, []string{"iota", "int", "int", "append", "len", "error", "string", "recover", "panic", "Error"}},
+		{"builtins", []string{""}, []string{"iota", "s", "int", "int", "append", "s", "len", "s", "err", "error", "string", "recover", "panic", "err", "Error"}},
+	}
+	for _, test := range tests {
+		pkg, err := parseTestPkg(test.pkgPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewContext()
+		c.FileSet = fset
+		c.OnlyRefsTo = test.onlyRefsTo
+		var names []string
+		err = c.IterateSymbsPkg(test.pkgPath, pkg, func(x *Symb) bool {
+			names = append(names, x.Ident.Name)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("%s with OnlyRefsTo %q: got symbs %v, want %v", test.pkgPath, test.onlyRefsTo, names, test.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"crypto/sha1"
+	"fmt"
+	"io"
+)
+
+// Fingerprint returns a digest of the declaration x, for telling whether
+// a declaration has changed between runs: a hex-encoded SHA-1 hash of its
+// QualifiedName, the kind of object it declares, and the canonical string
+// of what it declares (the signature of a function, the underlying type of
+// a type, the type and value of a constant, and the type of a variable).
+// Positions and comments do not contribute, so moving a declaration does
+// not change its fingerprint. Fingerprint returns "" if x is not a
+// declaration.
+func (x *Symb) Fingerprint() string {
+	if x.ReferObj == nil || !x.IsDecl() {
+		return ""
+	}
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n%s\n", x.QualifiedName(), objectIsa(x.ReferObj))
+	switch obj := x.ReferObj.(type) {
+	case *types.PkgName:
+		io.WriteString(h, obj.Imported().Path())
+	case *types.TypeName:
+		io.WriteString(h, obj.Type().Underlying().String())
+	case *types.Const:
+		fmt.Fprintf(h, "%s\n%s", obj.Type(), obj.Val())
+	default:
+		if obj.Type() != nil {
+			io.WriteString(h, obj.Type().String())
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
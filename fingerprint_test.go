@@ -0,0 +1,63 @@
+package symb
+
+import (
+	"strings"
+	"testing"
+)
+
+// fingerprints returns the fingerprints of the declarations in src, by
+// qualified name.
+func fingerprints(t *testing.T, src string) map[string]string {
+	fps := make(map[string]string)
+	err := IterateSource("fp.go", src, func(x *Symb) bool {
+		if x.IsDecl() {
+			fps[x.QualifiedName()] = x.Fingerprint()
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fps
+}
+
+const fingerprintSrc = `package fp
+
+// T is a type.
+type T struct{ N int }
+
+const C = 3
+
+func F(t T, n int) string { return "" }
+`
+
+func TestSymb_Fingerprint(t *testing.T) {
+	orig := fingerprints(t, fingerprintSrc)
+	for _, name := range []string{"fp.T", "fp.C", "fp.F"} {
+		if orig[name] == "" {
+			t.Fatalf("%s: got no fingerprint", name)
+		}
+	}
+
+	again := fingerprints(t, fingerprintSrc)
+	for name, fp := range orig {
+		if again[name] != fp {
+			t.Errorf("%s: got fingerprint %s on the second run, want %s", name, again[name], fp)
+		}
+	}
+
+	moved := fingerprints(t, strings.Replace(fingerprintSrc, "\nconst", "\n\n// More comments.\nconst", 1))
+	for name, fp := range orig {
+		if moved[name] != fp {
+			t.Errorf("%s: got fingerprint %s after inserting lines, want %s", name, moved[name], fp)
+		}
+	}
+
+	changed := fingerprints(t, strings.Replace(fingerprintSrc, "n int)", "n int64)", 1))
+	if changed["fp.F"] == orig["fp.F"] {
+		t.Error("fp.F: got the same fingerprint after changing a parameter type")
+	}
+	if changed["fp.T"] != orig["fp.T"] {
+		t.Error("fp.T: got a different fingerprint after changing an unrelated declaration")
+	}
+}
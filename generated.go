@@ -0,0 +1,30 @@
+package symb
+
+import (
+	"go/ast"
+	"regexp"
+)
+
+// generatedRx matches the comment line that marks a file as generated, per
+// the convention described at https://golang.org/s/generatedcode.
+var generatedRx = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// IsGenerated reports whether file is machine-generated: that is, whether
+// a line comment before its package clause is of the form
+//
+//	// Code generated ... DO NOT EDIT.
+//
+// The file must have been parsed with parser.ParseComments.
+func IsGenerated(file *ast.File) bool {
+	for _, cg := range file.Comments {
+		if cg.Pos() >= file.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if generatedRx.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
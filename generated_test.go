@@ -0,0 +1,57 @@
+package symb
+
+import (
+	"go/build"
+	"go/parser"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"// Code generated by stringer. DO NOT EDIT\n\npackage p\n", false},
+		{"// Code generated by stringer. DO NOT EDIT.\n\npackage p\n", true},
+		{"// Copyright 2013.\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage p\n", true},
+		{"// Package p does things.\npackage p\n", false},
+		{"package p\n\n// Code generated by stringer. DO NOT EDIT.\n", false},
+	}
+	for _, test := range tests {
+		file, err := parser.ParseFile(fset, "p.go", test.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := IsGenerated(file); got != test.want {
+			t.Errorf("IsGenerated(%q) = %v, want %v", test.src, got, test.want)
+		}
+	}
+}
+
+func TestSkipGenerated(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+
+	c := NewContext()
+	c.SkipGenerated = true
+	var refs int
+	err := c.IterateImportPath("generated", func(symb *Symb) bool {
+		if filename := filepath.Base(c.FileSet.Position(symb.Ident.Pos()).Filename); filename != "handwritten.go" {
+			t.Errorf("got symb %s in %s, want only symbs in handwritten.go", symb.Ident.Name, filename)
+		}
+		switch symb.Ident.Name {
+		case "GeneratedType", "GeneratedFunc":
+			if filename := filepath.Base(c.FileSet.Position(symb.ReferPos).Filename); filename != "generated.go" {
+				t.Errorf("got reference to %s declared in %q, want generated.go", symb.Ident.Name, filename)
+			}
+			refs++
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs != 2 {
+		t.Errorf("got %d references to generated decls, want 2", refs)
+	}
+}
@@ -0,0 +1,96 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// A Graph maps objects to the positions of their declarations and of the
+// references to them. A Graph is built by passing its Add method as the
+// visitf of one or more iterations (or by calling BuildGraph), merging
+// references to the same object across all files of a package.
+type Graph struct {
+	defs map[types.Object]token.Pos
+	refs map[types.Object][]token.Pos
+}
+
+// NewGraph returns an empty Graph.
+func NewGraph() *Graph {
+	return &Graph{
+		defs: make(map[types.Object]token.Pos),
+		refs: make(map[types.Object][]token.Pos),
+	}
+}
+
+// BuildGraph returns a Graph of the symbs in pkg, whose import path is
+// importPath.
+func BuildGraph(ctxt *Context, importPath string, pkg *ast.Package) (*Graph, error) {
+	g := NewGraph()
+	err := ctxt.IterateSymbsPkg(importPath, pkg, g.Add)
+	return g, err
+}
+
+// Add adds x to the graph, as the declaration of x.ReferObj if x is a
+// declaration and as a reference to it otherwise. Symbs that do not refer
+// to an object are ignored. Add always returns true, so it can be used as
+// the visitf of an iteration.
+func (g *Graph) Add(x *Symb) bool {
+	if x.ReferObj == nil {
+		return true
+	}
+	if x.IsDecl() {
+		g.defs[x.ReferObj] = x.pos()
+	} else {
+		g.refs[x.ReferObj] = append(g.refs[x.ReferObj], x.Ident.Pos())
+	}
+	return true
+}
+
+// Def returns the position of obj's declaration, or token.NoPos if the
+// declaration has not been added to the graph.
+func (g *Graph) Def(obj types.Object) token.Pos {
+	return g.defs[obj]
+}
+
+// Defs returns the objects whose declarations have been added to the
+// graph, in order of declaration position.
+func (g *Graph) Defs() []types.Object {
+	objs := make([]types.Object, 0, len(g.defs))
+	for obj := range g.defs {
+		objs = append(objs, obj)
+	}
+	sort.Sort(objectsByPos{objs, g.defs})
+	return objs
+}
+
+// Refs returns the positions of the references to obj, excluding its
+// declaration, in ascending order.
+func (g *Graph) Refs(obj types.Object) []token.Pos {
+	refs := make([]token.Pos, len(g.refs[obj]))
+	copy(refs, g.refs[obj])
+	sort.Sort(posSlice(refs))
+	return refs
+}
+
+// RefCount returns the number of references to obj, excluding its
+// declaration.
+func (g *Graph) RefCount(obj types.Object) int {
+	return len(g.refs[obj])
+}
+
+type objectsByPos struct {
+	objs []types.Object
+	pos  map[types.Object]token.Pos
+}
+
+func (s objectsByPos) Len() int           { return len(s.objs) }
+func (s objectsByPos) Swap(i, j int)      { s.objs[i], s.objs[j] = s.objs[j], s.objs[i] }
+func (s objectsByPos) Less(i, j int) bool { return s.pos[s.objs[i]] < s.pos[s.objs[j]] }
+
+type posSlice []token.Pos
+
+func (p posSlice) Len() int           { return len(p) }
+func (p posSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p posSlice) Less(i, j int) bool { return p[i] < p[j] }
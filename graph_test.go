@@ -0,0 +1,52 @@
+package symb
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildGraph(t *testing.T) {
+	pkg, err := parseTestPkg("graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	g, err := BuildGraph(c, "graph", pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, obj := range g.Defs() {
+		names = append(names, obj.Name())
+	}
+	if want := []string{"graph", "calls", "Helper", "one"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got defs %v, want %v", names, want)
+	}
+
+	helper := g.Defs()[2]
+	if n := g.RefCount(helper); n != 3 {
+		t.Errorf("got %d refs to Helper, want 3", n)
+	}
+	var refs []string
+	for _, ref := range g.Refs(helper) {
+		refs = append(refs, shortPosition(ref))
+	}
+	if want := []string{"calls.go:4", "calls.go:5", "helper.go:7"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("got refs to Helper %v, want %v", refs, want)
+	}
+	if def := shortPosition(g.Def(helper)); def != "helper.go:3" {
+		t.Errorf("got def of Helper at %s, want helper.go:3", def)
+	}
+}
+
+// shortPosition returns "file:line" for pos in fset, where file is the base
+// name of the file.
+func shortPosition(pos token.Pos) string {
+	p := fset.Position(pos)
+	return fmt.Sprintf("%s:%d", filepath.Base(p.Filename), p.Line)
+}
@@ -0,0 +1,85 @@
+package symb
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// UpdateFile replaces the file named filename in the package with the given
+// import path, which must be the package most recently iterated over (or,
+// if ctxt.RetainChecks is set, any package iterated over before), with
+// newAST, and type-checks the package again. newAST must have been parsed
+// into the Context's FileSet. If the package has no file named filename,
+// newAST is added to it. Packages that the package imports are not
+// re-imported. The error, if any, is that of the type checker.
+//
+// Use IterateFileSymbs to walk the updated file.
+func (ctxt *Context) UpdateFile(importPath, filename string, newAST *ast.File) error {
+	prev := ctxt.cachedCheck(importPath)
+	if prev == nil {
+		return fmt.Errorf("package %s has not been checked", importPath)
+	}
+
+	files := make([]*ast.File, 0, len(prev.files)+1)
+	for _, f := range prev.files {
+		if ctxt.Filename(f) != filename {
+			files = append(files, f)
+		}
+	}
+	files = ctxt.sortFiles(append(files, newAST))
+
+	// The whole package is checked again. This is the place for an
+	// incremental checker to reuse the parts of prev that newAST does not
+	// affect.
+	r := ctxt.check(importPath, files, prev.deps, false)
+	return joinErrors(r.errs, nil)
+}
+
+// IterateFileSymbs calls visitf for each symb in the file named filename in
+// the package with the given import path, using the result of the most
+// recent type-check of the package (by IterateSymbs, UpdateFile, or
+// another iteration method) rather than checking it again. The symbs of
+// the package's other files are not emitted. If visitf returns false, the
+// iteration stops.
+func (ctxt *Context) IterateFileSymbs(importPath, filename string, visitf func(symb *Symb) bool) error {
+	r := ctxt.cachedCheck(importPath)
+	if r == nil {
+		return fmt.Errorf("package %s has not been checked", importPath)
+	}
+	if r.funcBodiesIgnored {
+		// Only IterateExportedDecls has checked the package.
+		r = ctxt.check(importPath, r.files, r.deps, false)
+	}
+	for _, f := range r.files {
+		if ctxt.Filename(f) == filename {
+			job := &checkJob{importPath: importPath, files: r.files, walk: []*ast.File{f}, checked: r}
+			return ctxt.iterate(job, visitf)
+		}
+	}
+	return fmt.Errorf("package %s has no file %s", importPath, filename)
+}
+
+// IterateFile calls visitf for each symb in target, one of files, which
+// are the files of the package with the given import path. The whole
+// package is type-checked (or its cached check reused, as for
+// IterateSymbs), so that target's references to the package's other files
+// resolve, but only target is walked: the symbs emitted are those that
+// IterateSymbs would emit for target, which is cheaper to find for a
+// package with many files. If visitf returns false, the iteration stops.
+func (ctxt *Context) IterateFile(importPath string, files []*ast.File, target *ast.File, visitf func(symb *Symb) bool) error {
+	for _, f := range files {
+		if f == target {
+			job := &checkJob{importPath: importPath, files: ctxt.sortFiles(files), walk: []*ast.File{target}}
+			return ctxt.iterate(job, visitf)
+		}
+	}
+	return fmt.Errorf("package %s has no file %s", importPath, ctxt.Filename(target))
+}
+
+// cachedCheck returns the cached result of type-checking the package with
+// the given import path, or nil if there is none.
+func (ctxt *Context) cachedCheck(importPath string) *checkResult {
+	ctxt.checkedMu.Lock()
+	defer ctxt.checkedMu.Unlock()
+	return ctxt.checked[importPath]
+}
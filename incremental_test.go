@@ -0,0 +1,165 @@
+package symb
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateFile(t *testing.T) {
+	c := NewContext()
+	dir := filepath.Join("testdata", "src", "crossfile")
+	aFilename, bFilename := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	var files []*ast.File
+	for _, filename := range []string{aFilename, bFilename} {
+		file, err := c.ParseFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	if err := c.IterateSymbs("crossfile", files, func(*Symb) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace b.go's declaration of T with a different one, and add Fresh.
+	newB, err := parser.ParseFile(c.FileSet, bFilename, "package crossfile\n\ntype T string\n\nfunc Fresh() T { return \"\" }\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateFile("crossfile", bFilename, newB); err != nil {
+		t.Fatal(err)
+	}
+
+	symbsIn := func(filename string) []*Symb {
+		var symbs []*Symb
+		err := c.IterateFileSymbs("crossfile", filename, func(symb *Symb) bool {
+			symbs = append(symbs, symb)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return symbs
+	}
+
+	var names []string
+	var declT *Symb
+	for _, symb := range symbsIn(bFilename) {
+		if symb.File != newB {
+			t.Errorf("got symb %s in a file other than the updated b.go", symb.Ident.Name)
+		}
+		if symb.Ident.Name == "T" && symb.IsDecl() {
+			declT = symb
+		}
+		names = append(names, symb.Ident.Name)
+	}
+	if want := []string{"crossfile", "T", "string", "Fresh", "T"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got symbs %v in updated b.go, want %v", names, want)
+	}
+	if declT == nil {
+		t.Fatal("no decl of T in updated b.go")
+	}
+
+	// References in the other files of the package resolve to the new T.
+	for _, symb := range symbsIn(aFilename) {
+		if symb.Ident.Name == "T" && symb.ReferObj != declT.ReferObj {
+			t.Errorf("reference to T in a.go refers to %v at %s, want the updated decl", symb.ReferObj, c.FileSet.Position(symb.ReferPos))
+		}
+	}
+}
+
+func TestIterateFileSymbs_unchecked(t *testing.T) {
+	c := NewContext()
+	if err := c.IterateFileSymbs("crossfile", "a.go", func(*Symb) bool { return true }); err == nil {
+		t.Error("got no error for a package that has not been checked")
+	}
+}
+
+func TestIterateFile(t *testing.T) {
+	pkg, err := parseTestPkg("crossfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := pkgFiles(pkg)
+	c := NewContext()
+	c.FileSet = fset
+	var all []Symb
+	if err := c.IterateSymbs("crossfile", files, func(x *Symb) bool {
+		all = append(all, *x)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		var want, got []Symb
+		for _, x := range all {
+			if x.File == file {
+				want = append(want, x)
+			}
+		}
+		if err := c.IterateFile("crossfile", files, file, func(x *Symb) bool {
+			got = append(got, *x)
+			return true
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if len(want) == 0 {
+			t.Fatalf("%s: no symbs in the full iteration", c.Filename(file))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got symbs %v, want %v", c.Filename(file), pp(got), pp(want))
+		}
+	}
+
+	other, err := parser.ParseFile(c.FileSet, "other.go", "package crossfile\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.IterateFile("crossfile", files, other, func(*Symb) bool { return true }); err == nil {
+		t.Errorf("got no error for a file not in the package")
+	}
+}
+
+// benchmarkIterateFile walks one file of a package of many files, or the
+// whole package, after the package has been checked.
+func benchmarkIterateFile(b *testing.B, one bool) {
+	c := NewContext()
+	var files []*ast.File
+	for i := 0; i < 50; i++ {
+		src := fmt.Sprintf("package many\n\ntype T%d struct{ N int }\n\nfunc F%d(t T%d) int {\n", i, i, i)
+		for j := 0; j < 50; j++ {
+			src += fmt.Sprintf("\tt.N += F%d(T%d{t.N})\n", (i+1)%50, (i+1)%50)
+		}
+		src += "\treturn t.N\n}\n"
+		file, err := parser.ParseFile(c.FileSet, fmt.Sprintf("f%d.go", i), src, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	visitf := func(*Symb) bool { return true }
+	if err := c.IterateSymbs("many", files, visitf); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if one {
+			err = c.IterateFile("many", files, files[0], visitf)
+		} else {
+			err = c.IterateSymbs("many", files, visitf)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIterateFile(b *testing.B)     { benchmarkIterateFile(b, true) }
+func BenchmarkIterateFile_all(b *testing.B) { benchmarkIterateFile(b, false) }
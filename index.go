@@ -0,0 +1,105 @@
+package symb
+
+import (
+	"sort"
+)
+
+// An Index is a collection of symbs that can be looked up by name. An
+// Index is populated by passing its Add method as the visitf of one or
+// more iterations:
+//
+//	idx := NewIndex()
+//	err := ctxt.IterateSymbs(importPath, files, idx.Add)
+//
+// By default only declarations are indexed.
+type Index struct {
+	// IncludeRefs causes references to be indexed in addition to
+	// declarations. It must be set before symbs are added.
+	IncludeRefs bool
+
+	symbs   []Symb
+	byName  map[string][]int // indexes into symbs (and records)
+	records []IndexRecord    // records of symbs, if computed since the last Add
+	loaded  bool             // whether the index was read by LoadIndex, and has only records
+}
+
+// NewIndex returns an empty Index.
+func NewIndex() *Index {
+	return &Index{byName: make(map[string][]int)}
+}
+
+// Add adds a copy of x to the index if it is a declaration (or if
+// idx.IncludeRefs is set). Symbs that do not refer to an object are
+// ignored. Add always returns true, so it can be used as the visitf of an
+// iteration.
+func (idx *Index) Add(x *Symb) bool {
+	if idx.loaded || x.ReferObj == nil || !(idx.IncludeRefs || x.IsDecl()) {
+		return true
+	}
+	idx.records = nil
+	name := x.name()
+	idx.byName[name] = append(idx.byName[name], len(idx.symbs))
+	idx.symbs = append(idx.symbs, *x)
+	return true
+}
+
+// Lookup returns the indexed symbs whose identifier is name, in the order
+// in which they were added. It panics if idx was returned by LoadIndex,
+// which keeps only records; use LookupRecords instead.
+func (idx *Index) Lookup(name string) []Symb {
+	idx.needSymbs("Lookup", "LookupRecords")
+	is := idx.byName[name]
+	symbs := make([]Symb, len(is))
+	for j, i := range is {
+		symbs[j] = idx.symbs[i]
+	}
+	return symbs
+}
+
+// LookupQualified returns the indexed symbs whose identifier is name and
+// that refer to an object in the package with import path pkgPath. It
+// panics if idx was returned by LoadIndex; use LookupQualifiedRecords
+// instead.
+func (idx *Index) LookupQualified(pkgPath, name string) []Symb {
+	idx.needSymbs("LookupQualified", "LookupQualifiedRecords")
+	var symbs []Symb
+	for _, i := range idx.byName[name] {
+		x := &idx.symbs[i]
+		if x.ReferObj == nil {
+			continue
+		}
+		if pkg := x.ReferObj.Pkg(); pkg != nil && pkg.Path() == pkgPath {
+			symbs = append(symbs, *x)
+		}
+	}
+	return symbs
+}
+
+// Decls returns all indexed declarations, sorted by position. It panics if
+// idx was returned by LoadIndex; use DeclRecords instead.
+func (idx *Index) Decls() []Symb {
+	idx.needSymbs("Decls", "DeclRecords")
+	var decls []Symb
+	for i := range idx.symbs {
+		if idx.symbs[i].IsDecl() {
+			decls = append(decls, idx.symbs[i])
+		}
+	}
+	sort.Sort(symbsByPos(decls))
+	return decls
+}
+
+// needSymbs panics if idx was returned by LoadIndex, and so has no symbs
+// for method to return, rather than let method report that nothing
+// matched. alt is the method that answers from the records instead.
+func (idx *Index) needSymbs(method, alt string) {
+	if idx.loaded {
+		panic("symb: Index." + method + " called on an Index returned by LoadIndex, which has only records; use " + alt)
+	}
+}
+
+type symbsByPos []Symb
+
+func (s symbsByPos) Len() int           { return len(s) }
+func (s symbsByPos) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s symbsByPos) Less(i, j int) bool { return s[i].pos() < s[j].pos() }
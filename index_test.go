@@ -0,0 +1,78 @@
+package symb
+
+import (
+	"go/build"
+	"path/filepath"
+	"testing"
+)
+
+func buildTestIndex(t *testing.T, includeRefs bool) *Index {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+	idx := NewIndex()
+	idx.IncludeRefs = includeRefs
+	c := NewContext()
+	err := c.IterateTree(filepath.Join(build.Default.GOPATH, "src", "index"), func(pkgPath string, symb *Symb) bool {
+		return idx.Add(symb)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+func TestIndex_Lookup(t *testing.T) {
+	idx := buildTestIndex(t, false)
+
+	tests := []struct {
+		name     string
+		wantPkgs []string
+	}{
+		{"Area", []string{"index/shapes"}},
+		{"Radius", []string{"index/shapes"}},
+		{"New", []string{"index/other", "index/shapes"}},
+		{"radius", nil}, // function-local, but still a declaration
+		{"nonexistent", nil},
+	}
+	for _, test := range tests {
+		symbs := idx.Lookup(test.name)
+		if test.name == "radius" {
+			if len(symbs) != 1 || !symbs[0].Local {
+				t.Errorf("Lookup(%q): got %v, want one local declaration", test.name, symbs)
+			}
+			continue
+		}
+		if len(symbs) != len(test.wantPkgs) {
+			t.Errorf("Lookup(%q): got %d symbs, want %d", test.name, len(symbs), len(test.wantPkgs))
+			continue
+		}
+		for i, symb := range symbs {
+			if !symb.IsDecl() {
+				t.Errorf("Lookup(%q): got reference %s, want only declarations", test.name, pretty(symb.Expr))
+			}
+			if pkgPath := symb.ReferObj.Pkg().Path(); pkgPath != test.wantPkgs[i] {
+				t.Errorf("Lookup(%q): got symb %d in %s, want %s", test.name, i, pkgPath, test.wantPkgs[i])
+			}
+		}
+	}
+
+	if symbs := idx.LookupQualified("index/shapes", "New"); len(symbs) != 1 {
+		t.Errorf("LookupQualified: got %d symbs, want 1", len(symbs))
+	}
+}
+
+func TestIndex_includeRefs(t *testing.T) {
+	idx := buildTestIndex(t, true)
+
+	// Radius is declared once and referenced three times, once as a key
+	// in a composite literal.
+	if n := len(idx.Lookup("Radius")); n != 4 {
+		t.Errorf("got %d symbs for Radius, want 4", n)
+	}
+
+	decls := idx.Decls()
+	for i := 1; i < len(decls); i++ {
+		if decls[i-1].Ident.Pos() >= decls[i].Ident.Pos() {
+			t.Errorf("decls are not sorted by position: %s before %s", decls[i-1].Ident.Name, decls[i].Ident.Name)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// A Violation is a reference from one package to an object declared in an
+// internal package that the referring package is not allowed to use.
+type Violation struct {
+	Pos     token.Pos    // position of the violating reference
+	FromPkg string       // import path of the referring package
+	ToPkg   string       // import path of the internal package
+	Obj     types.Object // object referred to
+}
+
+// InternalBoundaryViolations returns the references in byPkg (a map from
+// import path to the symbs emitted for that package) that refer to objects
+// in an internal package from outside the tree rooted at the internal
+// package's parent. Only references to packages whose import paths begin
+// with rootPrefix are considered; an empty rootPrefix considers all
+// packages. The violations are sorted by referring package and position.
+func InternalBoundaryViolations(byPkg map[string][]Symb, rootPrefix string) []Violation {
+	var vs []Violation
+	for fromPkg, symbs := range byPkg {
+		for _, symb := range symbs {
+			if symb.ReferObj == nil || symb.Universe || symb.ReferObj.Pkg() == nil {
+				continue
+			}
+			if _, isPkg := symb.ReferObj.(*types.PkgName); isPkg {
+				// The package name in a qualified identifier is always
+				// accompanied by a reference to the selected object.
+				continue
+			}
+			toPkg := symb.ReferObj.Pkg().Path()
+			if toPkg == fromPkg || !strings.HasPrefix(toPkg, rootPrefix) {
+				continue
+			}
+			if !canReferToInternal(fromPkg, toPkg) {
+				vs = append(vs, Violation{
+					Pos:     symb.pos(),
+					FromPkg: fromPkg,
+					ToPkg:   toPkg,
+					Obj:     symb.ReferObj,
+				})
+			}
+		}
+	}
+	sort.Sort(violationsByPos(vs))
+	return vs
+}
+
+// canReferToInternal reports whether package from may refer to objects in
+// package to under the internal-visibility rule: an import path containing
+// an internal element may only be used from within the tree rooted at the
+// parent of the last internal element. An external test package, whose
+// import path is that of the tested package plus "_test", is in the
+// directory of the tested package.
+func canReferToInternal(from, to string) bool {
+	from = strings.TrimSuffix(from, "_test")
+	var parent string
+	switch {
+	case strings.HasSuffix(to, "/internal"):
+		parent = strings.TrimSuffix(to, "/internal")
+	case strings.Contains(to, "/internal/"):
+		parent = to[:strings.LastIndex(to, "/internal/")]
+	default:
+		// Not internal, or internal to the whole source tree.
+		return true
+	}
+	return from == parent || strings.HasPrefix(from, parent+"/")
+}
+
+type violationsByPos []Violation
+
+func (vs violationsByPos) Len() int      { return len(vs) }
+func (vs violationsByPos) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+func (vs violationsByPos) Less(i, j int) bool {
+	if vs[i].FromPkg != vs[j].FromPkg {
+		return vs[i].FromPkg < vs[j].FromPkg
+	}
+	return vs[i].Pos < vs[j].Pos
+}
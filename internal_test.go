@@ -0,0 +1,65 @@
+package symb
+
+import (
+	"go/build"
+	"path/filepath"
+	"testing"
+)
+
+func TestInternalBoundaryViolations(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+
+	c := NewContext()
+	c.IncludeTests = true
+	byPkg := make(map[string][]Symb)
+	err := c.IterateTree(filepath.Join(build.Default.GOPATH, "src", "monorepo"), func(pkgPath string, symb *Symb) bool {
+		byPkg[pkgPath] = append(byPkg[pkgPath], *symb)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, present := byPkg["monorepo/a/b_test"]; !present {
+		t.Fatal("got no symbs from the external test package monorepo/a/b_test")
+	}
+
+	vs := InternalBoundaryViolations(byPkg, "monorepo/")
+	if len(vs) != 1 {
+		t.Fatalf("got %d violations %v, want 1", len(vs), vs)
+	}
+	v := vs[0]
+	if v.FromPkg != "monorepo/c" || v.ToPkg != "monorepo/a/internal/secret" || v.Obj.Name() != "Value" {
+		t.Errorf("got violation %+v, want reference from monorepo/c to monorepo/a/internal/secret.Value", v)
+	}
+	if pos := c.FileSet.Position(v.Pos); filepath.Base(pos.Filename) != "c.go" || pos.Line != 5 || pos.Column != 22 {
+		t.Errorf("got violation at %v, want c.go:5:22", pos)
+	}
+}
+
+func TestCanReferToInternal(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"a/b", "a/internal/x", true},
+		{"a", "a/internal/x", true},
+		{"c", "a/internal/x", false},
+		{"ab", "a/internal/x", false},
+		{"a/internal/y", "a/internal/x", true},
+		{"a/b", "a/internal", true},
+		{"c", "a/internal", false},
+		{"a", "a/internal/x/internal/y", false},
+		{"a/internal/x/z", "a/internal/x/internal/y", true},
+		{"c", "internal/x", true},
+		{"c", "a/x", true},
+		{"a/b_test", "a/b/internal/x", true},
+		{"a/b_test", "a/internal/x", true},
+		{"c_test", "a/internal/x", false},
+	}
+	for _, test := range tests {
+		if got := canReferToInternal(test.from, test.to); got != test.want {
+			t.Errorf("canReferToInternal(%q, %q) = %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+}
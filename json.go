@@ -0,0 +1,308 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"encoding/json"
+	"go/token"
+)
+
+// SetFileSet sets the FileSet used to resolve x's positions when it is
+// marshalled to JSON. Symbs emitted by a Context already use the Context's
+// FileSet.
+func (x *Symb) SetFileSet(fset *token.FileSet) {
+	x.fset = fset
+}
+
+// MarshalJSON implements json.Marshaler. A Symb is encoded as an object
+// with the following fields:
+//
+//	Expr         the pretty-printed Expr
+//	Ident        the identifier's name
+//	IdentPos     the position of Ident
+//	IdentEnd     the position immediately after Ident
+//	ExprType     the string form of ExprType, or "" if it is nil
+//	Pkg          the package the symb was found in, or null
+//	FileName     the package name in the file's package clause
+//	ReferPos     the position of the referred-to object
+//	ReferFile    the name of the file declaring the referred-to object
+//	ReferPkgDir  ReferPkgDir, omitted if it is empty
+//	ReferObj     the referred-to object
+//	Local        whether the referred-to object is function-local
+//	Universe     whether the referred-to object is in the universe scope
+//	Builtin      whether the referred-to object is a builtin function
+//	Container    the name of Container, omitted if there is none
+//	Visibility   the string form of Visibility(), omitted if it is NoVisibility
+//	ConstVal     the string form of ConstVal, omitted if it is nil
+//	ConstGroup   the position of ConstGroup, omitted if it is unknown
+//	ConstIndex   ConstIndex, omitted if ConstGroup is
+//	IotaBased    whether the constant is iota-based, omitted if it is not
+//	DeclGroup    the position of DeclGroup, omitted if it is unknown
+//	SpecIndex    SpecIndex, omitted if DeclGroup is
+//	NameIndex    NameIndex, omitted if DeclGroup is
+//	Tag          the struct field's tag, omitted if it is empty
+//	Captured     whether the variable is captured, omitted if it is not
+//	Anonymous    whether the symb declares a function literal, omitted if it is not
+//	Form         "MethodValue" or "MethodExpr", omitted for other symbs
+//	Recv         the string form of Recv, omitted if it is nil
+//	Indirect     whether the selection dereferenced a pointer, omitted if it did not
+//	IsRecv       whether the referred-to object is a receiver, omitted if it is not
+//	Embedded     whether the symb names an embedded type, omitted if it does not
+//	DeclForm     the string form of DeclForm, omitted if it is NoDeclForm
+//	Implicit     whether the symb is synthesized for an elided type or an unkeyed
+//	             field, omitted if it is not
+//	InComment    whether the symb is a mention in a doc comment, omitted if it is not
+//	IsConversion whether the symb is the type of a conversion, omitted if it is not
+//	CallContext  "PlainCall", "DeferCall", or "GoCall", omitted for other symbs
+//	PkgDoc       PkgDoc, omitted if it is empty
+//	Unresolved   whether the identifier has no object, omitted if it has one
+//	FromTestFile whether the symb was found in a _test.go file, omitted if it was not
+//	IsDecl       whether the symb is the declaration of the object
+//	Synthetic    whether the symb is synthetic, omitted if it is not
+//
+// Synthetic symbs have no Expr or Ident, so those fields are empty and
+// IdentPos and IdentEnd are zero.
+//
+// Positions are objects with Filename, Offset (in bytes), Line, and Column
+// fields, resolved through the symb's FileSet, with filenames relative to
+// the Context's BaseDir if it is set; they are zero if the position is
+// unknown. The Filename, Line, and Column honor //line directives if the
+// Context's AdjustedPositions is set, but the Offset is always within the
+// file as parsed. Packages are objects with Isa ("Package"), Name, and
+// ImportPath fields. Other objects have Isa (one of "Const", "TypeName",
+// "Var", "Func", "Builtin", or "Nil"), Pkg, Name, and Type (null for
+// builtins) fields; constants additionally have their value in Val.
+func (x Symb) MarshalJSON() ([]byte, error) {
+	var exprType string
+	if x.ExprType != nil {
+		exprType = x.ExprType.String()
+	}
+	var expr, ident string
+	var identPos, identEnd token.Position
+	if !x.Synthetic {
+		expr, ident = pretty(x.Expr), pretty(x.Ident)
+		identPos, identEnd = x.Position(nil), x.position(x.Ident.End())
+	}
+	var constVal string
+	if x.ConstVal != nil {
+		constVal = x.ConstVal.String()
+	}
+	var constGroup *token.Position
+	var constIndex *int
+	if x.ConstGroup.IsValid() {
+		pos := x.position(x.ConstGroup)
+		constGroup, constIndex = &pos, &x.ConstIndex
+	}
+	var declGroup *token.Position
+	var specIndex, nameIndex *int
+	if x.DeclGroup.IsValid() {
+		pos := x.position(x.DeclGroup)
+		declGroup, specIndex, nameIndex = &pos, &x.SpecIndex, &x.NameIndex
+	}
+	var visibility string
+	if v := x.Visibility(); v != NoVisibility {
+		visibility = v.String()
+	}
+	var form string
+	if x.Form != OrdinarySelection {
+		form = x.Form.String()
+	}
+	var recv string
+	if x.Recv != nil {
+		recv = x.Recv.String()
+	}
+	var declForm string
+	if x.DeclForm != NoDeclForm {
+		declForm = x.DeclForm.String()
+	}
+	var callContext string
+	if x.CallContext != NoCall {
+		callContext = x.CallContext.String()
+	}
+	var fileName string
+	if x.File != nil {
+		fileName = x.File.Name.Name
+	}
+	return json.Marshal(struct {
+		Expr         string
+		Ident        string
+		IdentPos     token.Position
+		IdentEnd     token.Position
+		ExprType     string
+		Pkg          interface{}
+		FileName     string
+		ReferPos     token.Position
+		ReferFile    string
+		ReferPkgDir  string `json:",omitempty"`
+		ReferObj     interface{}
+		Local        bool
+		Universe     bool
+		Builtin      bool
+		Container    string          `json:",omitempty"`
+		Visibility   string          `json:",omitempty"`
+		ConstVal     string          `json:",omitempty"`
+		ConstGroup   *token.Position `json:",omitempty"`
+		ConstIndex   *int            `json:",omitempty"`
+		IotaBased    bool            `json:",omitempty"`
+		DeclGroup    *token.Position `json:",omitempty"`
+		SpecIndex    *int            `json:",omitempty"`
+		NameIndex    *int            `json:",omitempty"`
+		Tag          string          `json:",omitempty"`
+		Captured     bool            `json:",omitempty"`
+		Anonymous    bool            `json:",omitempty"`
+		Form         string          `json:",omitempty"`
+		Recv         string          `json:",omitempty"`
+		Indirect     bool            `json:",omitempty"`
+		IsRecv       bool            `json:",omitempty"`
+		Embedded     bool            `json:",omitempty"`
+		DeclForm     string          `json:",omitempty"`
+		Implicit     bool            `json:",omitempty"`
+		InComment    bool            `json:",omitempty"`
+		IsConversion bool            `json:",omitempty"`
+		CallContext  string          `json:",omitempty"`
+		PkgDoc       string          `json:",omitempty"`
+		Unresolved   bool            `json:",omitempty"`
+		FromTestFile bool            `json:",omitempty"`
+		IsDecl       bool
+		Synthetic    bool `json:",omitempty"`
+	}{
+		Expr:         expr,
+		Ident:        ident,
+		IdentPos:     identPos,
+		IdentEnd:     identEnd,
+		ExprType:     exprType,
+		Pkg:          packageJSON(x.Pkg),
+		FileName:     fileName,
+		ReferPos:     x.ReferPosition(nil),
+		ReferFile:    x.ReferFile,
+		ReferPkgDir:  x.ReferPkgDir,
+		ReferObj:     objectJSON(x.ReferObj),
+		Local:        x.Local,
+		Universe:     x.Universe,
+		Builtin:      x.Builtin,
+		Container:    x.ContainerName(),
+		Visibility:   visibility,
+		ConstVal:     constVal,
+		ConstGroup:   constGroup,
+		ConstIndex:   constIndex,
+		IotaBased:    x.IotaBased,
+		DeclGroup:    declGroup,
+		SpecIndex:    specIndex,
+		NameIndex:    nameIndex,
+		Tag:          x.Tag,
+		Captured:     x.Captured,
+		Anonymous:    x.Anonymous,
+		Form:         form,
+		Recv:         recv,
+		Indirect:     x.Indirect,
+		IsRecv:       x.IsRecv,
+		Embedded:     x.Embedded,
+		DeclForm:     declForm,
+		Implicit:     x.Implicit,
+		InComment:    x.InComment,
+		IsConversion: x.IsConversion,
+		CallContext:  callContext,
+		PkgDoc:       x.PkgDoc,
+		Unresolved:   x.Unresolved,
+		FromTestFile: x.FromTestFile,
+		IsDecl:       x.IsDecl(),
+		Synthetic:    x.Synthetic,
+	})
+}
+
+// Position returns the position of x's identifier, resolved through fset,
+// or through the FileSet of the Context that emitted x if fset is nil. If
+// that Context has a BaseDir, the filename is relative to it. Position
+// returns the zero Position for synthetic symbs.
+func (x *Symb) Position(fset *token.FileSet) token.Position {
+	if x.Ident == nil {
+		return token.Position{}
+	}
+	return x.resolve(fset, x.Ident.Pos())
+}
+
+// ReferPosition returns the position of the object x refers to, resolved
+// like Position. It returns the zero Position if the object's position is
+// unknown.
+func (x *Symb) ReferPosition(fset *token.FileSet) token.Position {
+	return x.resolve(fset, x.ReferPos)
+}
+
+// position resolves pos through x's FileSet.
+func (x *Symb) position(pos token.Pos) token.Position {
+	return x.resolve(nil, pos)
+}
+
+// resolve resolves pos through fset, or through x's FileSet if fset is nil,
+// adjusted by //line directives unless the Context that emitted x had
+// AdjustedPositions unset, with the filename made relative to that
+// Context's BaseDir.
+func (x *Symb) resolve(fset *token.FileSet, pos token.Pos) token.Position {
+	if fset == nil {
+		fset = x.fset
+	}
+	if fset == nil || !pos.IsValid() {
+		return token.Position{}
+	}
+	p := fset.PositionFor(pos, !x.rawPositions)
+	p.Filename = relativeTo(x.baseDir, p.Filename)
+	return p
+}
+
+type packageJSONObj struct {
+	Isa, Name, ImportPath string
+}
+
+func packageJSON(p *types.Package) interface{} {
+	if p == nil {
+		return nil
+	}
+	return packageJSONObj{"Package", p.Name(), p.Path()}
+}
+
+type objectJSONObj struct {
+	Isa  string
+	Pkg  interface{}
+	Name string
+	Type interface{}
+	Val  interface{} `json:",omitempty"`
+}
+
+func objectJSON(o types.Object) interface{} {
+	if o == nil {
+		return nil
+	}
+	var val interface{}
+	switch o := o.(type) {
+	case *types.PkgName:
+		return packageJSON(o.Imported())
+	case *types.Const:
+		val = o.Val()
+	}
+	var typ interface{}
+	if t := o.Type(); t != nil && t != types.Typ[types.Invalid] {
+		typ = t.String()
+	}
+	return objectJSONObj{objectIsa(o), packageJSON(o.Pkg()), o.Name(), typ, val}
+}
+
+// objectIsa returns the kind of o as it is named in the Isa field of its
+// JSON encoding.
+func objectIsa(o types.Object) string {
+	switch o.(type) {
+	case *types.PkgName:
+		return "Package"
+	case *types.Const:
+		return "Const"
+	case *types.TypeName:
+		return "TypeName"
+	case *types.Var:
+		return "Var"
+	case *types.Func:
+		return "Func"
+	case *types.Builtin:
+		return "Builtin"
+	case *types.Nil:
+		return "Nil"
+	}
+	return "Unknown"
+}
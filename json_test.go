@@ -0,0 +1,114 @@
+package symb
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSymb_positions(t *testing.T) {
+	c := NewContext()
+	filename := filepath.Join("testdata", "src", "bar", "bar.go")
+	file, err := c.ParseFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Filename(file); got != filename {
+		t.Errorf("got Filename %q, want %q", got, filename)
+	}
+	if got := c.Position(token.NoPos); got != (token.Position{}) {
+		t.Errorf("got Position(NoPos) %v, want the zero Position", got)
+	}
+
+	var symb *Symb
+	err = c.IterateSymbs("bar", []*ast.File{file}, func(x *Symb) bool {
+		if !x.IsDecl() && x.ReferPos.IsValid() {
+			symb = x
+			return false
+		}
+		return true
+	})
+	if symb == nil {
+		t.Fatalf("no reference with a known declaration in %s (error %v)", filename, err)
+	}
+	if got, want := symb.Position(c.FileSet), c.Position(symb.Ident.Pos()); got != want {
+		t.Errorf("got Position %v, want %v", got, want)
+	}
+	if got, want := symb.ReferPosition(nil), c.Position(symb.ReferPos); got != want {
+		t.Errorf("got ReferPosition with the Context's FileSet %v, want %v", got, want)
+	}
+
+	var decoded struct{ IdentPos, ReferPos token.Position }
+	data, err := json.Marshal(symb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.IdentPos != symb.Position(nil) || decoded.ReferPos != symb.ReferPosition(nil) {
+		t.Errorf("got JSON positions %v and %v, want %v and %v", decoded.IdentPos, decoded.ReferPos, symb.Position(nil), symb.ReferPosition(nil))
+	}
+
+	// A symb with no FileSet or no positions resolves to zero Positions.
+	bare := Symb{Ident: ast.NewIdent("x")}
+	if got := bare.Position(nil); got != (token.Position{}) {
+		t.Errorf("got Position without a FileSet %v, want the zero Position", got)
+	}
+	if got := bare.ReferPosition(c.FileSet); got != (token.Position{}) {
+		t.Errorf("got ReferPosition of NoPos %v, want the zero Position", got)
+	}
+	if got := (&Symb{Synthetic: true}).Position(c.FileSet); got != (token.Position{}) {
+		t.Errorf("got Position of a synthetic symb %v, want the zero Position", got)
+	}
+}
+
+func TestRelativeTo(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "symb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	gopath := filepath.Join(tmp, "gopath")
+	if err := os.MkdirAll(filepath.Join(gopath, "src", "p"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(tmp, "link")
+	if err := os.Symlink(gopath, link); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(gopath, "src", "p", "p.go")
+	if err := ioutil.WriteFile(file, []byte("package p\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		baseDir, filename, want string
+	}{
+		{"", file, file},
+		{gopath, file, filepath.Join("src", "p", "p.go")},
+		{gopath + "/src/../", file, filepath.Join("src", "p", "p.go")},
+		{link, file, filepath.Join("src", "p", "p.go")},
+		{gopath, filepath.Join(link, "src", "p", "p.go"), filepath.Join("src", "p", "p.go")},
+		{filepath.Join(gopath, "src", "q"), file, file},
+	}
+	for _, test := range tests {
+		if got := relativeTo(test.baseDir, test.filename); got != test.want {
+			t.Errorf("relativeTo(%q, %q): got %q, want %q", test.baseDir, test.filename, got, test.want)
+		}
+	}
+
+	c := NewContext()
+	c.BaseDir = link
+	f, err := c.ParseFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Position(f.Package).Filename, filepath.Join("src", "p", "p.go"); got != want {
+		t.Errorf("got Position filename %q, want %q", got, want)
+	}
+}
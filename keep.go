@@ -0,0 +1,136 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// A keptFile holds the symbs retained from one file when KeepSymbols is
+// set, sorted by position when they are next queried.
+type keptFile struct {
+	file   *ast.File
+	symbs  []Symb
+	sorted bool
+}
+
+// keep retains a copy of symb, which has syntax, in the keptFile of its
+// file.
+func (ctxt *Context) keep(symb *Symb) {
+	kf := ctxt.lastKept
+	if kf == nil || kf.file != symb.File {
+		filename := ctxt.FileSet.File(symb.Ident.Pos()).Name()
+		if ctxt.kept == nil {
+			ctxt.kept = make(map[string]*keptFile)
+		}
+		kf = ctxt.kept[filename]
+		if kf == nil {
+			kf = &keptFile{}
+			ctxt.kept[filename] = kf
+			ctxt.keptOrder = append(ctxt.keptOrder, filename)
+		}
+		kf.file = symb.File
+		ctxt.lastKept = kf
+	}
+	kf.symbs = append(kf.symbs, *symb)
+	kf.sorted = false
+}
+
+// sortedSymbs returns the symbs of kf sorted by the positions of their
+// identifiers, and then by their ends, so that each identifier's symbs
+// are together; symbs with the same span stay in the order in which they
+// were emitted.
+func (kf *keptFile) sortedSymbs() []Symb {
+	if !kf.sorted {
+		sort.Stable(symbsBySpan(kf.symbs))
+		kf.sorted = true
+	}
+	return kf.symbs
+}
+
+// SymbolsInFile returns the symbs retained from the named file (as it was
+// parsed into the Context's FileSet) because KeepSymbols was set, sorted
+// by position, with the symbs at the same position in the order in which
+// they were emitted. It returns nil if no symbs were retained from the
+// file.
+func (ctxt *Context) SymbolsInFile(filename string) []Symb {
+	kf := ctxt.kept[filename]
+	if kf == nil {
+		return nil
+	}
+	symbs := kf.sortedSymbs()
+	return append([]Symb(nil), symbs...)
+}
+
+// SymbAt returns the first symb retained because KeepSymbols was set whose
+// identifier covers pos, that is, pos is at or after its start and before
+// its end, or nil if there is none. Symbs of synthesized identifiers (see
+// Anonymous and Implicit) are not considered. The returned symb belongs
+// to the Context and must not be modified.
+func (ctxt *Context) SymbAt(pos token.Pos) *Symb {
+	f := ctxt.FileSet.File(pos)
+	if f == nil {
+		return nil
+	}
+	kf := ctxt.kept[f.Name()]
+	if kf == nil {
+		return nil
+	}
+	symbs := kf.sortedSymbs()
+
+	// Find the last symb that starts at or before pos, and then the first
+	// of the symbs of the identifier that covers it.
+	i := sort.Search(len(symbs), func(i int) bool {
+		return symbs[i].Ident.Pos() > pos
+	}) - 1
+	var found *Symb
+	for ; i >= 0; i-- {
+		x := &symbs[i]
+		if x.Anonymous || x.Implicit {
+			continue
+		}
+		if found != nil && x.Ident.Pos() != found.Ident.Pos() {
+			break
+		}
+		if pos >= x.Ident.End() {
+			break
+		}
+		found = x
+	}
+	return found
+}
+
+// AllSymbols returns all of the symbs retained because KeepSymbols was
+// set: those of each file as SymbolsInFile returns them, with the files
+// in the order in which their first symbs were retained.
+func (ctxt *Context) AllSymbols() []Symb {
+	var all []Symb
+	for _, filename := range ctxt.keptOrder {
+		all = append(all, ctxt.kept[filename].sortedSymbs()...)
+	}
+	return all
+}
+
+// Reset discards the symbs that the Context has retained because
+// KeepSymbols, IndexSymbs, or TrackReferences was set, and the events it
+// has recorded for Timeline because Debug was set (restarting their
+// sequence numbers), so that their memory can be reclaimed and later
+// iterations start afresh.
+func (ctxt *Context) Reset() {
+	ctxt.timeline, ctxt.seq = nil, 0
+	ctxt.kept, ctxt.keptOrder, ctxt.lastKept = nil, nil, nil
+	ctxt.posIndex, ctxt.posIndexSorted = nil, false
+	ctxt.refsByObj = make(map[types.Object][]*Symb)
+}
+
+type symbsBySpan []Symb
+
+func (s symbsBySpan) Len() int      { return len(s) }
+func (s symbsBySpan) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s symbsBySpan) Less(i, j int) bool {
+	if s[i].Ident.Pos() != s[j].Ident.Pos() {
+		return s[i].Ident.Pos() < s[j].Ident.Pos()
+	}
+	return s[i].Ident.End() < s[j].Ident.End()
+}
@@ -0,0 +1,81 @@
+package symb
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestContext_KeepSymbols(t *testing.T) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.KeepSymbols = true
+	c.Debug = true
+	emitted := make(map[string]int)
+	var total int
+	err = c.IterateSymbsPkg("foo", pkg, func(symb *Symb) bool {
+		if !symb.Synthetic {
+			emitted[fset.File(symb.Ident.Pos()).Name()]++
+			total++
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join("testdata", "src", "foo", "local.go")
+	const line = "println(NonLocalVar, localRecv.NonLocalFunc"
+	tests := []struct {
+		delta int
+		want  string // pretty-printed Expr, or "" for none
+	}{
+		{len("println("), "NonLocalVar"},
+		{len("println(NonLocalVar") - 1, "NonLocalVar"},
+		{len("println(NonLocalVar,"), ""},
+		{len("println(NonLocalVar, localRecv.NonLocal"), "localRecv.NonLocalFunc"},
+	}
+	for _, test := range tests {
+		symb := c.SymbAt(testPos(t, filename, line, test.delta))
+		if test.want == "" {
+			if symb != nil {
+				t.Errorf("offset %d: got symb %s, want none", test.delta, pretty(symb.Expr))
+			}
+			continue
+		}
+		if symb == nil {
+			t.Errorf("offset %d: got no symb, want %s", test.delta, test.want)
+		} else if got := pretty(symb.Expr); got != test.want {
+			t.Errorf("offset %d: got symb %s, want %s", test.delta, got, test.want)
+		}
+	}
+
+	for name, n := range emitted {
+		symbs := c.SymbolsInFile(name)
+		if len(symbs) != n {
+			t.Errorf("%s: got %d symbs, want the %d emitted", name, len(symbs), n)
+		}
+		for i := 1; i < len(symbs); i++ {
+			if symbs[i].Ident.Pos() < symbs[i-1].Ident.Pos() {
+				t.Errorf("%s: symb %d (%s) is before symb %d (%s)", name, i, symbs[i].Ident.Name, i-1, symbs[i-1].Ident.Name)
+			}
+		}
+	}
+	if n := len(c.AllSymbols()); n != total {
+		t.Errorf("got %d symbs in all, want %d", n, total)
+	}
+
+	c.Reset()
+	if n := len(c.AllSymbols()); n != 0 {
+		t.Errorf("got %d symbs after Reset, want 0", n)
+	}
+	if symbs := c.SymbolsInFile(filename); symbs != nil {
+		t.Errorf("got %d symbs in %s after Reset, want none", len(symbs), filename)
+	}
+	if events := c.Timeline(); events != nil {
+		t.Errorf("got %d events after Reset, want none", len(events))
+	}
+}
@@ -0,0 +1,40 @@
+package symb
+
+// Level is the severity of a message logged by a Context.
+type Level int
+
+const (
+	LevelDebug Level = iota // details of interest only when debugging go-symb itself
+	LevelInfo               // expected conditions, such as unsupported constructs that are skipped
+	LevelWarn               // problems that may leave symbs missing, such as unresolved identifiers
+	LevelError              // problems that are also reported as Errors by the iteration
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "Debug"
+	case LevelInfo:
+		return "Info"
+	case LevelWarn:
+		return "Warn"
+	case LevelError:
+		return "Error"
+	}
+	return "Level(?)"
+}
+
+// LogCount returns the number of messages of the given level that ctxt
+// has logged, whether or not Log or Logf is set.
+func (ctxt *Context) LogCount(level Level) int {
+	return ctxt.logCounts[level]
+}
+
+// logLevel returns the level at which Errors of kind k are logged.
+// Unsupported constructs are skipped as a matter of course.
+func (k ErrorKind) logLevel() Level {
+	if k == Unsupported {
+		return LevelInfo
+	}
+	return LevelError
+}
@@ -0,0 +1,59 @@
+package symb
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestContext_Log(t *testing.T) {
+	c := NewContext()
+	var logged, warned []string
+	c.Log = func(level Level, pos token.Pos, msg string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf("%s %s", level, fmt.Sprintf(msg, args...)))
+	}
+	c.Logf = func(pos token.Pos, f string, a ...interface{}) {
+		warned = append(warned, fmt.Sprintf(f, a...))
+	}
+	var files []*ast.File
+	for _, name := range []string{"a.go", "b.go"} {
+		file, err := c.ParseFile(filepath.Join("testdata", "src", "errs", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	c.IterateSymbs("errs", files, func(*Symb) bool { return true })
+
+	if want := []string{"Warn no object for missing", "Info import to . not supported"}; !reflect.DeepEqual(logged, want) {
+		t.Errorf("got logged %q, want %q", logged, want)
+	}
+	if want := []string{"no object for missing"}; !reflect.DeepEqual(warned, want) {
+		t.Errorf("got Logf messages %q, want %q", warned, want)
+	}
+	for level, want := range map[Level]int{LevelDebug: 0, LevelInfo: 1, LevelWarn: 1, LevelError: 0} {
+		if got := c.LogCount(level); got != want {
+			t.Errorf("got %d %s messages, want %d", got, level, want)
+		}
+	}
+}
+
+func TestContext_LogCount_clean(t *testing.T) {
+	for _, pkgPath := range []string{"foo", "bar", "typeexprs"} {
+		pkg, err := parseTestPkg(pkgPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewContext()
+		c.FileSet = fset
+		if err := c.IterateSymbsPkg(pkgPath, pkg, func(*Symb) bool { return true }); err != nil {
+			t.Fatal(err)
+		}
+		if n := c.LogCount(LevelWarn) + c.LogCount(LevelError); n != 0 {
+			t.Errorf("%s: got %d warnings and errors, want none", pkgPath, n)
+		}
+	}
+}
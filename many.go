@@ -0,0 +1,138 @@
+package symb
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IterateMany calls visitf for each symb in each of pkgs, which maps import
+// paths to the files of each package. The packages are type-checked in
+// dependency order, and a package that imports another package in pkgs is
+// checked against the result of checking that package, rather than against
+// its installed export data. Consequently, the ReferObj of a reference in
+// one package to a declaration in another is the same object as the
+// ReferObj of the declaration's symb.
+//
+// Packages that do not depend on each other are visited in order of import
+// path, and may be type-checked concurrently (see ctxt.Parallelism). If
+// pkgs contains an import cycle, an error describing it is
+// returned before any package is checked. Otherwise, a package that fails
+// to type-check does not stop the iteration; its error is recorded and
+// returned in a PackageErrors once all packages have been visited. If
+// visitf returns false, the iteration stops.
+func (ctxt *Context) IterateMany(pkgs map[string][]*ast.File, visitf func(importPath string, symb *Symb) bool) error {
+	order, err := importOrder(pkgs)
+	if err != nil {
+		return err
+	}
+
+	jobs := make(map[string]*checkJob, len(order))
+	var errs PackageErrors
+	i := 0
+	next := func() ([]*checkJob, bool) {
+		path := order[i]
+		i++
+		job := &checkJob{importPath: path, files: ctxt.sortFiles(pkgs[path])}
+		for _, imp := range fileImports(pkgs[path]) {
+			if dep, present := jobs[imp]; present {
+				job.deps = append(job.deps, dep)
+			}
+		}
+		jobs[path] = job
+		return []*checkJob{job}, i < len(order)
+	}
+	walk := func(job *checkJob) bool {
+		ok := true
+		err := ctxt.iterate(job, func(symb *Symb) bool {
+			ok = visitf(job.importPath, symb)
+			return ok
+		})
+		if err != nil {
+			errs = append(errs, &PackageError{job.importPath, err})
+		}
+		return ok
+	}
+	if len(order) > 0 {
+		ctxt.checkPipeline(next, walk)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// importOrder returns the import paths of pkgs in an order in which each
+// package comes after the packages in pkgs that it imports, or an error if
+// the imports of pkgs form a cycle.
+func importOrder(pkgs map[string][]*ast.File) ([]string, error) {
+	paths := make([]string, 0, len(pkgs))
+	for path := range pkgs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(pkgs))
+	order := make([]string, 0, len(pkgs))
+	var stack []string
+	var visit func(path string) error
+	visit = func(path string) error {
+		switch state[path] {
+		case visited:
+			return nil
+		case visiting:
+			for i, p := range stack {
+				if p == path {
+					cycle := append(stack[i:len(stack):len(stack)], path)
+					return fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+				}
+			}
+		}
+		state[path] = visiting
+		stack = append(stack, path)
+		for _, imp := range fileImports(pkgs[path]) {
+			if _, present := pkgs[imp]; present {
+				if err := visit(imp); err != nil {
+					return err
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[path] = visited
+		order = append(order, path)
+		return nil
+	}
+	for _, path := range paths {
+		if err := visit(path); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// fileImports returns the import paths imported by files, sorted and
+// without duplicates.
+func fileImports(files []*ast.File) []string {
+	seen := make(map[string]bool)
+	var imports []string
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || seen[path] {
+				continue
+			}
+			seen[path] = true
+			imports = append(imports, path)
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
@@ -0,0 +1,143 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIterateMany(t *testing.T) {
+	pkgs := make(map[string][]*ast.File)
+	for _, path := range []string{"bar", "foo"} {
+		pkg, err := parseTestPkg(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgs[path] = pkgFiles(pkg)
+	}
+
+	c := NewContext()
+	c.FileSet = fset
+	var pkgPaths []string
+	var fooA, barA types.Object
+	err := c.IterateMany(pkgs, func(importPath string, symb *Symb) bool {
+		if len(pkgPaths) == 0 || pkgPaths[len(pkgPaths)-1] != importPath {
+			pkgPaths = append(pkgPaths, importPath)
+		}
+		if symb.Ident.Name == "A" {
+			switch importPath {
+			case "foo":
+				if symb.IsDecl() {
+					fooA = symb.ReferObj
+				}
+			case "bar":
+				barA = symb.ReferObj
+			}
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"foo", "bar"}; !reflect.DeepEqual(pkgPaths, want) {
+		t.Errorf("got packages %v, want %v", pkgPaths, want)
+	}
+	if fooA == nil || barA == nil {
+		t.Fatalf("got foo.A decl %v and ref %v, want both", fooA, barA)
+	}
+	if barA != fooA {
+		t.Errorf("reference to foo.A in bar refers to %p, want decl object %p", barA, fooA)
+	}
+	if _, present := c.packages["foo"]; present {
+		t.Errorf("checked package foo left in import cache")
+	}
+}
+
+func TestIterateMany_cycle(t *testing.T) {
+	srcs := map[string]string{
+		"a": "package a\nimport _ \"b\"\n",
+		"b": "package b\nimport _ \"c\"\n",
+		"c": "package c\nimport _ \"a\"\n",
+	}
+	c := NewContext()
+	pkgs := make(map[string][]*ast.File)
+	for path, src := range srcs {
+		file, err := parser.ParseFile(c.FileSet, path+".go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgs[path] = []*ast.File{file}
+	}
+
+	err := c.IterateMany(pkgs, func(string, *Symb) bool {
+		t.Error("visited symb in cyclic packages")
+		return false
+	})
+	if err == nil {
+		t.Fatal("got no error, want import cycle error")
+	}
+	if want := "import cycle: a -> b -> c -> a"; !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %q, want %q", err, want)
+	}
+}
+
+func TestImportOrder(t *testing.T) {
+	pkgs := make(map[string][]*ast.File)
+	for path, src := range map[string]string{
+		"x": "package x\nimport (_ \"z\"; _ \"fmt\")\n",
+		"y": "package y\n",
+		"z": "package z\nimport _ \"y\"\n",
+	} {
+		file, err := parser.ParseFile(token.NewFileSet(), path+".go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgs[path] = []*ast.File{file}
+	}
+	order, err := importOrder(pkgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"y", "z", "x"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("got order %v, want %v", order, want)
+	}
+}
+
+func TestIterateMany_parallel(t *testing.T) {
+	// bar is left out because the golden files for it were made with foo
+	// imported from export data.
+	pkgs := make(map[string][]*ast.File)
+	for _, path := range testPkgPaths {
+		if path == "bar" {
+			continue
+		}
+		pkg, err := parseTestPkg(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgs[path] = pkgFiles(pkg)
+	}
+
+	c := NewContext()
+	c.FileSet = fset
+	c.BaseDir = build.Default.GOPATH
+	c.Parallelism = len(pkgs)
+	symbsByFilename := make(map[string][]Symb)
+	err := c.IterateMany(pkgs, func(_ string, symb *Symb) bool {
+		filename := fset.File(symb.Ident.Pos()).Name()
+		symbsByFilename[filename] = append(symbsByFilename[filename], *symb)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for filename, symbs := range symbsByFilename {
+		checkOutput(filename, symbs, t)
+	}
+}
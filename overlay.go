@@ -0,0 +1,111 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+	"go/parser"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ParseAndIterate parses sources, which maps filenames to file contents,
+// into the Context's FileSet and calls visitf for each symb in the package
+// they make up, which has the given import path. The files are read only
+// from sources, never from disk, and positions in them report the given
+// filenames. Files with syntax errors are checked and walked as far as
+// they could be parsed, and the errors are returned as SyntaxErrors along
+// with the package's other Errors. It is otherwise like IterateSymbs.
+func (ctxt *Context) ParseAndIterate(importPath string, sources map[string][]byte, visitf func(symb *Symb) bool) error {
+	filenames := make([]string, 0, len(sources))
+	for filename := range sources {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	files := make([]*ast.File, len(filenames))
+	var syntaxErrs []Error
+	for i, filename := range filenames {
+		file, err := ctxt.parseSource(filename, sources[filename])
+		if file == nil {
+			return err
+		}
+		if err != nil {
+			syntaxErrs = append(syntaxErrs, ctxt.syntaxErrors(err)...)
+		}
+		files[i] = file
+	}
+	return ctxt.iterate(&checkJob{importPath: importPath, files: ctxt.sortFiles(files), syntaxErrs: syntaxErrs}, visitf)
+}
+
+// IterateSource parses a single file, named filename, from src (a string,
+// []byte, or io.Reader, as for go/parser), type-checks it as a package on
+// its own, and calls visitf for each of its symbs, using a new Context.
+// Imports that cannot be found are satisfied by empty packages, so the
+// file's own symbs (and its references to those packages, by name) are
+// still emitted; references to their members are not, and are reported
+// as type errors in the returned Errors. Syntax errors are tolerated as
+// by ParseAndIterate. The package's import path is its name.
+func IterateSource(filename string, src interface{}, visitf func(symb *Symb) bool) error {
+	ctxt := NewContext()
+	file, err := parser.ParseFile(ctxt.FileSet, filename, src, parser.ParseComments|parser.AllErrors|parser.DeclarationErrors)
+	if file == nil || file.Name.Name == "" {
+		return err
+	}
+	var syntaxErrs []Error
+	if err != nil {
+		syntaxErrs = ctxt.syntaxErrors(err)
+	}
+	ctxt.typesConfig.Import = func(imports map[string]*types.Package, path string) (*types.Package, error) {
+		if pkg, err := ctxt.importPackage(imports, path); err == nil {
+			return pkg, nil
+		}
+		pkg := types.NewPackage(path, path[strings.LastIndex(path, "/")+1:])
+		pkg.MarkComplete()
+		imports[path] = pkg
+		return pkg, nil
+	}
+	return ctxt.iterate(&checkJob{importPath: file.Name.Name, files: []*ast.File{file}, syntaxErrs: syntaxErrs}, visitf)
+}
+
+// addOverlayFiles returns infos, the entries of dir on disk, with entries
+// added for the files of dir in ctxt.Overlay that are not on disk, sorted
+// by name.
+func (ctxt *Context) addOverlayFiles(dir string, infos []os.FileInfo) []os.FileInfo {
+	onDisk := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		onDisk[info.Name()] = true
+	}
+	added := false
+	for filename, src := range ctxt.Overlay {
+		if filepath.Dir(filename) != dir || onDisk[filepath.Base(filename)] {
+			continue
+		}
+		infos = append(infos, overlayFileInfo{name: filepath.Base(filename), size: int64(len(src))})
+		added = true
+	}
+	if added {
+		sort.Sort(fileInfosByName(infos))
+	}
+	return infos
+}
+
+// overlayFileInfo describes a file that exists only in Context.Overlay.
+type overlayFileInfo struct {
+	name string
+	size int64
+}
+
+func (fi overlayFileInfo) Name() string       { return fi.name }
+func (fi overlayFileInfo) Size() int64        { return fi.size }
+func (fi overlayFileInfo) Mode() os.FileMode  { return 0444 }
+func (fi overlayFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi overlayFileInfo) IsDir() bool        { return false }
+func (fi overlayFileInfo) Sys() interface{}   { return nil }
+
+type fileInfosByName []os.FileInfo
+
+func (s fileInfosByName) Len() int           { return len(s) }
+func (s fileInfosByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s fileInfosByName) Less(i, j int) bool { return s[i].Name() < s[j].Name() }
@@ -0,0 +1,132 @@
+package symb
+
+import (
+	"bytes"
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIteratePackageDir_overlay(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+	dir := filepath.Join(build.Default.GOPATH, "src", "foo")
+	usage := filepath.Join(dir, "usage.go")
+	added := filepath.Join(dir, "added.go")
+	onDisk, err := ioutil.ReadFile(usage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewContext()
+	c.Overlay = map[string][]byte{
+		usage: append(append([]byte{}, onDisk...), "\nfunc C() { B() }\n"...),
+		added: []byte("package foo\n\nfunc D() { C() }\n"),
+	}
+	decls := make(map[string]string)
+	err = c.IteratePackageDir(dir, func(x *Symb) bool {
+		if x.IsDecl() && !x.Local {
+			pos := c.Position(x.Ident.Pos())
+			decls[x.Ident.Name] = filepath.Base(pos.Filename)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"foo":          "added.go",
+		"A":            "func.go",
+		"B":            "usage.go",
+		"C":            "usage.go",
+		"D":            "added.go",
+		"NonLocalVar":  "local.go",
+		"NonLocalType": "local.go",
+		"NonLocalFunc": "local.go",
+		"main":         "stdlib.go",
+	}
+	if !reflect.DeepEqual(decls, want) {
+		t.Errorf("got decls %v, want %v", decls, want)
+	}
+
+	if now, err := ioutil.ReadFile(usage); err != nil || !bytes.Equal(now, onDisk) {
+		t.Errorf("usage.go changed on disk (error %v)", err)
+	}
+	if _, err := ioutil.ReadFile(added); err == nil {
+		t.Errorf("added.go was written to disk")
+	}
+}
+
+func TestParseAndIterate(t *testing.T) {
+	c := NewContext()
+	sources := map[string][]byte{
+		"/mem/b.go": []byte("package mem\n\nfunc B() int { return A }\n"),
+		"/mem/a.go": []byte("package mem\n\nconst A = 1\n"),
+	}
+	var got []string
+	var declA *Symb
+	err := c.ParseAndIterate("mem", sources, func(x *Symb) bool {
+		got = append(got, c.Position(x.Ident.Pos()).String()+" "+x.Ident.Name)
+		if x.Ident.Name == "A" && x.IsDecl() {
+			declA = x
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"/mem/a.go:1:9 mem",
+		"/mem/a.go:3:7 A",
+		"/mem/b.go:1:9 mem",
+		"/mem/b.go:3:6 B",
+		"/mem/b.go:3:10 int",
+		"/mem/b.go:3:23 A",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbs %v, want %v", got, want)
+	}
+
+	// The source of in-memory files is available without a disk read.
+	if declA == nil {
+		t.Fatal("no decl of A")
+	}
+	if src, err := c.SourceOf(declA.Ident); err != nil || src != "A" {
+		t.Errorf("got source %q and error %v for A in an in-memory file, want %q", src, err, "A")
+	}
+}
+
+func TestIterateSource(t *testing.T) {
+	const src = `package snippet
+
+import "example.com/missing"
+
+type T struct{ N int }
+
+func (t T) Double() int { return t.N * 2 }
+
+var v = missing.Value
+`
+	var got []string
+	err := IterateSource("snippet.go", src, func(x *Symb) bool {
+		name := x.Ident.Name
+		if x.IsDecl() {
+			name += " (decl)"
+		}
+		got = append(got, name)
+		return true
+	})
+	want := []string{
+		"snippet (decl)", "T (decl)", "N (decl)", "int",
+		"t (decl)", "T", "Double (decl)", "int", "t", "N",
+		"v (decl)", "missing",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbs %v, want %v", got, want)
+	}
+	errs := AsErrors(err)
+	if len(errs) != 1 || errs[0].Kind != TypeError {
+		t.Errorf("got errors %v, want one type error for missing.Value", errs)
+	}
+}
@@ -0,0 +1,119 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// A checkJob is a package to be type-checked and then walked.
+type checkJob struct {
+	importPath string
+	files      []*ast.File // sorted by filename
+	walk       []*ast.File // files to walk, if not all of files
+	deps       []*checkJob // jobs for packages that this package imports
+	err        error       // error preparing the job (such as a file that cannot be read), if any
+	syntaxErrs []Error     // syntax errors in files, which are checked and walked as far as they were parsed
+
+	exportedOnly bool // walk only the exported declarations, checking no function bodies
+	declsOnly    bool // emit only declarations
+
+	checked   *checkResult  // set once the package has been checked
+	checkTime time.Duration // time taken to check the package
+	done      chan struct{} // closed once checked is set, if the job is run by checkPipeline
+}
+
+// runCheck type-checks the package of job against the packages of its
+// deps, which must have been checked already.
+func (ctxt *Context) runCheck(job *checkJob) {
+	var deps map[string]*types.Package
+	for _, dep := range job.deps {
+		if dep.checked == nil || dep.checked.pkg == nil {
+			continue
+		}
+		if deps == nil {
+			deps = make(map[string]*types.Package)
+		}
+		deps[dep.importPath] = dep.checked.pkg
+	}
+	start := time.Now()
+	job.checked = ctxt.check(job.importPath, job.files, deps, job.exportedOnly)
+	job.checkTime = time.Since(start)
+}
+
+// parallelism returns the maximum number of packages to check at once.
+func (ctxt *Context) parallelism() int {
+	if ctxt.Parallelism > 0 {
+		return ctxt.Parallelism
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// checkPipeline type-checks the packages of the jobs returned by
+// successive calls to next, until it returns false, and calls walk with
+// each job once its package has been checked. The jobs are walked in the
+// order in which next returns them, in the calling goroutine, while up to
+// ctxt.parallelism() of them are prepared and checked in other goroutines.
+// A job must come after the jobs it depends on. If walk returns false, no
+// more jobs are walked, and checkPipeline returns once the checks in
+// progress have finished.
+func (ctxt *Context) checkPipeline(next func() ([]*checkJob, bool), walk func(job *checkJob) bool) {
+	n := ctxt.parallelism()
+	// A slot is taken when a job is started and freed once it has been
+	// walked, which bounds the number of checked packages held at once.
+	// Jobs take slots in walk order, so the next job to be walked never
+	// waits for a slot held by a later one.
+	slots := make(chan struct{}, n)
+	jobs := make(chan *checkJob, n)
+	quit := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for {
+			batch, more := next()
+			for _, job := range batch {
+				select {
+				case slots <- struct{}{}:
+				case <-quit:
+					return
+				}
+				job.done = make(chan struct{})
+				wg.Add(1)
+				go func(job *checkJob) {
+					defer wg.Done()
+					defer close(job.done)
+					for _, dep := range job.deps {
+						<-dep.done
+					}
+					if job.err == nil {
+						ctxt.runCheck(job)
+					}
+				}(job)
+				select {
+				case jobs <- job:
+				case <-quit:
+					return
+				}
+			}
+			if !more {
+				return
+			}
+		}
+	}()
+
+	for job := range jobs {
+		<-job.done
+		ok := walk(job)
+		<-slots
+		if !ok {
+			break
+		}
+	}
+	close(quit)
+	wg.Wait()
+}
@@ -0,0 +1,57 @@
+package symb
+
+import (
+	"go/ast"
+)
+
+// EnclosingNodes returns the nodes of x.File that enclose x's identifier,
+// from the *ast.File (first) to the innermost node (last), not including
+// the identifier itself. For an identifier synthesized by the Context,
+// which is not in the file's syntax tree, they are the nodes that were
+// being walked when it was synthesized. It returns nil for synthetic
+// symbs.
+//
+// During the visitf callback for x, the nodes are those the Context is
+// walking, so no search is needed. For other symbs (such as ones kept from
+// earlier callbacks), EnclosingNodes searches x.File for the nodes that
+// enclose the identifier's position, which takes time proportional to the
+// size of the file.
+//
+// To avoid allocating for each symb, the returned slice is reused by the
+// next call to EnclosingNodes on ctxt, so it is only valid until then
+// (typically, for the rest of a visitf callback). Use CopyNodes to keep it.
+func (ctxt *Context) EnclosingNodes(x *Symb) []ast.Node {
+	if x.File == nil || x.Ident == nil {
+		return nil
+	}
+	path := ctxt.enclosing[:0]
+	if x.Ident == ctxt.emitting && len(ctxt.path) > 0 && ctxt.path[0] == x.File {
+		path = append(path, ctxt.path...)
+		if path[len(path)-1] == x.Ident {
+			path = path[:len(path)-1]
+		}
+		ctxt.enclosing = path
+		return path
+	}
+	pos, end := x.Ident.Pos(), x.Ident.End()
+	ast.Inspect(x.File, func(n ast.Node) bool {
+		if n == nil || n == x.Ident || !(n.Pos() <= pos && end <= n.End()) {
+			return false
+		}
+		path = append(path, n)
+		// The span of a FuncDecl's FuncType covers its name, though the
+		// name is not in it.
+		if fn, isFunc := n.(*ast.FuncDecl); isFunc && fn.Name == x.Ident {
+			return false
+		}
+		return true
+	})
+	ctxt.enclosing = path
+	return path
+}
+
+// CopyNodes returns a copy of nodes, such as the result of EnclosingNodes,
+// that is not reused.
+func CopyNodes(nodes []ast.Node) []ast.Node {
+	return append([]ast.Node(nil), nodes...)
+}
@@ -0,0 +1,59 @@
+package symb
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"testing"
+)
+
+func TestContext_EnclosingNodes(t *testing.T) {
+	pkg, err := parseTestPkg("paths")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	var path []string
+	err = c.IterateSymbsPkg("paths", pkg, func(x *Symb) bool {
+		if x.Ident.Name == "Ready" && !x.IsDecl() {
+			for _, n := range c.EnclosingNodes(x) {
+				path = append(path, fmt.Sprintf("%T", n))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"*ast.File", "*ast.FuncDecl", "*ast.BlockStmt", "*ast.IfStmt", "*ast.CallExpr", "*ast.SelectorExpr"}
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("got path %v, want %v", path, want)
+	}
+}
+
+func TestContext_EnclosingNodes_afterWalk(t *testing.T) {
+	pkg, err := parseTestPkg("paths")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	var symbs []Symb
+	var paths [][]ast.Node
+	err = c.IterateSymbsPkg("paths", pkg, func(x *Symb) bool {
+		symbs = append(symbs, *x)
+		paths = append(paths, CopyNodes(c.EnclosingNodes(x)))
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// After the walk, the paths are found by searching the files, and
+	// agree with those the walk maintained.
+	for i := range symbs {
+		if got := c.EnclosingNodes(&symbs[i]); !reflect.DeepEqual(got, paths[i]) {
+			t.Errorf("%s: got path %d nodes after the walk, want the %d during it", symbs[i].Ident.Name, len(got), len(paths[i]))
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"io"
+	"sort"
+)
+
+// indexVersion is the version of the encoding written by Index.Save. It
+// is incremented whenever IndexRecord changes incompatibly.
+const indexVersion = 2
+
+// An IndexRecord is what Index.Save keeps of an indexed symb: everything
+// that can be serialized, without the syntax and type-checker objects of
+// the symb. Declarations and references are linked by ID, the record's
+// position in the index.
+type IndexRecord struct {
+	ID            int    // index of the record (and of its symb) in the Index
+	Name          string // the identifier's name, as looked up by LookupRecords
+	QualifiedName string // see Symb.QualifiedName
+	Kind          string // kind of object referred to, as in the Isa field of a symb's JSON encoding
+	Pkg           string // import path of the package of the object, or "" for the universe scope
+	File          string // name of the file containing the identifier, relative to the Context's BaseDir
+	Start, End    int    // byte offsets of the identifier and of the byte immediately after it in File
+	Type          string // the type of the object, or "" if it has none
+	Exported      bool   // whether the Visibility of the symb is Exported
+	Local         bool   // whether the object is function-local
+	IsDecl        bool   // whether the symb is the declaration of the object
+	Decl          int    // ID of the declaration of the object (ID for a declaration), or -1 if it is not indexed
+	Container     int    // ID of the declaration of the symb's Container, or -1 if there is none or it is not indexed
+
+	// For declarations in an index with IncludeRefs set, the number of
+	// indexed references to the object from outside its declaration
+	// (see Index.RefCount), and the number from inside it, such as
+	// recursive calls.
+	RefCount int
+	SelfRefs int
+}
+
+// indexFile is the encoding of an Index written by Save.
+type indexFile struct {
+	Version     int
+	IncludeRefs bool
+	Records     []IndexRecord
+}
+
+// Save writes the records of the indexed symbs (see IndexRecord) to w, as
+// JSON, for LoadIndex to read back without parsing or type-checking the
+// packages again.
+func (idx *Index) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(indexFile{
+		Version:     indexVersion,
+		IncludeRefs: idx.IncludeRefs,
+		Records:     idx.allRecords(),
+	})
+}
+
+// LoadIndex reads an index written by Index.Save from r. Only the records
+// of its symbs are recovered, so the Index answers LookupRecords,
+// LookupQualifiedRecords, DeclRecords, Refs, and RefCount, but the methods
+// that return symbs (Lookup, LookupQualified, Decls, SearchPrefix, and
+// SearchFuzzy) panic, and Add has no effect on it.
+func LoadIndex(r io.Reader) (*Index, error) {
+	var f indexFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+	if f.Version != indexVersion {
+		return nil, fmt.Errorf("index has version %d, want %d", f.Version, indexVersion)
+	}
+	idx := NewIndex()
+	idx.IncludeRefs = f.IncludeRefs
+	idx.loaded = true
+	idx.records = f.Records
+	if idx.records == nil {
+		idx.records = []IndexRecord{}
+	}
+	for i, rec := range idx.records {
+		if rec.ID != i {
+			return nil, fmt.Errorf("index record %d has ID %d", i, rec.ID)
+		}
+		idx.byName[rec.Name] = append(idx.byName[rec.Name], i)
+	}
+	return idx, nil
+}
+
+// LookupRecords returns the records of the indexed symbs whose identifier
+// is name, in the order in which they were added, as Lookup returns the
+// symbs.
+func (idx *Index) LookupRecords(name string) []IndexRecord {
+	records := idx.allRecords()
+	is := idx.byName[name]
+	recs := make([]IndexRecord, len(is))
+	for j, i := range is {
+		recs[j] = records[i]
+	}
+	return recs
+}
+
+// LookupQualifiedRecords returns the records of the indexed symbs whose
+// identifier is name and that refer to an object in the package with
+// import path pkgPath, as LookupQualified returns the symbs.
+func (idx *Index) LookupQualifiedRecords(pkgPath, name string) []IndexRecord {
+	var recs []IndexRecord
+	for _, rec := range idx.LookupRecords(name) {
+		if rec.Pkg == pkgPath {
+			recs = append(recs, rec)
+		}
+	}
+	return recs
+}
+
+// DeclRecords returns the records of all indexed declarations, sorted by
+// file and offset (the order of Decls within each file).
+func (idx *Index) DeclRecords() []IndexRecord {
+	var decls []IndexRecord
+	for _, rec := range idx.allRecords() {
+		if rec.IsDecl {
+			decls = append(decls, rec)
+		}
+	}
+	sort.Sort(recordsByPos(decls))
+	return decls
+}
+
+type recordsByPos []IndexRecord
+
+func (r recordsByPos) Len() int      { return len(r) }
+func (r recordsByPos) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r recordsByPos) Less(i, j int) bool {
+	if r[i].File != r[j].File {
+		return r[i].File < r[j].File
+	}
+	return r[i].Start < r[j].Start
+}
+
+// Refs returns the records of the indexed references to the declaration
+// with the given ID, in the order in which they were added. References
+// are only indexed if idx.IncludeRefs is set.
+func (idx *Index) Refs(id int) []IndexRecord {
+	var refs []IndexRecord
+	for _, rec := range idx.allRecords() {
+		if !rec.IsDecl && rec.Decl == id {
+			refs = append(refs, rec)
+		}
+	}
+	return refs
+}
+
+// RefCount returns the number of indexed references to the declaration
+// with the given ID from outside the node that declares it (its function
+// declaration, type or value spec, and so on): the references that Refs
+// returns, less those in the declaration itself, such as recursive calls,
+// which are counted in the declaration's SelfRefs. It returns 0 if
+// idx.IncludeRefs is not set.
+func (idx *Index) RefCount(id int) int {
+	records := idx.allRecords()
+	if id < 0 || id >= len(records) {
+		return 0
+	}
+	return records[id].RefCount
+}
+
+// allRecords returns the records of all indexed symbs, computing them if
+// symbs have been added since they were last computed.
+func (idx *Index) allRecords() []IndexRecord {
+	if idx.records != nil {
+		return idx.records
+	}
+	declIDs := make(map[types.Object]int)
+	for i := range idx.symbs {
+		if x := &idx.symbs[i]; x.IsDecl() {
+			if _, present := declIDs[x.ReferObj]; !present {
+				declIDs[x.ReferObj] = i
+			}
+		}
+	}
+	idx.records = make([]IndexRecord, len(idx.symbs))
+	for i := range idx.symbs {
+		idx.records[i] = idx.symbs[i].record(i, declIDs)
+	}
+	for i := range idx.records {
+		ref := &idx.records[i]
+		if ref.IsDecl || ref.Decl < 0 {
+			continue
+		}
+		if idx.symbs[i].within(&idx.symbs[ref.Decl]) {
+			idx.records[ref.Decl].SelfRefs++
+		} else {
+			idx.records[ref.Decl].RefCount++
+		}
+	}
+	return idx.records
+}
+
+// within reports whether the identifier of x is inside the node that
+// declares decl: its DeclNode, narrowed to the spec for a name declared in
+// a GenDecl. The declaration of a package is its first file, which is not
+// taken to contain the references in it.
+func (x *Symb) within(decl *Symb) bool {
+	if x.Synthetic || x.fset != decl.fset {
+		return false
+	}
+	node := decl.DeclNode
+	switch n := node.(type) {
+	case nil, *ast.File:
+		return false
+	case *ast.GenDecl:
+		for _, spec := range n.Specs {
+			if spec.Pos() <= decl.Ident.Pos() && decl.Ident.Pos() < spec.End() {
+				node = spec
+				break
+			}
+		}
+	}
+	pos := x.Ident.Pos()
+	return node.Pos() <= pos && pos < node.End()
+}
+
+// record returns the IndexRecord of x, with the given ID, linked to the
+// declarations in declIDs.
+func (x *Symb) record(id int, declIDs map[types.Object]int) IndexRecord {
+	obj := x.ReferObj
+	rec := IndexRecord{
+		ID:            id,
+		Name:          x.name(),
+		QualifiedName: x.QualifiedName(),
+		Kind:          objectIsa(obj),
+		Exported:      x.Visibility() == Exported,
+		Local:         x.Local,
+		IsDecl:        x.IsDecl(),
+		Decl:          -1,
+		Container:     -1,
+	}
+	if pkg := obj.Pkg(); pkg != nil {
+		rec.Pkg = pkg.Path()
+	}
+	if t := obj.Type(); t != nil && t != types.Typ[types.Invalid] {
+		rec.Type = t.String()
+	}
+	start, end := x.Span()
+	if p := x.position(start); p.IsValid() {
+		rec.File, rec.Start, rec.End = p.Filename, p.Offset, x.position(end).Offset
+	}
+	if id, present := declIDs[obj]; present {
+		rec.Decl = id
+	}
+	if x.Container != nil {
+		if id, present := declIDs[x.Container]; present {
+			rec.Container = id
+		}
+	}
+	return rec
+}
@@ -0,0 +1,151 @@
+package symb
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndex_SaveLoad(t *testing.T) {
+	idx := buildTestIndex(t, true)
+	var buf bytes.Buffer
+	if err := idx.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadIndex(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.IncludeRefs {
+		t.Errorf("got IncludeRefs false after loading, want true")
+	}
+
+	for _, name := range []string{"Circle", "Radius", "Area", "New", "radius", "c", "nonexistent"} {
+		want := idx.LookupRecords(name)
+		got := loaded.LookupRecords(name)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("LookupRecords(%q): got %+v after loading, want %+v", name, got, want)
+		}
+		// The records describe the symbs that Lookup returns.
+		symbs := idx.Lookup(name)
+		if len(symbs) != len(want) {
+			t.Fatalf("LookupRecords(%q): got %d records, want %d for the symbs", name, len(want), len(symbs))
+		}
+		for i, x := range symbs {
+			rec := want[i]
+			pos := x.Position(nil)
+			if rec.File != pos.Filename || rec.Start != pos.Offset || rec.End != pos.Offset+len(name) {
+				t.Errorf("LookupRecords(%q): got record %d at %s:%d-%d, want %s:%d", name, i, rec.File, rec.Start, rec.End, pos.Filename, pos.Offset)
+			}
+			if rec.QualifiedName != x.QualifiedName() || rec.IsDecl != x.IsDecl() || rec.Local != x.Local {
+				t.Errorf("LookupRecords(%q): got record %+v for symb %s", name, rec, x.QualifiedName())
+			}
+		}
+	}
+
+	if got, want := loaded.LookupQualifiedRecords("index/shapes", "New"), idx.LookupQualifiedRecords("index/shapes", "New"); len(want) != 1 || !reflect.DeepEqual(got, want) {
+		t.Errorf("LookupQualifiedRecords(index/shapes, New): got %+v after loading, want %+v (one record)", got, want)
+	}
+	if got, want := loaded.DeclRecords(), idx.DeclRecords(); len(want) != len(idx.Decls()) || !reflect.DeepEqual(got, want) {
+		t.Errorf("DeclRecords: got %+v after loading, want %+v (one for each of the %d Decls)", got, want, len(idx.Decls()))
+	}
+
+	// The methods that return symbs have none to return.
+	lookups := map[string]func(){
+		"Lookup":          func() { loaded.Lookup("Circle") },
+		"LookupQualified": func() { loaded.LookupQualified("index/shapes", "Circle") },
+		"Decls":           func() { loaded.Decls() },
+		"SearchPrefix":    func() { loaded.SearchPrefix("C", 0) },
+		"SearchFuzzy":     func() { loaded.SearchFuzzy("C", 0) },
+	}
+	for name, lookup := range lookups {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: got no panic on a loaded index", name)
+				}
+			}()
+			lookup()
+		}()
+	}
+
+	var radius IndexRecord
+	for _, rec := range loaded.LookupRecords("Radius") {
+		if rec.IsDecl {
+			radius = rec
+		}
+	}
+	if radius.QualifiedName != "index/shapes.Circle.Radius" || radius.Kind != "Var" || radius.Type != "float64" || !radius.Exported {
+		t.Errorf("got Radius record %+v", radius)
+	}
+	if circle := loaded.LookupRecords("Circle")[0]; radius.Container != circle.ID {
+		t.Errorf("got Radius container %d, want Circle's ID %d", radius.Container, circle.ID)
+	}
+	refs := loaded.Refs(radius.ID)
+	if !reflect.DeepEqual(refs, idx.Refs(radius.ID)) {
+		t.Errorf("Refs(Radius): got %+v after loading, want %+v", refs, idx.Refs(radius.ID))
+	}
+	if len(refs) != 3 {
+		t.Errorf("Refs(Radius): got %d references, want 3", len(refs))
+	}
+}
+
+func TestLoadIndex_version(t *testing.T) {
+	_, err := LoadIndex(strings.NewReader(`{"Version":0,"Records":[]}`))
+	if err == nil {
+		t.Errorf("got no error loading an index of version 0")
+	}
+}
+
+func TestIndex_RefCount(t *testing.T) {
+	pkg, err := parseTestPkg("refcount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	idx := NewIndex()
+	idx.IncludeRefs = true
+	if err := c.IterateSymbsPkg("refcount", pkg, idx.Add); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := idx.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadIndex(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name               string
+		refCount, selfRefs int
+	}{
+		{"helper", 2, 1},
+		{"Use", 0, 0},
+		{"List", 1, 1},
+		{"Ring", 0, 0},
+		{"unused", 0, 0},
+	}
+	for _, test := range tests {
+		for _, idx := range []*Index{idx, loaded} {
+			var decl IndexRecord
+			for _, rec := range idx.LookupRecords(test.name) {
+				if rec.IsDecl && rec.QualifiedName == "refcount."+test.name {
+					decl = rec
+				}
+			}
+			if got := idx.RefCount(decl.ID); got != test.refCount {
+				t.Errorf("RefCount(%s): got %d, want %d", test.name, got, test.refCount)
+			}
+			if decl.SelfRefs != test.selfRefs {
+				t.Errorf("%s: got %d self-references, want %d", test.name, decl.SelfRefs, test.selfRefs)
+			}
+			if n := len(idx.Refs(decl.ID)); n != test.refCount+test.selfRefs {
+				t.Errorf("Refs(%s): got %d references, want %d", test.name, n, test.refCount+test.selfRefs)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package symb
+
+import (
+	"time"
+)
+
+// A ProgressEvent reports that IterateSymbs has reached a phase boundary
+// while analyzing a package.
+type ProgressEvent struct {
+	Phase      ProgressPhase
+	ImportPath string        // import path of the package being analyzed
+	Filename   string        // file being walked, for FileStarted and FileFinished
+	Symbs      int           // number of symbs emitted for the package so far
+	Duration   time.Duration // time taken by the phase, for CheckFinished and FileFinished
+}
+
+// ProgressPhase identifies the phase boundary that a ProgressEvent reports.
+type ProgressPhase int
+
+const (
+	CheckStarted  ProgressPhase = iota // type-checking of the package started
+	CheckFinished                      // type-checking of the package finished
+	FileStarted                        // walking of a file started
+	FileFinished                       // walking of a file finished
+)
+
+func (p ProgressPhase) String() string {
+	switch p {
+	case CheckStarted:
+		return "CheckStarted"
+	case CheckFinished:
+		return "CheckFinished"
+	case FileStarted:
+		return "FileStarted"
+	case FileFinished:
+		return "FileFinished"
+	}
+	return "ProgressPhase(?)"
+}
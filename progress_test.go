@@ -0,0 +1,41 @@
+package symb
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	pkg, err := parseTestPkg("crossfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewContext()
+	c.FileSet = fset
+	var events []string
+	c.Progress = func(e ProgressEvent) {
+		if e.Duration < 0 {
+			t.Errorf("%s: negative duration %s", e.Phase, e.Duration)
+		}
+		events = append(events, fmt.Sprintf("%s %s %s %d", e.Phase, e.ImportPath, filepath.Base(e.Filename), e.Symbs))
+	}
+	err = c.IterateSymbsPkg("crossfile", pkg, func(*Symb) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"CheckStarted crossfile . 0",
+		"CheckFinished crossfile . 0",
+		"FileStarted crossfile a.go 0",
+		"FileFinished crossfile a.go 3",
+		"FileStarted crossfile b.go 3",
+		"FileFinished crossfile b.go 6",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got events\n%v\nwant\n%v", events, want)
+	}
+}
@@ -0,0 +1,124 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+)
+
+// QualifiedName returns a human-readable name for the object x refers to,
+// qualified by the import path of its package and by the type or function
+// it belongs to: "path/to/pkg.Func", "path/to/pkg.Type.Method",
+// "path/to/pkg.Type.Field", or "path/to/pkg.Func.local" (with the method's
+// receiver type before Func for locals in methods). Objects in the universe
+// scope have their bare name, and package names have their import path.
+// The name is meant for display and is not necessarily unique.
+func (x *Symb) QualifiedName() string {
+	obj := x.ReferObj
+	if obj == nil {
+		if x.Ident == nil {
+			return ""
+		}
+		return x.Ident.Name
+	}
+	if pkgName, isPkg := obj.(*types.PkgName); isPkg {
+		return pkgName.Imported().Path()
+	}
+	if x.Universe || obj.Pkg() == nil {
+		if owner := universeOwner(obj); owner != nil {
+			return owner.Name() + "." + obj.Name()
+		}
+		return obj.Name()
+	}
+
+	prefix := obj.Pkg().Path() + "."
+	if x.Anonymous {
+		// The synthesized name already includes the enclosing function.
+		return prefix + obj.Name()
+	}
+	if x.Local {
+		if f := enclosingFuncDecl(x.File, obj.Pos()); f != nil {
+			if f.Recv != nil && len(f.Recv.List) == 1 {
+				prefix += recvExprName(f.Recv.List[0].Type) + "."
+			}
+			return prefix + f.Name.Name + "." + obj.Name()
+		}
+	}
+	if owner := memberOwner(obj); owner != nil {
+		return prefix + owner.Name() + "." + obj.Name()
+	}
+	return prefix + obj.Name()
+}
+
+// memberOwner returns the named type that obj is a method or field of, or
+// nil if obj is not a member of a named type declared at package level.
+// Fields of anonymous struct types nested in a named type's declaration
+// belong to the named type.
+func memberOwner(obj types.Object) *types.TypeName {
+	switch obj := obj.(type) {
+	case *types.Func:
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			if named, ok := derefType(sig.Recv().Type()).(*types.Named); ok {
+				return named.Obj()
+			}
+		}
+	case *types.Var:
+		if !obj.IsField() {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	// Interface methods and struct fields do not know their owner, so
+	// search the package's named types for it.
+	scope := obj.Pkg().Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if ok && hasMember(tn.Type().Underlying(), obj) {
+			return tn
+		}
+	}
+	return nil
+}
+
+// hasMember reports whether obj is a field or method of the unnamed type
+// t, or a field of an anonymous struct type nested in t.
+func hasMember(t types.Type, obj types.Object) bool {
+	switch t := t.(type) {
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			f := t.Field(i)
+			if f == obj {
+				return true
+			}
+			if _, named := f.Type().(*types.Named); !named && hasMember(f.Type(), obj) {
+				return true
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumMethods(); i++ {
+			if t.Method(i) == obj {
+				return true
+			}
+		}
+	case *types.Pointer:
+		return hasMember(t.Elem(), obj)
+	case *types.Slice:
+		return hasMember(t.Elem(), obj)
+	case *types.Array:
+		return hasMember(t.Elem(), obj)
+	case *types.Map:
+		return hasMember(t.Elem(), obj)
+	case *types.Chan:
+		return hasMember(t.Elem(), obj)
+	}
+	return false
+}
+
+// derefType returns the element type of t if it is a pointer, and t
+// otherwise.
+func derefType(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
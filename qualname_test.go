@@ -0,0 +1,49 @@
+package symb
+
+import (
+	"testing"
+)
+
+func TestSymb_QualifiedName(t *testing.T) {
+	pkg, err := parseTestPkg("qualname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]string)
+	c := NewContext()
+	c.FileSet = fset
+	err = c.IterateSymbsPkg("qualname", pkg, func(symb *Symb) bool {
+		names[symb.Ident.Name] = symb.QualifiedName()
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"strings", "strings"},
+		{"ToUpper", "strings.ToUpper"},
+		{"Global", "qualname.Global"},
+		{"Shape", "qualname.Shape"},
+		{"Rect", "qualname.Rect"},
+		{"Width", "qualname.Rect.Width"},
+		{"X", "qualname.Rect.X"},
+		{"Area", "qualname.Rect.Area"},
+		{"Perimeter", "qualname.Shape.Perimeter"},
+		{"area", "qualname.Rect.Area.area"},
+		{"r", "qualname.Rect.Area.r"},
+		{"NewRect", "qualname.NewRect"},
+		{"rect", "qualname.NewRect.rect"},
+		{"width", "qualname.NewRect.width"},
+		{"len", "len"},
+		{"float64", "float64"},
+	}
+	for _, test := range tests {
+		if got := names[test.name]; got != test.want {
+			t.Errorf("%s: got qualified name %q, want %q", test.name, got, test.want)
+		}
+	}
+}
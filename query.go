@@ -0,0 +1,401 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"errors"
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// ErrNotIndexed is returned by queries that require the Context to retain
+// symbs when it has not been configured to do so.
+var ErrNotIndexed = errors.New("symbs are not indexed (set Context.IndexSymbs before iterating)")
+
+// ErrNotTracked is returned by FindReferences when the Context has not been
+// configured to track references.
+var ErrNotTracked = errors.New("references are not tracked (set Context.TrackReferences before iterating)")
+
+// ErrUniverse is returned by Definition for objects in the universe scope,
+// such as builtin functions and predeclared types, which have no
+// declaration in source.
+var ErrUniverse = errors.New("object is in the universe scope")
+
+// ErrNoPosition is returned by Definition for objects whose declaration
+// position is unknown, such as objects imported from compiled packages.
+var ErrNoPosition = errors.New("object has no known position")
+
+// ErrNoSymb is returned by FindSymbolAt when no symb covers a position.
+var ErrNoSymb = errors.New("no symb at position")
+
+// ErrNotNamedType is returned by MethodsOf for objects that are not named
+// types.
+var ErrNotNamedType = errors.New("object is not a named type")
+
+// ErrNotInterface is returned by ImplementedBy for objects that are not
+// named interface types.
+var ErrNotInterface = errors.New("object is not a named interface type")
+
+// ErrEmptyInterface is returned by ImplementedBy for interfaces with no
+// methods, which every type implements.
+var ErrEmptyInterface = errors.New("interface has no methods")
+
+// FindSymbolAt returns the symb emitted by a previous iteration whose Ident
+// covers pos, that is, pos is at or after the start of the identifier and
+// before its end. For a selector expression such as fmt.Println, a
+// position in fmt yields the symb for the package and a position in
+// Println yields the symb for the function. ctxt.IndexSymbs must have been
+// set during the iteration.
+func (ctxt *Context) FindSymbolAt(pos token.Pos) (*Symb, error) {
+	if !ctxt.IndexSymbs {
+		return nil, ErrNotIndexed
+	}
+	if !ctxt.posIndexSorted {
+		sort.Stable(symbPtrsByPos(ctxt.posIndex))
+		ctxt.posIndexSorted = true
+	}
+
+	// Find the last symb that starts at or before pos.
+	i := sort.Search(len(ctxt.posIndex), func(i int) bool {
+		return ctxt.posIndex[i].Ident.Pos() > pos
+	}) - 1
+	if i < 0 || pos >= ctxt.posIndex[i].Ident.End() {
+		return nil, ErrNoSymb
+	}
+	return ctxt.posIndex[i], nil
+}
+
+// FindReferences returns the symbs emitted by previous iterations that
+// refer to obj, in emission order. The declaration of obj is included only
+// if includeDecl is true. ctxt.TrackReferences must have been set during
+// the iterations.
+//
+// Objects are compared by identity. All files of a package are checked
+// together, so references from any file of the package are found, but
+// the objects of separately checked packages are distinct: references to
+// obj from a package that imports obj's package are not found.
+func (ctxt *Context) FindReferences(obj types.Object, includeDecl bool) ([]*Symb, error) {
+	if !ctxt.TrackReferences {
+		return nil, ErrNotTracked
+	}
+	var refs []*Symb
+	for _, symb := range ctxt.refsByObj[obj] {
+		if includeDecl || !symb.IsDecl() {
+			refs = append(refs, symb)
+		}
+	}
+	return refs, nil
+}
+
+// Definition resolves the symb at pos (as FindSymbolAt does) and returns
+// the position of the declaration of the object it refers to (resolved as
+// by ctxt.Position, so relative to ctxt.BaseDir if it is set), along with
+// the object. For universe objects it returns ErrUniverse, and for objects
+// whose declaration position is unknown (e.g., those imported from
+// compiled packages) it returns ErrNoPosition; the object is returned in
+// both cases.
+func (ctxt *Context) Definition(pos token.Pos) (token.Position, types.Object, error) {
+	symb, err := ctxt.FindSymbolAt(pos)
+	if err != nil {
+		return token.Position{}, nil, err
+	}
+	if symb.Universe {
+		return token.Position{}, symb.ReferObj, ErrUniverse
+	}
+	if !symb.ReferPos.IsValid() {
+		return token.Position{}, symb.ReferObj, ErrNoPosition
+	}
+	return ctxt.Position(symb.ReferPos), symb.ReferObj, nil
+}
+
+// ObjectOf returns the object that id declares or refers to, or nil if it
+// is unknown. id must be in the package most recently iterated over by
+// ctxt. The result is the ReferObj of the symb emitted for id, if any,
+// including for identifiers that the checker records no object for, such
+// as type switch variables.
+func (ctxt *Context) ObjectOf(id *ast.Ident) types.Object {
+	obj, _ := ctxt.exprInfo(id)
+	return obj
+}
+
+// TypeOf returns the type of e, or nil if it is unknown. Like the ExprType
+// of a plain identifier's symb, it is the base type of e (see
+// typeBaseType), with arrays, slices, maps, and pointers replaced by their
+// element types; for an identifier that is not an expression, such as the
+// name in a declaration, it is the type of the declared object. e must be
+// in the package most recently iterated over by ctxt.
+func (ctxt *Context) TypeOf(e ast.Expr) types.Type {
+	if id, isIdent := e.(*ast.Ident); isIdent {
+		_, typ := ctxt.exprInfo(id)
+		return typ
+	}
+	if tv, present := ctxt.info.Types[e]; present && tv.Type != nil {
+		return typeBaseType(tv.Type)
+	}
+	return nil
+}
+
+// A MethodInfo describes a method in the method set of a named type, as
+// returned by MethodsOf.
+type MethodInfo struct {
+	Method *types.Func
+
+	// Promoted is whether the method is promoted from an embedded field of
+	// the type's struct, and Embedded is that field (the outermost one, if
+	// the method is promoted through several).
+	Promoted bool
+	Embedded *types.Var
+
+	// PointerOnly is whether the method is only in the method set of a
+	// pointer to the type, because its receiver is a pointer (and it is
+	// not promoted through an embedded pointer).
+	PointerOnly bool
+
+	// Position is the position of the method's declaration, or the zero
+	// Position if it is not in the package most recently iterated over.
+	Position token.Position
+}
+
+// MethodsOf returns the methods of the named type t: the methods in the
+// method set of a pointer to it (which include those of the type itself),
+// in the order of the type checker's method set. For an interface type, they are the methods of the
+// interface, including those of the interfaces it embeds, which are not
+// taken to be promoted. t may be a type of the package most recently
+// iterated over by ctxt or, as far as the type checker knows its methods,
+// of a package it imports. MethodsOf returns ErrNotNamedType if t is not a
+// named type.
+func (ctxt *Context) MethodsOf(t types.Object) ([]MethodInfo, error) {
+	tn, isTypeName := t.(*types.TypeName)
+	if !isTypeName {
+		return nil, ErrNotNamedType
+	}
+	named, isNamed := tn.Type().(*types.Named)
+	if !isNamed {
+		return nil, ErrNotNamedType
+	}
+
+	valueSet := types.NewMethodSet(named)
+	mset := valueSet
+	if !types.IsInterface(named) {
+		mset = types.NewMethodSet(types.NewPointer(named))
+	}
+	methods := make([]MethodInfo, 0, mset.Len())
+	for i := 0; i < mset.Len(); i++ {
+		sel := mset.At(i)
+		m, isFunc := sel.Obj().(*types.Func)
+		if !isFunc {
+			continue
+		}
+		info := MethodInfo{
+			Method:      m,
+			PointerOnly: valueSet.Lookup(m.Pkg(), m.Name()) == nil,
+		}
+		if index := sel.Index(); len(index) > 1 {
+			if st, isStruct := named.Underlying().(*types.Struct); isStruct {
+				info.Promoted = true
+				info.Embedded = st.Field(index[0])
+			}
+		}
+		if m.Pkg() == ctxt.currentPackage && m.Pos().IsValid() {
+			info.Position = ctxt.Position(m.Pos())
+		}
+		methods = append(methods, info)
+	}
+	return methods, nil
+}
+
+// An Implementer is a named type that implements an interface, as
+// returned by ImplementedBy.
+type Implementer struct {
+	Type *types.TypeName
+
+	// PointerOnly is whether only a pointer to the type implements the
+	// interface, because some of the methods have pointer receivers.
+	PointerOnly bool
+}
+
+// ImplementedBy returns the named types that implement the named interface
+// type iface, among the package-level types of the package most recently
+// iterated over by ctxt and of the packages it imports, directly or
+// indirectly: first those of the package itself, then those of the others
+// in order of import path, each sorted by name. Interface types are not
+// included. ImplementedBy returns ErrNotInterface if iface is not a named
+// interface type, and ErrEmptyInterface if it has no methods, rather than
+// every type.
+func (ctxt *Context) ImplementedBy(iface types.Object) ([]Implementer, error) {
+	tn, isTypeName := iface.(*types.TypeName)
+	if !isTypeName {
+		return nil, ErrNotInterface
+	}
+	it, isInterface := tn.Type().Underlying().(*types.Interface)
+	if !isInterface {
+		return nil, ErrNotInterface
+	}
+	if it.NumMethods() == 0 {
+		return nil, ErrEmptyInterface
+	}
+	if ctxt.currentPackage == nil {
+		return nil, nil
+	}
+
+	pkgs := []*types.Package{ctxt.currentPackage}
+	seen := map[*types.Package]bool{ctxt.currentPackage: true}
+	var imports []*types.Package
+	var add func([]*types.Package)
+	add = func(imported []*types.Package) {
+		for _, pkg := range imported {
+			if !seen[pkg] {
+				seen[pkg] = true
+				imports = append(imports, pkg)
+				add(pkg.Imports())
+			}
+		}
+	}
+	add(ctxt.currentPackage.Imports())
+	sort.Sort(packagesByPath(imports))
+	pkgs = append(pkgs, imports...)
+
+	var impls []Implementer
+	for _, pkg := range pkgs {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			t, isTypeName := scope.Lookup(name).(*types.TypeName)
+			if !isTypeName || types.IsInterface(t.Type()) {
+				continue
+			}
+			if _, isNamed := t.Type().(*types.Named); !isNamed {
+				continue
+			}
+			if value, pointer := implements(t.Type(), it); value || pointer {
+				impls = append(impls, Implementer{Type: t, PointerOnly: !value})
+			}
+		}
+	}
+	return impls, nil
+}
+
+// implements reports whether the type t and a pointer to it implement
+// iface: whether their method sets contain each method of iface, with an
+// identical signature.
+func implements(t types.Type, iface *types.Interface) (value, pointer bool) {
+	value, pointer = true, true
+	valueSet, pointerSet := types.NewMethodSet(t), types.NewMethodSet(types.NewPointer(t))
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		value = value && hasMethod(valueSet, m)
+		pointer = pointer && hasMethod(pointerSet, m)
+		if !pointer {
+			// The method set of *t includes that of t.
+			return false, false
+		}
+	}
+	return value, pointer
+}
+
+// hasMethod reports whether mset contains a method with the name and
+// signature of m.
+func hasMethod(mset *types.MethodSet, m *types.Func) bool {
+	sel := mset.Lookup(m.Pkg(), m.Name())
+	return sel != nil && sel.Kind() == types.MethodVal && types.IsIdentical(sel.Obj().Type(), m.Type())
+}
+
+// A Description summarizes the symb at a position, for display in an
+// editor, as returned by Describe.
+type Description struct {
+	Symb          *Symb
+	Kind          string         // kind of the object, as in the Isa field of a symb's JSON encoding
+	QualifiedName string         // see Symb.QualifiedName
+	DeclPosition  token.Position // position of the declaration, or the zero Position if it is unknown
+	Doc           string         // text of the declaration's doc comment, if the declaration was indexed
+	Type          string         // the signature of a function, the underlying type of a named type, or the type of another object
+	ConstVal      string         // the value of a constant, or ""
+	Func          types.Object   // for a function-local object, the function (or literal) that declares it
+}
+
+// Describe returns a Description of the symb at pos, which may be a
+// declaration or a reference. Like FindSymbolAt, which it uses, it
+// requires ctxt.IndexSymbs to have been set during the iteration, and
+// returns ErrNoSymb if no symb covers pos (as in whitespace or comments).
+// The doc comment is that of the declaration's DeclNode, if the
+// declaration is also in the index; nothing is parsed or checked again.
+func (ctxt *Context) Describe(pos token.Pos) (*Description, error) {
+	x, err := ctxt.FindSymbolAt(pos)
+	if err != nil {
+		return nil, err
+	}
+	d := &Description{Symb: x, QualifiedName: x.QualifiedName()}
+	if x.ConstVal != nil {
+		d.ConstVal = x.ConstVal.String()
+	}
+	obj := x.ReferObj
+	if obj == nil {
+		return d, nil
+	}
+	d.Kind = objectIsa(obj)
+	if t := obj.Type(); t != nil && t != types.Typ[types.Invalid] {
+		if _, isTypeName := obj.(*types.TypeName); isTypeName {
+			t = t.Underlying()
+		}
+		d.Type = t.String()
+	}
+	if x.Local {
+		d.Func = x.Container
+	}
+	if x.Universe || !x.ReferPos.IsValid() {
+		return d, nil
+	}
+	d.DeclPosition = ctxt.Position(x.ReferPos)
+
+	decl := x
+	if !x.IsDecl() {
+		decl, err = ctxt.FindSymbolAt(x.ReferPos)
+		if err != nil || decl.ReferObj != obj || !decl.IsDecl() {
+			return d, nil
+		}
+	}
+	d.Doc = declDoc(decl)
+	return d, nil
+}
+
+// declDoc returns the text of the doc comment of the declaration decl: that
+// of its spec, for a name declared in a parenthesized const, var, or type
+// declaration, and otherwise that of its DeclNode; for a package, it is
+// PkgDoc.
+func declDoc(decl *Symb) string {
+	var doc *ast.CommentGroup
+	switch n := decl.DeclNode.(type) {
+	case *ast.File:
+		return decl.PkgDoc
+	case *ast.FuncDecl:
+		doc = n.Doc
+	case *ast.Field:
+		doc = n.Doc
+	case *ast.GenDecl:
+		doc = n.Doc
+		for _, spec := range n.Specs {
+			if !(spec.Pos() <= decl.Ident.Pos() && decl.Ident.Pos() < spec.End()) {
+				continue
+			}
+			switch spec := spec.(type) {
+			case *ast.ValueSpec:
+				if spec.Doc != nil {
+					doc = spec.Doc
+				}
+			case *ast.TypeSpec:
+				if spec.Doc != nil {
+					doc = spec.Doc
+				}
+			}
+		}
+	}
+	if doc == nil {
+		return ""
+	}
+	return doc.Text()
+}
+
+type symbPtrsByPos []*Symb
+
+func (s symbPtrsByPos) Len() int           { return len(s) }
+func (s symbPtrsByPos) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s symbPtrsByPos) Less(i, j int) bool { return s[i].Ident.Pos() < s[j].Ident.Pos() }
@@ -0,0 +1,456 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testPos returns the position of the byte at offset delta from the first
+// occurrence of substr in the named file, as most recently parsed into
+// fset.
+func testPos(t *testing.T, filename, substr string, delta int) token.Pos {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := strings.Index(string(src), substr)
+	if off == -1 {
+		t.Fatalf("%q not found in %s", substr, filename)
+	}
+	var pos token.Pos
+	fset.Iterate(func(f *token.File) bool {
+		if f.Name() == filename {
+			pos = f.Pos(off + delta)
+		}
+		return true
+	})
+	if pos == token.NoPos {
+		t.Fatalf("%s is not in the FileSet", filename)
+	}
+	return pos
+}
+
+func TestFindSymbolAt(t *testing.T) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.IndexSymbs = true
+	if err := c.IterateSymbsPkg("foo", pkg, func(*Symb) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join("testdata", "src", "foo", "local.go")
+	const line = "println(NonLocalVar, localRecv.NonLocalFunc"
+	tests := []struct {
+		delta int
+		want  string // pretty-printed Expr, or "" for not found
+	}{
+		{len("println("), "NonLocalVar"},                                           // start
+		{len("println(NonLoc"), "NonLocalVar"},                                     // middle
+		{len("println(NonLocalVar") - 1, "NonLocalVar"},                            // end
+		{len("println(NonLocalVar,"), ""},                                          // whitespace
+		{len("println(NonLocalVar, local"), "localRecv"},                           // X of selector
+		{len("println(NonLocalVar, localRecv.NonLocal"), "localRecv.NonLocalFunc"}, // Sel of selector
+	}
+	for _, test := range tests {
+		symb, err := c.FindSymbolAt(testPos(t, filename, line, test.delta))
+		if test.want == "" {
+			if err != ErrNoSymb {
+				t.Errorf("offset %d: got %v, %v, want ErrNoSymb", test.delta, symb, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("offset %d: %s", test.delta, err)
+			continue
+		}
+		if got := pretty(symb.Expr); got != test.want {
+			t.Errorf("offset %d: got symb %s, want %s", test.delta, got, test.want)
+		}
+	}
+}
+
+func TestFindSymbolAt_notIndexed(t *testing.T) {
+	c := NewContext()
+	if _, err := c.FindSymbolAt(token.Pos(1)); err != ErrNotIndexed {
+		t.Errorf("got error %v, want ErrNotIndexed", err)
+	}
+}
+
+func TestFindReferences(t *testing.T) {
+	pkg, err := parseTestPkg("refs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.TrackReferences = true
+	objs := make(map[string]types.Object)
+	err = c.IterateSymbsPkg("refs", pkg, func(symb *Symb) bool {
+		if symb.IsDecl() {
+			objs[symb.Ident.Name] = symb.ReferObj
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		includeDecl bool
+		want        []string
+	}{
+		{"Counter", false, []string{"a.go:6", "b.go:4", "b.go:5"}},
+		{"Counter", true, []string{"a.go:3", "a.go:6", "b.go:4", "b.go:5"}},
+		{"local", false, []string{"b.go:5"}},
+		{"local", true, []string{"b.go:4", "b.go:5"}},
+	}
+	for _, test := range tests {
+		refs, err := c.FindReferences(objs[test.name], test.includeDecl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, ref := range refs {
+			got = append(got, shortPosition(ref.Ident.Pos()))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("FindReferences(%s, %v): got %v, want %v", test.name, test.includeDecl, got, test.want)
+		}
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	pkg, err := parseTestPkg("refs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.IndexSymbs = true
+	if err := c.IterateSymbsPkg("refs", pkg, func(*Symb) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	b := filepath.Join("testdata", "src", "refs", "b.go")
+	tests := []struct {
+		pos     token.Pos
+		want    string // file:line of the definition
+		wantErr error
+	}{
+		{testPos(t, b, "local + Counter", 0), "b.go:4", nil},               // local
+		{testPos(t, b, "local + Counter", len("local + ")), "a.go:3", nil}, // other file
+		{testPos(t, b, "len(", 0), "", ErrUniverse},                        // builtin
+		{testPos(t, b, "return local", len("return")), "", ErrNoSymb},      // whitespace
+	}
+	for _, test := range tests {
+		position, obj, err := c.Definition(test.pos)
+		if err != test.wantErr {
+			t.Errorf("%s: got error %v, want %v", fset.Position(test.pos), err, test.wantErr)
+			continue
+		}
+		if err == ErrUniverse && obj.Name() != "len" {
+			t.Errorf("%s: got object %s, want len", fset.Position(test.pos), obj.Name())
+		}
+		if err != nil {
+			continue
+		}
+		if got := fmt.Sprintf("%s:%d", filepath.Base(position.Filename), position.Line); got != test.want {
+			t.Errorf("%s: got definition at %s, want %s", fset.Position(test.pos), got, test.want)
+		}
+	}
+
+	// The filename is relative to BaseDir.
+	c.BaseDir = filepath.Join("testdata", "src")
+	position, _, err := c.Definition(testPos(t, b, "local + Counter", len("local + ")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("refs", "a.go"); position.Filename != want {
+		t.Errorf("got definition in %s with BaseDir set, want %s", position.Filename, want)
+	}
+}
+
+func TestObjectOfTypeOf(t *testing.T) {
+	c := NewContext()
+	file, err := c.ParseFile(filepath.Join("testdata", "src", "selections", "selections.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	symbs := make(map[*ast.Ident]*Symb)
+	err = c.IterateSymbs("selections", []*ast.File{file}, func(symb *Symb) bool {
+		if _, isIdent := symb.Expr.(*ast.Ident); isIdent {
+			symbs[symb.Ident] = symb
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	ast.Inspect(file, func(node ast.Node) bool {
+		id, ok := node.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		symb := symbs[id]
+		if symb == nil || id == file.Name {
+			// The package clause's symb has a synthesized object.
+			return true
+		}
+		n++
+		if obj := c.ObjectOf(id); obj != symb.ReferObj {
+			t.Errorf("ObjectOf(%s at %s): got %v, want %v", id.Name, c.FileSet.Position(id.Pos()), obj, symb.ReferObj)
+		}
+		if typ := c.TypeOf(id); typ != symb.ExprType {
+			t.Errorf("TypeOf(%s at %s): got %v, want %v", id.Name, c.FileSet.Position(id.Pos()), typ, symb.ExprType)
+		}
+		return true
+	})
+	if n == 0 {
+		t.Fatal("found no identifiers with symbs")
+	}
+
+	// No symbs are emitted for calls, but their types are known.
+	var call *ast.CallExpr
+	ast.Inspect(file, func(node ast.Node) bool {
+		if e, ok := node.(*ast.CallExpr); ok && call == nil {
+			call = e
+		}
+		return true
+	})
+	if typ := c.TypeOf(call); typ == nil || typ.String() != "string" {
+		t.Errorf("TypeOf(%s): got %v, want string", pretty(call), typ)
+	}
+}
+
+func TestMethodsOf(t *testing.T) {
+	pkg, err := parseTestPkg("methodset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.IndexSymbs = true
+	if err := c.IterateSymbsPkg("methodset", pkg, func(*Symb) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join("testdata", "src", "methodset", "methodset.go")
+	// lookup returns the object declared by the identifier that follows
+	// the keyword in decl.
+	lookup := func(decl string) types.Object {
+		x, err := c.FindSymbolAt(testPos(t, filename, decl, strings.Index(decl, " ")+1))
+		if err != nil {
+			t.Fatalf("%s: %s", decl, err)
+		}
+		return x.ReferObj
+	}
+
+	tests := []struct {
+		typeName string
+		want     []string // name, embedded field (or "-"), and "ptr" or "val" for each method
+	}{
+		{"type Base", []string{"Name - val", "SetName - ptr"}},
+		{"type Derived", []string{"Describe - val", "Log Logger val", "Name Base val", "Reset - ptr", "SetName Base ptr"}},
+		{"type Shape", []string{"Area - val", "Perimeter - val"}},
+	}
+	for _, test := range tests {
+		methods, err := c.MethodsOf(lookup(test.typeName))
+		if err != nil {
+			t.Errorf("MethodsOf(%s): %s", test.typeName, err)
+			continue
+		}
+		var got []string
+		for _, m := range methods {
+			embedded, recv := "-", "val"
+			if m.Promoted {
+				embedded = m.Embedded.Name()
+			}
+			if m.PointerOnly {
+				recv = "ptr"
+			}
+			got = append(got, fmt.Sprintf("%s %s %s", m.Method.Name(), embedded, recv))
+
+			// Methods declared in the package have their positions.
+			if m.Method.Pkg().Path() == "methodset" && !m.Position.IsValid() {
+				t.Errorf("MethodsOf(%s): got no position for %s", test.typeName, m.Method.Name())
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("MethodsOf(%s): got %v, want %v", test.typeName, got, test.want)
+		}
+	}
+
+	// Methods promoted from an imported type have no position.
+	methods, err := c.MethodsOf(lookup("type Buffered"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(methods) == 0 {
+		t.Fatalf("MethodsOf(Buffered): got no methods")
+	}
+	for _, m := range methods {
+		if !m.Promoted || m.Embedded.Name() != "Buffer" || m.Position.IsValid() {
+			t.Errorf("MethodsOf(Buffered): got %s promoted %v via %v at %s", m.Method.Name(), m.Promoted, m.Embedded, m.Position)
+		}
+	}
+
+	if _, err := c.MethodsOf(lookup("var count")); err != ErrNotNamedType {
+		t.Errorf("MethodsOf(count): got error %v, want ErrNotNamedType", err)
+	}
+}
+
+func TestImplementedBy(t *testing.T) {
+	pkg, err := parseTestPkg("implby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.IndexSymbs = true
+	if err := c.IterateSymbsPkg("implby", pkg, func(*Symb) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join("testdata", "src", "implby", "implby.go")
+	lookup := func(decl string) types.Object {
+		x, err := c.FindSymbolAt(testPos(t, filename, decl, strings.Index(decl, " ")+1))
+		if err != nil {
+			t.Fatalf("%s: %s", decl, err)
+		}
+		return x.ReferObj
+	}
+
+	impls, err := c.ImplementedBy(lookup("type Store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, impl := range impls {
+		s := impl.Type.Name()
+		if impl.PointerOnly {
+			s = "*" + s
+		}
+		got = append(got, s)
+	}
+	if want := []string{"*DiskStore", "MemStore"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ImplementedBy(Store): got %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		decl string
+		want error
+	}{
+		{"type Any", ErrEmptyInterface},
+		{"type MemStore", ErrNotInterface},
+		{"var count", ErrNotInterface},
+	}
+	for _, test := range tests {
+		if _, err := c.ImplementedBy(lookup(test.decl)); err != test.want {
+			t.Errorf("ImplementedBy(%s): got error %v, want %v", test.decl, err, test.want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	pkg, err := parseTestPkg("describe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	if _, err := c.Describe(token.NoPos); err != ErrNotIndexed {
+		t.Errorf("got error %v before indexing, want ErrNotIndexed", err)
+	}
+	c.IndexSymbs = true
+	if err := c.IterateSymbsPkg("describe", pkg, func(*Symb) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join("testdata", "src", "describe", "describe.go")
+	tests := []struct {
+		substr string
+		delta  int
+		want   Description // without Symb or the Offset of DeclPosition; Func is compared by name
+	}{
+		{ // method call site
+			"visitor.Greet(", len("visitor."),
+			Description{
+				Kind:          "Func",
+				QualifiedName: "describe.Visitor.Greet",
+				DeclPosition:  token.Position{Filename: filename, Line: 12, Column: 19},
+				Doc:           "Greet returns the greeting for v.\n",
+				Type:          "func(punct string) string",
+			},
+		},
+		{ // const reference
+			"return Greeting", len("return "),
+			Description{
+				Kind:          "Const",
+				QualifiedName: "describe.Greeting",
+				DeclPosition:  token.Position{Filename: filename, Line: 4, Column: 7},
+				Doc:           "Greeting is said to each visitor.\n",
+				Type:          "untyped string",
+				ConstVal:      `"hello"`,
+			},
+		},
+		{ // local variable
+			"visitor.Greet(", 0,
+			Description{
+				Kind:          "Var",
+				QualifiedName: "describe.Welcome.visitor",
+				DeclPosition:  token.Position{Filename: filename, Line: 19, Column: 3},
+				Type:          "*describe.Visitor",
+				Func:          types.NewFunc(token.NoPos, nil, "Welcome", nil),
+			},
+		},
+		{ // named type declaration
+			"type Visitor", len("type "),
+			Description{
+				Kind:          "TypeName",
+				QualifiedName: "describe.Visitor",
+				DeclPosition:  token.Position{Filename: filename, Line: 7, Column: 6},
+				Doc:           "A Visitor is someone who visits.\n",
+				Type:          "struct{Name string}",
+			},
+		},
+	}
+	for _, test := range tests {
+		d, err := c.Describe(testPos(t, filename, test.substr, test.delta))
+		if err != nil {
+			t.Errorf("%q+%d: %s", test.substr, test.delta, err)
+			continue
+		}
+		if d.Symb == nil || d.Symb.ReferObj == nil {
+			t.Errorf("%q+%d: got no symb", test.substr, test.delta)
+			continue
+		}
+		if (d.Func == nil) != (test.want.Func == nil) || d.Func != nil && d.Func.Name() != test.want.Func.Name() {
+			t.Errorf("%q+%d: got Func %v, want %v", test.substr, test.delta, d.Func, test.want.Func)
+		}
+		got := *d
+		got.Symb, got.Func, test.want.Func = nil, nil, nil
+		got.DeclPosition.Offset = 0
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q+%d: got %+v, want %+v", test.substr, test.delta, got, test.want)
+		}
+	}
+
+	// Whitespace and comments have no symb.
+	for _, substr := range []string{"// A Visitor", " Welcome"} {
+		if _, err := c.Describe(testPos(t, filename, substr, 0)); err != ErrNoSymb {
+			t.Errorf("%q: got error %v, want ErrNoSymb", substr, err)
+		}
+	}
+}
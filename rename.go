@@ -0,0 +1,215 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"unicode"
+)
+
+// An Edit replaces Length bytes at byte offset Offset of the named file
+// with Replacement.
+type Edit struct {
+	Filename    string
+	Offset      int
+	Length      int
+	Replacement string
+}
+
+// RenameEdits returns the edits that rename obj, and every reference to it
+// found by ctxt, to newName. ctxt.TrackReferences must have been set while
+// iterating over the packages to be edited. The edits are sorted by
+// filename and offset.
+//
+// RenameEdits refuses to rename objects in the universe scope, in imported
+// packages for which only synthetic symbs were emitted, or synthesized for
+// function literals, and returns an error if the rename would conflict with
+// another object named newName. For a field or method, that is a field or
+// method of the type it belongs to (including promoted ones). For another
+// object, it is one declared in obj's package scope, an import in one of
+// the package's files, or a function-local one declared in a function
+// that also contains obj or a reference to it. The check is conservative:
+// it does not consider the block structure of functions.
+func RenameEdits(ctxt *Context, obj types.Object, newName string) ([]Edit, error) {
+	if !ctxt.TrackReferences {
+		return nil, ErrNotTracked
+	}
+	if !isIdent(newName) || newName == "_" {
+		return nil, fmt.Errorf("%q is not a valid identifier", newName)
+	}
+	refs, err := ctxt.FindReferences(obj, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("no references to %s", obj.Name())
+	}
+	if refs[0].Universe {
+		return nil, fmt.Errorf("cannot rename %s: it is in the universe scope", obj.Name())
+	}
+	for _, ref := range refs {
+		if ref.Synthetic {
+			return nil, fmt.Errorf("cannot rename %s: it is declared in an imported package", obj.Name())
+		}
+		if ref.Anonymous {
+			return nil, fmt.Errorf("cannot rename %s: it is a function literal", obj.Name())
+		}
+	}
+
+	if err := ctxt.checkRenameConflicts(obj, refs, newName); err != nil {
+		return nil, err
+	}
+
+	var edits []Edit
+	seen := make(map[token.Pos]bool)
+	for _, ref := range refs {
+		if ref.Implicit {
+			// There is no name in the source to edit.
+			continue
+		}
+		if seen[ref.Ident.Pos()] {
+			continue
+		}
+		seen[ref.Ident.Pos()] = true
+		pos := ctxt.FileSet.PositionFor(ref.Ident.Pos(), false)
+		edits = append(edits, Edit{
+			Filename:    pos.Filename,
+			Offset:      pos.Offset,
+			Length:      len(ref.Ident.Name),
+			Replacement: newName,
+		})
+	}
+	sort.Sort(editsByPos(edits))
+	return edits, nil
+}
+
+// checkRenameConflicts returns an error if renaming obj, which is referred
+// to by refs, to newName would conflict with another object.
+func (ctxt *Context) checkRenameConflicts(obj types.Object, refs []*Symb, newName string) error {
+	if owner := renameOwner(obj, refs); owner != nil {
+		// Members are selected, so only the other members of the type can
+		// conflict.
+		if other, _, _ := types.LookupFieldOrMethod(owner, obj.Pkg(), newName); other != nil && other != obj {
+			return fmt.Errorf("cannot rename %s to %s: conflicts with %s declared at %s", obj.Name(), newName, newName, ctxt.FileSet.Position(other.Pos()))
+		}
+		return nil
+	}
+
+	// Functions that contain obj or a reference to it.
+	funcs := make(map[*ast.FuncDecl]bool)
+	for _, ref := range refs {
+		if f := enclosingFuncDecl(ref.File, ref.Ident.Pos()); f != nil {
+			funcs[f] = true
+		}
+	}
+
+	if pkg := obj.Pkg(); pkg != nil && !ctxt.isLocal(obj) {
+		if other := pkg.Scope().Lookup(newName); other != nil {
+			return fmt.Errorf("cannot rename %s to %s: conflicts with %s declared at %s", obj.Name(), newName, newName, ctxt.FileSet.Position(other.Pos()))
+		}
+		// The package's files are the children of its scope, and hold
+		// its imports.
+		scope := pkg.Scope()
+		for i := 0; i < scope.NumChildren(); i++ {
+			if imp, isPkgName := scope.Child(i).Lookup(newName).(*types.PkgName); isPkgName {
+				return fmt.Errorf("cannot rename %s to %s: conflicts with the import of %s at %s", obj.Name(), newName, imp.Imported().Path(), ctxt.FileSet.Position(imp.Pos()))
+			}
+		}
+	}
+
+	for other, symbs := range ctxt.refsByObj {
+		if other == nil || other == obj || other.Name() != newName {
+			continue
+		}
+		for _, symb := range symbs {
+			if !funcs[enclosingFuncDecl(symb.File, symb.Ident.Pos())] {
+				continue
+			}
+			// A local named newName would shadow obj; a non-local one
+			// referred to near obj would be shadowed by the renamed obj.
+			if ctxt.isLocal(other) || ctxt.isLocal(obj) {
+				return fmt.Errorf("cannot rename %s to %s: conflicts with %s at %s", obj.Name(), newName, newName, ctxt.FileSet.Position(symb.Ident.Pos()))
+			}
+		}
+	}
+	return nil
+}
+
+// renameOwner returns the type that obj, which is referred to by refs,
+// belongs to if it is a field or method: the named type (see memberOwner),
+// or, for a field or method of a type literal, the type of its Container.
+// It returns nil for other objects.
+func renameOwner(obj types.Object, refs []*Symb) types.Type {
+	switch obj := obj.(type) {
+	case *types.Var:
+		if !obj.IsField() {
+			return nil
+		}
+	case *types.Func:
+		if sig, ok := obj.Type().(*types.Signature); !ok || sig.Recv() == nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if owner := memberOwner(obj); owner != nil {
+		return owner.Type()
+	}
+	for _, ref := range refs {
+		if ref.Container != nil {
+			return ref.Container.Type()
+		}
+	}
+	return nil
+}
+
+// isLocal reports whether obj is function-local, according to the tracked
+// symbs that refer to it.
+func (ctxt *Context) isLocal(obj types.Object) bool {
+	for _, symb := range ctxt.refsByObj[obj] {
+		if symb.Local {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosingFuncDecl returns the function declaration in file that contains
+// pos, or nil if there is none.
+func enclosingFuncDecl(file *ast.File, pos token.Pos) *ast.FuncDecl {
+	if file == nil {
+		return nil
+	}
+	for _, decl := range file.Decls {
+		if f, ok := decl.(*ast.FuncDecl); ok && f.Pos() <= pos && pos < f.End() {
+			return f
+		}
+	}
+	return nil
+}
+
+// isIdent reports whether s is a valid Go identifier.
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !(c == '_' || unicode.IsLetter(c) || i > 0 && unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return !token.Lookup(s).IsKeyword()
+}
+
+type editsByPos []Edit
+
+func (e editsByPos) Len() int      { return len(e) }
+func (e editsByPos) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e editsByPos) Less(i, j int) bool {
+	if e[i].Filename != e[j].Filename {
+		return e[i].Filename < e[j].Filename
+	}
+	return e[i].Offset < e[j].Offset
+}
@@ -0,0 +1,147 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenameEdits(t *testing.T) {
+	pkg, err := parseTestPkg("refs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.TrackReferences = true
+	objs := make(map[string]types.Object)
+	err = c.IterateSymbsPkg("refs", pkg, func(symb *Symb) bool {
+		if symb.IsDecl() {
+			objs[symb.Ident.Name] = symb.ReferObj
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edits, err := RenameEdits(c, objs["Counter"], "Total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := filepath.Join("testdata", "src", "refs", "a.go"), filepath.Join("testdata", "src", "refs", "b.go")
+	want := []Edit{
+		{a, 18, 7, "Total"},
+		{a, 46, 7, "Total"},
+		{b, 41, 7, "Total"},
+		{b, 65, 7, "Total"},
+	}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("got edits %+v, want %+v", edits, want)
+	}
+
+	conflicts := []struct {
+		obj, newName string
+	}{
+		{"Counter", "local"}, // would be shadowed in get
+		{"Counter", "incr"},  // already declared in the package
+		{"local", "Counter"}, // would shadow Counter in get
+	}
+	for _, test := range conflicts {
+		_, err := RenameEdits(c, objs[test.obj], test.newName)
+		if err == nil || !strings.Contains(err.Error(), "conflicts with") {
+			t.Errorf("renaming %s to %s: got error %v, want a conflict", test.obj, test.newName, err)
+		}
+	}
+
+	if _, err := RenameEdits(c, objs["Counter"], "func"); err == nil {
+		t.Error("renaming Counter to func: got no error, want an invalid identifier error")
+	}
+}
+
+func TestRenameEdits_unkeyedFields(t *testing.T) {
+	pkg, err := parseTestPkg("complits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.TrackReferences = true
+	var bar types.Object
+	err = c.IterateSymbsPkg("complits", pkg, func(symb *Symb) bool {
+		if symb.IsDecl() && symb.Ident.Name == "Bar" {
+			bar = symb.ReferObj
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The unkeyed elements that initialize Bar have nothing to rename.
+	edits, err := RenameEdits(c, bar, "Qux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := filepath.Join("testdata", "src", "complits", "complits.go")
+	want := []Edit{
+		{f, 37, 3, "Qux"},
+		{f, 131, 3, "Qux"},
+		{f, 183, 3, "Qux"},
+		{f, 218, 3, "Qux"},
+		{f, 320, 3, "Qux"},
+	}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("got edits %+v, want %+v", edits, want)
+	}
+}
+
+func TestRenameEdits_members(t *testing.T) {
+	pkg, err := parseTestPkg("renames")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.TrackReferences = true
+	objs := make(map[string]types.Object)
+	err = c.IterateSymbsPkg("renames", pkg, func(symb *Symb) bool {
+		if symb.IsDecl() {
+			objs[symb.QualifiedName()] = symb.ReferObj
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		obj, newName string
+		conflict     bool
+	}{
+		{"renames.T.A", "B", true},      // another field
+		{"renames.T.M", "N", true},      // another method, with a pointer receiver
+		{"renames.T.A", "M", true},      // a method
+		{"renames.T.N", "B", true},      // a field
+		{"renames.T.A", "X", false},     // a package-level var, which selectors cannot see
+		{"renames.T.B", "local", false}, // a local var in a function referring to B
+		{"renames.X", "strings", true},  // an import
+		{"renames.X", "Y", false},
+	}
+	for _, test := range tests {
+		obj := objs[test.obj]
+		if obj == nil {
+			t.Fatalf("%s: no declaration found", test.obj)
+		}
+		edits, err := RenameEdits(c, obj, test.newName)
+		if test.conflict {
+			if err == nil || !strings.Contains(err.Error(), "conflicts with") {
+				t.Errorf("renaming %s to %s: got edits %+v and error %v, want a conflict", test.obj, test.newName, edits, err)
+			}
+		} else if err != nil {
+			t.Errorf("renaming %s to %s: %s", test.obj, test.newName, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package symb
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A ScoredSymb is a declaration found by Index.SearchFuzzy, with the score
+// of its match; higher scores are better matches.
+type ScoredSymb struct {
+	Symb  Symb
+	Score int
+}
+
+// SearchPrefix returns the indexed declarations whose names begin with
+// prefix, ignoring case: exported declarations first, then the others,
+// each sorted by name (see searchLess for ties). It returns at most limit
+// declarations, or all of them if limit is 0 or less. It panics if idx
+// was returned by LoadIndex, which has no symbs to search.
+func (idx *Index) SearchPrefix(prefix string, limit int) []Symb {
+	idx.needSymbs("SearchPrefix", "DeclRecords")
+	prefix = strings.ToLower(prefix)
+	var symbs []Symb
+	for i := range idx.symbs {
+		x := &idx.symbs[i]
+		if x.IsDecl() && strings.HasPrefix(strings.ToLower(x.name()), prefix) {
+			symbs = append(symbs, *x)
+		}
+	}
+	sort.Sort(symbsBySearchOrder(symbs))
+	if limit > 0 && len(symbs) > limit {
+		symbs = symbs[:limit]
+	}
+	return symbs
+}
+
+// SearchFuzzy returns the indexed declarations whose names contain the
+// characters of query in order, ignoring case, as in "nctx" for
+// NewContext, sorted by score (see fuzzyScore), with ties broken by the
+// length of the name and then as for SearchPrefix. It returns at most
+// limit declarations, or all of them if limit is 0 or less. It panics if
+// idx was returned by LoadIndex, which has no symbs to search.
+func (idx *Index) SearchFuzzy(query string, limit int) []ScoredSymb {
+	idx.needSymbs("SearchFuzzy", "DeclRecords")
+	q := []rune(strings.ToLower(query))
+	var scored []ScoredSymb
+	for i := range idx.symbs {
+		x := &idx.symbs[i]
+		if !x.IsDecl() {
+			continue
+		}
+		if score, ok := fuzzyScore(q, []rune(x.name())); ok {
+			scored = append(scored, ScoredSymb{*x, score})
+		}
+	}
+	sort.Sort(scoredSymbs(scored))
+	if limit > 0 && len(scored) > limit {
+		scored = scored[:limit]
+	}
+	return scored
+}
+
+// Scores of the characters of a fuzzy match.
+const (
+	matchScore       = 1 // any matched character
+	startBonus       = 8 // the first character of the name
+	boundaryBonus    = 6 // the start of a word: after an underscore or digit, or a camelCase hump
+	consecutiveBonus = 4 // the character after the previous match
+)
+
+// fuzzyScore returns the best score of the matches of q, which is lower
+// case, as a subsequence of name, ignoring case, and whether there is one.
+// Each matched character scores matchScore, plus startBonus or
+// boundaryBonus if it starts the name or a word in it, plus
+// consecutiveBonus if it immediately follows the previous match.
+func fuzzyScore(q, name []rune) (int, bool) {
+	if len(q) == 0 {
+		return 0, true
+	}
+	// best[j] is the best score of the matches of q[:i+1] with q[i] at
+	// name[j], or -1 if there is none.
+	best := make([]int, len(name))
+	next := make([]int, len(name))
+	for i := range q {
+		for j := range name {
+			next[j] = -1
+			if unicode.ToLower(name[j]) != q[i] {
+				continue
+			}
+			prev := 0
+			if i > 0 {
+				prev = -1
+				for k := i - 1; k < j; k++ {
+					s := best[k]
+					if s >= 0 && k == j-1 {
+						s += consecutiveBonus
+					}
+					if s > prev {
+						prev = s
+					}
+				}
+				if prev < 0 {
+					continue
+				}
+			}
+			next[j] = prev + charScore(name, j)
+		}
+		best, next = next, best
+	}
+	score := -1
+	for _, s := range best {
+		if s > score {
+			score = s
+		}
+	}
+	return score, score >= 0
+}
+
+// charScore returns the score of matching the character name[j].
+func charScore(name []rune, j int) int {
+	switch {
+	case j == 0:
+		return matchScore + startBonus
+	case name[j-1] == '_', unicode.IsDigit(name[j-1]) && !unicode.IsDigit(name[j]):
+		return matchScore + boundaryBonus
+	case unicode.IsUpper(name[j]) && unicode.IsLower(name[j-1]):
+		// The first capital of a hump, as C in NewContext.
+		return matchScore + boundaryBonus
+	case unicode.IsUpper(name[j]) && j+1 < len(name) && unicode.IsLower(name[j+1]):
+		// The last capital of an acronym that starts a word, as F in
+		// HTTPFile.
+		return matchScore + boundaryBonus
+	}
+	return matchScore
+}
+
+// searchLess orders declarations for SearchPrefix: exported ones first,
+// then by name, qualified name, filename, and offset, so that the order
+// does not depend on the order in which they were indexed.
+func searchLess(x, y *Symb) bool {
+	if xe, ye := x.Visibility() == Exported, y.Visibility() == Exported; xe != ye {
+		return xe
+	}
+	if xn, yn := x.name(), y.name(); xn != yn {
+		return xn < yn
+	}
+	if xq, yq := x.QualifiedName(), y.QualifiedName(); xq != yq {
+		return xq < yq
+	}
+	p, q := x.position(x.pos()), y.position(y.pos())
+	if p.Filename != q.Filename {
+		return p.Filename < q.Filename
+	}
+	return p.Offset < q.Offset
+}
+
+type symbsBySearchOrder []Symb
+
+func (s symbsBySearchOrder) Len() int           { return len(s) }
+func (s symbsBySearchOrder) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s symbsBySearchOrder) Less(i, j int) bool { return searchLess(&s[i], &s[j]) }
+
+type scoredSymbs []ScoredSymb
+
+func (s scoredSymbs) Len() int      { return len(s) }
+func (s scoredSymbs) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s scoredSymbs) Less(i, j int) bool {
+	if s[i].Score != s[j].Score {
+		return s[i].Score > s[j].Score
+	}
+	if li, lj := len(s[i].Symb.name()), len(s[j].Symb.name()); li != lj {
+		return li < lj
+	}
+	return searchLess(&s[i].Symb, &s[j].Symb)
+}
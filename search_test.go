@@ -0,0 +1,73 @@
+package symb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func buildSearchIndex(t *testing.T) *Index {
+	pkg, err := parseTestPkg("search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	idx := NewIndex()
+	idx.IncludeRefs = true
+	if err := c.IterateSymbsPkg("search", pkg, idx.Add); err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+func TestIndex_SearchPrefix(t *testing.T) {
+	idx := buildSearchIndex(t)
+
+	tests := []struct {
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{"new", 0, []string{"NewConfig", "NewContext", "newContextCache"}},
+		{"new", 1, []string{"NewConfig"}},
+		{"NEXT", 0, []string{"Next", "nextToken"}},
+		{"co", 0, []string{"Config", "Context", "CountTokens"}},
+		{"z", 0, nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, x := range idx.SearchPrefix(test.prefix, test.limit) {
+			got = append(got, x.Ident.Name)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SearchPrefix(%q, %d): got %v, want %v", test.prefix, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestIndex_SearchFuzzy(t *testing.T) {
+	idx := buildSearchIndex(t)
+
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		{"nctx", 0, []string{"NewContext", "newContextCache"}},
+		{"ct", 0, []string{"CountTokens", "ctx", "Context", "NewContext", "newContextCache"}},
+		{"ct", 2, []string{"CountTokens", "ctx"}},
+		{"ncfg", 0, []string{"NewConfig"}},
+		{"xyz", 0, nil},
+	}
+	for _, test := range tests {
+		var got []string
+		var scores []int
+		for _, x := range idx.SearchFuzzy(test.query, test.limit) {
+			got = append(got, x.Symb.Ident.Name)
+			scores = append(scores, x.Score)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SearchFuzzy(%q, %d): got %v (scores %v), want %v", test.query, test.limit, got, scores, test.want)
+		}
+	}
+}
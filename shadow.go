@@ -0,0 +1,217 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// ShadowKind classifies ShadowReports.
+type ShadowKind int
+
+const (
+	ShadowsVar      ShadowKind = iota // a local shadows a variable in an enclosing function-local scope
+	ShadowsUniverse                   // a declaration shadows a universe object, such as len or error
+)
+
+// A ShadowReport describes a declaration that shadows another object of
+// the same name.
+type ShadowReport struct {
+	Kind        ShadowKind
+	Name        string
+	Pos         token.Pos // position of the shadowing declaration
+	ShadowedPos token.Pos // position of the shadowed declaration (NoPos for universe objects)
+}
+
+// Shadows reports the declarations in the package most recently iterated
+// over by ctxt that shadow other objects: function-local declarations
+// (including range and type switch variables) of a name that is declared
+// as a variable in an enclosing function-local scope, and declarations of
+// any kind of a name that is declared in the universe scope. Declarations
+// of the same name in sibling scopes, and := statements that reuse a
+// variable declared in the same scope, are not reported. The reports are
+// sorted by position.
+func Shadows(ctxt *Context) []ShadowReport {
+	var reports []ShadowReport
+	for _, file := range ctxt.currentFiles {
+		w := &shadowWalker{ctxt: ctxt, reports: &reports}
+		for _, decl := range file.Decls {
+			w.topLevel(decl)
+			ast.Walk(w, decl)
+		}
+	}
+	sort.Sort(shadowReportsByPos(reports))
+	return reports
+}
+
+// shadowScope is a function-local scope: a block, or the implicit block of
+// a statement such as if or for.
+type shadowScope struct {
+	outer *shadowScope
+	names map[string]token.Pos // declaration positions of variables (NoPos for other objects)
+}
+
+// shadowWalker is an ast.Visitor that tracks the function-local scopes
+// enclosing the nodes it visits.
+type shadowWalker struct {
+	ctxt    *Context
+	scope   *shadowScope   // innermost scope, or nil outside functions
+	body    *ast.BlockStmt // function body whose block is scope, if any
+	reports *[]ShadowReport
+}
+
+// topLevel reports package-level declarations in decl that shadow universe
+// objects.
+func (w *shadowWalker) topLevel(decl ast.Decl) {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Recv == nil {
+			w.checkUniverse(decl.Name)
+		}
+	case *ast.GenDecl:
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.ValueSpec:
+				for _, name := range spec.Names {
+					w.checkUniverse(name)
+				}
+			case *ast.TypeSpec:
+				w.checkUniverse(spec.Name)
+			}
+		}
+	}
+}
+
+func (w *shadowWalker) checkUniverse(id *ast.Ident) {
+	if id.Name != "_" && types.Universe.Lookup(id.Name) != nil {
+		*w.reports = append(*w.reports, ShadowReport{Kind: ShadowsUniverse, Name: id.Name, Pos: id.Pos()})
+	}
+}
+
+// enter returns a walker for the nodes inside a new scope.
+func (w *shadowWalker) enter(body *ast.BlockStmt) *shadowWalker {
+	return &shadowWalker{
+		ctxt:    w.ctxt,
+		scope:   &shadowScope{outer: w.scope, names: make(map[string]token.Pos)},
+		body:    body,
+		reports: w.reports,
+	}
+}
+
+// declare records the declaration by id of a local variable (or, if isVar
+// is false, of a local constant or type) in the current scope, and reports
+// it if it shadows a variable in an enclosing scope or a universe object.
+func (w *shadowWalker) declare(id *ast.Ident, isVar bool) {
+	if id == nil || id.Name == "_" || w.scope == nil {
+		return
+	}
+	if _, present := w.scope.names[id.Name]; present {
+		// Reuse or redeclaration in the same scope.
+		return
+	}
+	shadowed := false
+	for s := w.scope.outer; s != nil && !shadowed; s = s.outer {
+		if pos, present := s.names[id.Name]; present {
+			if pos.IsValid() {
+				*w.reports = append(*w.reports, ShadowReport{Kind: ShadowsVar, Name: id.Name, Pos: id.Pos(), ShadowedPos: pos})
+			}
+			shadowed = true
+		}
+	}
+	if !shadowed {
+		w.checkUniverse(id)
+	}
+	if isVar {
+		w.scope.names[id.Name] = id.Pos()
+	} else {
+		w.scope.names[id.Name] = token.NoPos
+	}
+}
+
+// isNewVar reports whether id, on the left side of a := statement,
+// declares a new variable rather than reusing an existing one. (The
+// variable in a type switch guard is recorded as neither.)
+func (w *shadowWalker) isNewVar(id *ast.Ident) bool {
+	_, reused := w.ctxt.info.Uses[id]
+	return !reused
+}
+
+func (w *shadowWalker) Visit(n ast.Node) ast.Visitor {
+	switch n := n.(type) {
+	case *ast.FuncDecl:
+		inner := w.enter(n.Body)
+		inner.declareFields(n.Recv)
+		inner.declareFields(n.Type.Params)
+		inner.declareFields(n.Type.Results)
+		if n.Body != nil {
+			ast.Walk(inner, n.Body)
+		}
+		return nil
+
+	case *ast.FuncLit:
+		inner := w.enter(n.Body)
+		inner.declareFields(n.Type.Params)
+		inner.declareFields(n.Type.Results)
+		ast.Walk(inner, n.Body)
+		return nil
+
+	case *ast.BlockStmt:
+		if n == w.body {
+			// The function body shares the scope of the parameters.
+			w.body = nil
+			return w
+		}
+		return w.enter(nil)
+
+	case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt, *ast.CaseClause, *ast.CommClause:
+		inner := w.enter(nil)
+		if n, ok := n.(*ast.RangeStmt); ok && n.Tok == token.DEFINE {
+			if key, ok := n.Key.(*ast.Ident); ok {
+				inner.declare(key, true)
+			}
+			if value, ok := n.Value.(*ast.Ident); ok {
+				inner.declare(value, true)
+			}
+		}
+		return inner
+
+	case *ast.AssignStmt:
+		if n.Tok == token.DEFINE {
+			for _, lhs := range n.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok && w.isNewVar(id) {
+					w.declare(id, true)
+				}
+			}
+		}
+
+	case *ast.ValueSpec:
+		_, isConst := w.ctxt.info.Defs[n.Names[0]].(*types.Const)
+		for _, name := range n.Names {
+			w.declare(name, !isConst)
+		}
+
+	case *ast.TypeSpec:
+		w.declare(n.Name, false)
+	}
+	return w
+}
+
+// declareFields declares the names in a parameter, result, or receiver
+// list.
+func (w *shadowWalker) declareFields(fields *ast.FieldList) {
+	if fields == nil {
+		return
+	}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			w.declare(name, true)
+		}
+	}
+}
+
+type shadowReportsByPos []ShadowReport
+
+func (r shadowReportsByPos) Len() int           { return len(r) }
+func (r shadowReportsByPos) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r shadowReportsByPos) Less(i, j int) bool { return r[i].Pos < r[j].Pos }
@@ -0,0 +1,39 @@
+package symb
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestShadows(t *testing.T) {
+	pkg, err := parseTestPkg("shadow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	if err := c.IterateSymbsPkg("shadow", pkg, func(*Symb) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, r := range Shadows(c) {
+		s := fmt.Sprintf("%d %s %s", r.Kind, r.Name, shortPosition(r.Pos))
+		if r.ShadowedPos.IsValid() {
+			s += " shadows " + shortPosition(r.ShadowedPos)
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"0 err shadow.go:10 shadows shadow.go:8",
+		"0 n shadow.go:16 shadows shadow.go:8",
+		"1 len shadow.go:41",
+		"0 s shadow.go:46 shadows shadow.go:45",
+		"0 v shadow.go:52 shadows shadow.go:51",
+		"1 new shadow.go:61",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got shadows:\n%v\nwant:\n%v", got, want)
+	}
+}
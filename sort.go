@@ -0,0 +1,36 @@
+package symb
+
+import (
+	"go/token"
+	"sort"
+)
+
+// Sort sorts symbs, which may have been accumulated from several
+// iterations, by the name of the file containing each symb's Ident and
+// then by offset within the file, as resolved through fset. Symbs at the
+// same position are ordered with declarations first and then by Variant.
+func Sort(symbs []Symb, fset *token.FileSet) {
+	sort.Stable(symbsByFilePos{symbs, fset})
+}
+
+type symbsByFilePos struct {
+	symbs []Symb
+	fset  *token.FileSet
+}
+
+func (s symbsByFilePos) Len() int      { return len(s.symbs) }
+func (s symbsByFilePos) Swap(i, j int) { s.symbs[i], s.symbs[j] = s.symbs[j], s.symbs[i] }
+func (s symbsByFilePos) Less(i, j int) bool {
+	x, y := &s.symbs[i], &s.symbs[j]
+	p, q := s.fset.PositionFor(x.pos(), false), s.fset.PositionFor(y.pos(), false)
+	if p.Filename != q.Filename {
+		return p.Filename < q.Filename
+	}
+	if p.Offset != q.Offset {
+		return p.Offset < q.Offset
+	}
+	if xd, yd := x.IsDecl(), y.IsDecl(); xd != yd {
+		return xd
+	}
+	return x.Variant < y.Variant
+}
@@ -0,0 +1,39 @@
+package symb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIterateSymbs_order(t *testing.T) {
+	for _, pkgPath := range testPkgPaths {
+		pkg, err := parseTestPkg(pkgPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		symbs := collectSymbs(pkgPath, pkg)
+		for i := 1; i < len(symbs); i++ {
+			p, q := fset.PositionFor(symbs[i-1].Ident.Pos(), false), fset.PositionFor(symbs[i].Ident.Pos(), false)
+			if p.Filename > q.Filename || p.Filename == q.Filename && p.Offset >= q.Offset {
+				t.Errorf("%s: symb %s at %s emitted after %s at %s", pkgPath, symbs[i].Ident.Name, q, symbs[i-1].Ident.Name, p)
+			}
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := collectSymbs("foo", pkg)
+
+	symbs := make([]Symb, len(want))
+	for i, x := range want {
+		symbs[len(symbs)-1-i] = x
+	}
+	Sort(symbs, fset)
+	if !reflect.DeepEqual(symbs, want) {
+		t.Errorf("got sorted symbs %s, want %s", prettys(symbs), prettys(want))
+	}
+}
@@ -0,0 +1,232 @@
+package symb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// source holds the contents of a file as they were when it was parsed,
+// along with the file's size and modification time at that point, which
+// are used to notice when the file changes on disk.
+type source struct {
+	src      []byte
+	size     int64
+	modTime  time.Time
+	stale    bool // whether a StaleFile warning has been raised; guarded by Context.sourcesMu
+	inMemory bool // whether src came from memory (see Context.Overlay) rather than disk
+}
+
+// ParseFile parses the named file into the Context's FileSet, retaining
+// its contents so that later accesses to the file's source (such as
+// SourceOf) see exactly the bytes that were parsed, even if the file
+// changes on disk in the meantime. If ctxt.Overlay holds contents for
+// filename, they are parsed instead of the file on disk.
+//
+// If the file has syntax errors, ParseFile returns them (as a
+// scanner.ErrorList) along with the partial file that the parser built,
+// like go/parser does; the partial file may be iterated over, with the
+// unparsable parts skipped.
+func (ctxt *Context) ParseFile(filename string) (*ast.File, error) {
+	if src, present := ctxt.Overlay[filename]; present {
+		return ctxt.parseSource(filename, src)
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	file, err := parser.ParseFile(ctxt.FileSet, filename, src, parser.ParseComments|parser.AllErrors|parser.DeclarationErrors)
+	if file == nil || file.Name.Name == "" {
+		// Not even the package clause could be parsed.
+		return nil, err
+	}
+	ctxt.sourcesMu.Lock()
+	ctxt.sources[filename] = &source{src: src, size: fi.Size(), modTime: fi.ModTime()}
+	ctxt.sourcesMu.Unlock()
+	return file, err
+}
+
+// parseSource parses src, the in-memory contents of the named file, into
+// the Context's FileSet, and retains src as the file's contents, like
+// ParseFile. Files parsed from memory are never reported as stale.
+func (ctxt *Context) parseSource(filename string, src []byte) (*ast.File, error) {
+	file, err := parser.ParseFile(ctxt.FileSet, filename, src, parser.ParseComments|parser.AllErrors|parser.DeclarationErrors)
+	if file == nil || file.Name.Name == "" {
+		// Not even the package clause could be parsed.
+		return nil, err
+	}
+	ctxt.sourcesMu.Lock()
+	ctxt.sources[filename] = &source{src: src, size: int64(len(src)), inMemory: true}
+	ctxt.sourcesMu.Unlock()
+	return file, err
+}
+
+// SourceOf returns the source text of node, which must be in a file in the
+// Context's FileSet. The text is taken from the contents retained when the
+// file was parsed by ParseFile or one of the directory-based iteration
+// methods. Files parsed by the caller are read from disk on first access,
+// and an error is returned if they no longer match what was parsed.
+//
+// If a file has changed on disk since it was parsed, SourceOf still returns
+// the parsed text but raises a StaleFile warning (once per file).
+//
+// SourceOf, like SourceText, SourceLine, and WriteEtags, is not safe for
+// concurrent use: the warning it raises updates the Context's log counts
+// and Timeline, which are not locked. It may be called from visitf, which
+// runs on the goroutine that called the iteration method, even while other
+// packages are parsed concurrently (see Parallelism).
+func (ctxt *Context) SourceOf(node ast.Node) (string, error) {
+	src, err := ctxt.source(node.Pos())
+	if err != nil {
+		return "", err
+	}
+	f := ctxt.FileSet.File(node.Pos())
+	start, end := f.Offset(node.Pos()), f.Offset(node.End())
+	return string(src[start:end]), nil
+}
+
+// SourceText returns the source text of the declaration x, as for display
+// in search results: the signature of a function or method (its
+// declaration without the body) or of a function literal; the spec of a
+// name declared in a parenthesized const, var, or type declaration, or
+// the whole declaration if it is not parenthesized; and the field, with
+// its names, type, and tag, of a struct field, interface method, or
+// parameter. Declarations that span several lines are returned whole. For
+// other declarations, such as those of := statements, range and type
+// switch variables, labels, and packages, it returns the line containing
+// the identifier, without surrounding white space. Doc comments are not
+// included.
+//
+// The text comes from the file containing the declaration, through
+// SourceOf's retained contents; no other file is read. SourceText returns
+// an error if x is not a declaration or has no syntax (see Synthetic).
+func (ctxt *Context) SourceText(x *Symb) (string, error) {
+	if x.Synthetic || x.Ident == nil {
+		return "", errors.New("symb has no syntax")
+	}
+	if !x.IsDecl() {
+		return "", fmt.Errorf("%s is not a declaration", x.Ident.Name)
+	}
+	src, err := ctxt.source(x.Ident.Pos())
+	if err != nil {
+		return "", err
+	}
+	f := ctxt.FileSet.File(x.Ident.Pos())
+
+	var start, end token.Pos
+	switch n := x.DeclNode.(type) {
+	case *ast.FuncDecl:
+		start, end = n.Pos(), n.Type.End()
+	case *ast.FuncLit:
+		start, end = n.Type.Pos(), n.Type.End()
+	case *ast.Field:
+		start, end = n.Pos(), n.End()
+	case *ast.GenDecl:
+		start, end = n.Pos(), n.End()
+		if n.Lparen.IsValid() {
+			for _, spec := range n.Specs {
+				if spec.Pos() <= x.Ident.Pos() && x.Ident.Pos() < spec.End() {
+					start, end = spec.Pos(), spec.End()
+				}
+			}
+		}
+	}
+	if start.IsValid() {
+		return string(src[f.Offset(start):f.Offset(end)]), nil
+	}
+
+	// Return the line containing the identifier.
+	return string(bytes.TrimSpace(lineAt(src, f.Offset(x.Ident.Pos())))), nil
+}
+
+// SourceLine returns the line of source containing pos, without its
+// newline, as for display alongside a position. The text comes from the
+// file as parsed (the file itself, not one that a //line directive names),
+// through SourceOf's retained contents.
+func (ctxt *Context) SourceLine(pos token.Pos) (string, error) {
+	src, err := ctxt.source(pos)
+	if err != nil {
+		return "", err
+	}
+	return string(lineAt(src, ctxt.FileSet.File(pos).Offset(pos))), nil
+}
+
+// lineAt returns the line of src containing the byte at offset, without
+// its newline.
+func lineAt(src []byte, offset int) []byte {
+	start, end := offset, offset
+	for start > 0 && src[start-1] != '\n' {
+		start--
+	}
+	for end < len(src) && src[end] != '\n' {
+		end++
+	}
+	return src[start:end]
+}
+
+// source returns the contents of the file containing pos, as it was when
+// the file was parsed. All access to the contents of analyzed files should
+// go through source so that results stay consistent with the parsed ASTs.
+// source is called only from the goroutine that is iterating (see
+// SourceOf); sourcesMu guards against the goroutines that parse files.
+//
+// The first time source reads a file that the Context did not parse itself
+// (with ParseFile), it has only the file's size in the FileSet to compare
+// with, so a change on disk that keeps the size goes unnoticed and the new
+// contents are used.
+func (ctxt *Context) source(pos token.Pos) ([]byte, error) {
+	if !pos.IsValid() {
+		return nil, errors.New("no position")
+	}
+	f := ctxt.FileSet.File(pos)
+	if f == nil {
+		return nil, fmt.Errorf("position %d is not in the FileSet", pos)
+	}
+	filename := f.Name()
+
+	ctxt.sourcesMu.Lock()
+	s, present := ctxt.sources[filename]
+	stale := present && s.stale
+	ctxt.sourcesMu.Unlock()
+	if !present {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return nil, err
+		}
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if len(src) != f.Size() {
+			ctxt.warnf(StaleFile, LevelWarn, token.Pos(f.Base()), "%s changed on disk since it was parsed", filename)
+			return nil, fmt.Errorf("%s changed on disk since it was parsed", filename)
+		}
+		s = &source{src: src, size: fi.Size(), modTime: fi.ModTime()}
+		ctxt.sourcesMu.Lock()
+		ctxt.sources[filename] = s
+		ctxt.sourcesMu.Unlock()
+		return s.src, nil
+	}
+
+	if !stale && !s.inMemory {
+		if fi, err := os.Stat(filename); err != nil || fi.Size() != s.size || !fi.ModTime().Equal(s.modTime) {
+			if cur, err := ioutil.ReadFile(filename); err != nil || !bytes.Equal(cur, s.src) {
+				ctxt.sourcesMu.Lock()
+				s.stale = true
+				ctxt.sourcesMu.Unlock()
+				ctxt.warnf(StaleFile, LevelWarn, token.Pos(f.Base()), "%s changed on disk since it was parsed", filename)
+			}
+		}
+	}
+	return s.src, nil
+}
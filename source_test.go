@@ -0,0 +1,147 @@
+package symb
+
+import (
+	"go/ast"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSourceOf_staleFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "symb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "stale.go")
+	if err := ioutil.WriteFile(filename, []byte("package stale\n\nvar Original = 1\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewContext()
+	c.Debug = true
+	file, err := c.ParseFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decl *Symb
+	c.IterateSymbs("stale", []*ast.File{file}, func(symb *Symb) bool {
+		if symb.Ident.Name == "Original" {
+			decl = symb
+		}
+		return true
+	})
+	if decl == nil {
+		t.Fatal("no symb for Original")
+	}
+
+	// Rewrite the file between parsing and reading its source.
+	if err := ioutil.WriteFile(filename, []byte("package stale\n\n// Rewritten.\nvar Rewritten = 2\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := c.SourceOf(decl.Ident)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src != "Original" {
+		t.Errorf("got source %q, want %q", src, "Original")
+	}
+	if line, err := c.SourceLine(decl.Ident.Pos()); err != nil || line != "var Original = 1" {
+		t.Errorf("got source line %q (error %v), want %q", line, err, "var Original = 1")
+	}
+
+	var stale int
+	for _, e := range c.Timeline() {
+		if e.Warning != nil && e.Warning.Kind == StaleFile {
+			stale++
+		}
+	}
+	if stale != 1 {
+		t.Errorf("got %d StaleFile warnings, want 1", stale)
+	}
+
+	// The warning is only raised once per file.
+	c.SourceOf(decl.Ident)
+	var n int
+	for _, e := range c.Timeline() {
+		if e.Warning != nil && e.Warning.Kind == StaleFile {
+			n++
+		}
+	}
+	if n != stale {
+		t.Errorf("got %d StaleFile warnings after second access, want %d", n, stale)
+	}
+}
+
+func TestSourceLine_inMemory(t *testing.T) {
+	c := NewContext()
+	sources := map[string][]byte{
+		"/mem/a.go": []byte("package mem\n\nfunc F() int {\n\treturn 1\n}\n"),
+	}
+	var decl *Symb
+	if err := c.ParseAndIterate("mem", sources, func(x *Symb) bool {
+		if x.Ident.Name == "F" {
+			decl = x
+		}
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if decl == nil {
+		t.Fatal("no symb for F")
+	}
+	if line, err := c.SourceLine(decl.Ident.Pos()); err != nil || line != "func F() int {" {
+		t.Errorf("got source line %q (error %v), want %q", line, err, "func F() int {")
+	}
+}
+
+func TestSourceText(t *testing.T) {
+	pkg, err := parseTestPkg("sourcetext")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	decls := make(map[string]*Symb)
+	err = c.IterateDecls("sourcetext", pkgFiles(pkg), func(symb *Symb) bool {
+		decls[symb.QualifiedName()] = symb
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"sourcetext.Config.Log", "func (c *Config) Log(level Level,\n\tmsg string) bool"}, // method, without its body
+		{"sourcetext.Info", "Info"},                                              // grouped const
+		{"sourcetext.Warn", "Warn"},                                              // grouped const, without its comment
+		{"sourcetext.Debug", "Debug Level = iota"},                               // grouped const with a value
+		{"sourcetext.Default", "var Default = Config{\n\tName: \"default\",\n}"}, // ungrouped var
+		{"sourcetext.Level", "type Level int"},                                   // ungrouped type
+		{"sourcetext.Config.Level", "Level Level"},                               // struct field, without its comment
+		{"sourcetext.Config.Name", "Name  string `json:\"name\"`"},               // struct field, without its doc
+		{"sourcetext.Config.Log.level", "level Level"},                           // parameter
+		{"sourcetext.Config.Log.enabled", "enabled := level >= c.Level"},         // local, by its line
+		{"sourcetext", "package sourcetext"},                                     // package, by its line
+	}
+	for _, test := range tests {
+		decl := decls[test.name]
+		if decl == nil {
+			t.Errorf("%s: no declaration found", test.name)
+			continue
+		}
+		got, err := c.SourceText(decl)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got source text %q, want %q", test.name, got, test.want)
+		}
+	}
+}
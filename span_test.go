@@ -0,0 +1,67 @@
+package symb
+
+import (
+	"encoding/json"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSymb_Span(t *testing.T) {
+	pkg, err := parseTestPkg("span")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join("testdata", "src", "span", "span.go")
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sel *Symb
+	c := NewContext()
+	c.FileSet = fset
+	err = c.IterateSymbsPkg("span", pkg, func(symb *Symb) bool {
+		if symb.Ident.Name == "Größe" && !symb.IsDecl() {
+			sel = symb
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sel == nil {
+		t.Fatal("no reference to Größe found")
+	}
+
+	off := strings.Index(string(src), "m.Größe")
+	start, end := sel.Span()
+	if got, want := fset.Position(start).Offset, off+len("m."); got != want {
+		t.Errorf("got Span start offset %d, want %d", got, want)
+	}
+	if got, want := fset.Position(end).Offset, off+len("m.Größe"); got != want {
+		t.Errorf("got Span end offset %d, want %d", got, want)
+	}
+	if got := string(src[fset.Position(start).Offset:fset.Position(end).Offset]); got != "Größe" {
+		t.Errorf("got Span text %q, want %q", got, "Größe")
+	}
+
+	start, end = sel.ExprSpan()
+	if got := string(src[fset.Position(start).Offset:fset.Position(end).Offset]); got != "m.Größe" {
+		t.Errorf("got ExprSpan text %q, want %q", got, "m.Größe")
+	}
+
+	data, err := json.Marshal(sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pos struct{ IdentPos, IdentEnd token.Position }
+	if err := json.Unmarshal(data, &pos); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := pos.IdentEnd.Offset-pos.IdentPos.Offset, len("Größe"); got != want {
+		t.Errorf("got JSON span of %d bytes, want %d", got, want)
+	}
+}
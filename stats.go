@@ -0,0 +1,103 @@
+package symb
+
+import "go/ast"
+
+// A Summary holds statistics about a collection of symbs. It marshals to
+// JSON as an object with the same field names (maps become objects, with
+// their keys sorted). A Summary is built by Stats, or by passing the Add
+// method of a new Summary as the visitf of one or more iterations.
+type Summary struct {
+	Symbs int // the number of symbs
+	Decls int // declarations
+	Refs  int // references
+
+	// Symbs that refer to function-local objects, to objects of packages
+	// (including fields and methods), and to objects of the universe
+	// scope. Symbs that refer to no object are in none of these.
+	Local        int
+	PackageLevel int
+	Universe     int
+
+	// Symbs among the PackageLevel ones whose names are exported, and
+	// those whose names are not.
+	Exported   int
+	Unexported int
+
+	// Kinds counts the symbs by the kind of object they refer to, named
+	// as in the Isa field of their JSON encoding (Const, TypeName, Var,
+	// Func, Package, Builtin, or Nil).
+	Kinds map[string]int
+
+	// UniverseNames counts the symbs that refer to universe objects by
+	// name, and Builtins those among them that are builtin functions.
+	UniverseNames map[string]int
+	Builtins      map[string]int
+
+	// Files counts the symbs by the filename of their position (see
+	// Symb.Position). Synthetic symbs are not counted.
+	Files map[string]int
+}
+
+// NewSummary returns an empty Summary.
+func NewSummary() *Summary {
+	return &Summary{
+		Kinds:         make(map[string]int),
+		UniverseNames: make(map[string]int),
+		Builtins:      make(map[string]int),
+		Files:         make(map[string]int),
+	}
+}
+
+// Stats returns a Summary of symbs.
+func Stats(symbs []Symb) Summary {
+	s := NewSummary()
+	for i := range symbs {
+		s.Add(&symbs[i])
+	}
+	return *s
+}
+
+// Add counts x in the summary. Add always returns true, so it can be used
+// as the visitf of an iteration.
+func (s *Summary) Add(x *Symb) bool {
+	s.Symbs++
+	if x.IsDecl() {
+		s.Decls++
+	} else {
+		s.Refs++
+	}
+	if !x.Synthetic {
+		s.Files[x.Position(nil).Filename]++
+	}
+	if x.ReferObj == nil {
+		return true
+	}
+	s.Kinds[objectIsa(x.ReferObj)]++
+	switch {
+	case x.Local:
+		s.Local++
+	case x.Universe:
+		s.Universe++
+		s.UniverseNames[x.ReferObj.Name()]++
+		if x.Builtin {
+			s.Builtins[x.ReferObj.Name()]++
+		}
+	default:
+		s.PackageLevel++
+		if ast.IsExported(x.ReferObj.Name()) {
+			s.Exported++
+		} else {
+			s.Unexported++
+		}
+	}
+	return true
+}
+
+// Counting returns a visitf that adds each symb to the summary and then
+// calls visitf with it.
+func (s *Summary) Counting(visitf func(symb *Symb) bool) func(symb *Symb) bool {
+	return func(symb *Symb) bool {
+		s.Add(symb)
+		return visitf(symb)
+	}
+}
@@ -0,0 +1,50 @@
+package symb
+
+import (
+	"encoding/json"
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := Stats(collectSymbs("foo", pkg))
+	got, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	expectedFilename := filepath.Join("testdata", "src", "foo", "stats_expected.json")
+	want, err := ioutil.ReadFile(expectedFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		ioutil.WriteFile(filepath.Join("testdata", "src", "foo", "stats_actual.json"), got, 0666)
+		t.Errorf("got summary:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Counting as a visitf gives the same summary.
+	c := NewContext()
+	c.FileSet = fset
+	c.BaseDir = build.Default.GOPATH
+	counted := NewSummary()
+	var n int
+	err = c.IterateSymbsPkg("foo", pkg, counted.Counting(func(*Symb) bool {
+		n++
+		return true
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != summary.Symbs || !reflect.DeepEqual(*counted, summary) {
+		t.Errorf("got summary %+v from Counting (with %d symbs visited), want %+v", *counted, n, summary)
+	}
+}
@@ -11,21 +11,198 @@ import (
 	"code.google.com/p/go.tools/go/types"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/printer"
 	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Symb holds information about a symbol.
 type Symb struct {
-	Expr     ast.Expr   // expression for symb (*ast.Ident or *ast.SelectorExpr)
-	Ident    *ast.Ident // identifier in parse tree
-	ExprType types.Type // type of expression.
-	Pkg      *types.Package
-	File     *ast.File
-	ReferPos token.Pos    // position of referred-to thing.
-	ReferObj types.Object // object referred to.
-	Local    bool         // whether referred-to object is function-local.
-	Universe bool         // whether referred-to object is in universe.
+	Expr      ast.Expr   // expression for symb (*ast.Ident or *ast.SelectorExpr); nil if Synthetic
+	Ident     *ast.Ident // identifier in parse tree; nil if Synthetic
+	ExprType  types.Type // type of expression.
+	Pkg       *types.Package
+	File      *ast.File
+	ReferPos  token.Pos        // position of referred-to thing.
+	ReferFile string           // name of the file declaring referred-to thing, if its position is known.
+	ReferObj  types.Object     // object referred to.
+	Local     bool             // whether referred-to object is function-local.
+	Universe  bool             // whether referred-to object is in universe.
+	Builtin   bool             // whether referred-to object is a builtin function.
+	Container types.Object     // type or function that referred-to object is a member of, if any.
+	ConstVal  exact.Value      // value of the referred-to object, if it is a constant.
+	Tag       string           // for the declaration of a struct field, its tag (unquoted), if any.
+	Captured  bool             // whether the symb is in a function literal and refers to a variable of an enclosing function.
+	Anonymous bool             // whether the symb declares a function literal, under a synthesized name.
+	Selection *types.Selection // for a field or method selector, how the selection was made.
+	Form      SelectionForm    // for a method selector that is not called, whether it is a method value or expression.
+	Recv      types.Type       // for a field or method selector, the type (without pointers) that declares the member, after following embedded fields; for a method declaration, its receiver's base type.
+	Indirect  bool             // for a field or method selector, whether the selection dereferenced a pointer.
+	IsRecv    bool             // whether referred-to object is the receiver of a method (whose Container is then the method).
+	Embedded  bool             // whether the symb names a type embedded in a struct or interface type.
+	DeclForm  DeclForm         // for a name that a statement or declaration binds, how it is bound.
+	DeclNode  ast.Node         // for a declaration, the node that declares it: a FuncDecl, GenDecl, Field, AssignStmt (for :=), RangeStmt, TypeSwitchStmt, LabeledStmt, FuncLit, or File (for the package); nil for references.
+	Implicit  bool             // whether the symb refers to the elided type of a composite literal, under an Ident synthesized at its opening brace, or to the field that an element of an unkeyed struct literal initializes, under an Ident synthesized at the element.
+	InComment bool             // whether the symb is a mention of the object in a doc comment, under an Ident synthesized at the mention (see CommentRefs).
+	Variant   Variant          // which variant of the package the symb was found in.
+	Synthetic bool             // whether the symb was made from an imported package's scope, without syntax (see EmitImportedDecls).
+	Seq       int              // sequence number in the Context's timeline (only if Debug is set).
+
+	// For the declaration of a constant, the position of the const
+	// declaration (usually a parenthesized block) that it is part of, the
+	// index of its spec in that declaration (the value of iota in the
+	// spec), and whether its value is derived from iota, either explicitly
+	// or by implicit repetition of an earlier spec's expression.
+	ConstGroup token.Pos
+	ConstIndex int
+	IotaBased  bool
+
+	// For the declaration of a name in a const, var, or type declaration,
+	// the position of the declaration, which the names declared in the
+	// same parenthesized block share, the index of the name's spec in the
+	// declaration, and the index of the name in its spec (always 0 for a
+	// type). For a constant, DeclGroup is ConstGroup and SpecIndex is
+	// ConstIndex.
+	DeclGroup token.Pos
+	SpecIndex int
+	NameIndex int
+
+	// Whether the symb is the type operand of a conversion, as Celsius is
+	// in Celsius(f) (but byte is not in []byte(s)), in which case ExprType
+	// is the result type of the conversion; and otherwise, whether the symb
+	// is the function of a call (a plain call, or the call of a go or defer
+	// statement). Symbs in the arguments of a call are not affected.
+	IsConversion bool
+	CallContext  CallContext
+
+	// For a symb that refers to an object of another package that was
+	// found in a GOPATH directory, that directory (relative to the
+	// Context's BaseDir, if it is set). It is inside a vendor directory if
+	// the import was resolved to a vendored copy of the package.
+	ReferPkgDir string
+
+	// For the declaration of the package in its first file's package
+	// clause, the package's documentation (see Context.PackageDoc).
+	PkgDoc string
+
+	// Whether the symb was found in a _test.go file, going by the name of
+	// File (not one adjusted by a //line directive). Test files are walked
+	// in filename order along with the package's other files, so their
+	// symbs are interleaved with the others; in-package test files are
+	// type-checked together with the package, so their references to its
+	// declarations have the same ReferObj. It is false for Synthetic symbs.
+	FromTestFile bool
+
+	// Whether the identifier has no object, usually because the package
+	// does not type-check, in which case ReferObj is nil and ExprType is
+	// whatever type the checker recorded, if any. Such symbs are emitted
+	// only if Context.EmitUnresolved is set. Unresolved is also set, with
+	// ReferObj but without ReferPos, for a reference to a constant of the
+	// package whose position the checker lost, which is always emitted.
+	Unresolved bool
+
+	fset         *token.FileSet // used to resolve positions when marshalling
+	baseDir      string         // the BaseDir of the Context that emitted the symb
+	rawPositions bool           // whether positions are resolved ignoring //line directives
+}
+
+// Variant identifies the variant of a package that a symb was found in:
+// the package itself, the package combined with its in-package tests, or
+// its external test package.
+type Variant int
+
+const (
+	PkgVariant   Variant = iota // non-test files only
+	TestVariant                 // package files plus in-package _test.go files
+	XTestVariant                // external test package (package foo_test)
+)
+
+// DeclForm classifies how a name is bound by the statement or declaration
+// it appears in.
+type DeclForm int
+
+const (
+	NoDeclForm     DeclForm = iota // not bound by a statement or declaration listed below
+	VarKeyword                     // declared by a var declaration
+	ShortDecl                      // declared by a := statement
+	ShortDeclReuse                 // assigned by a := statement that declares other names, reusing a variable of the same scope
+	Assign                         // assigned by an assignment statement (including op= and range with =)
+	Param                          // declared as a receiver, parameter, or result of a function
+	Range                          // declared by a range clause with :=
+	TypeSwitch                     // declared in a type switch guard
+)
+
+func (f DeclForm) String() string {
+	switch f {
+	case NoDeclForm:
+		return "NoDeclForm"
+	case VarKeyword:
+		return "VarKeyword"
+	case ShortDecl:
+		return "ShortDecl"
+	case ShortDeclReuse:
+		return "ShortDeclReuse"
+	case Assign:
+		return "Assign"
+	case Param:
+		return "Param"
+	case Range:
+		return "Range"
+	case TypeSwitch:
+		return "TypeSwitch"
+	}
+	return "DeclForm(?)"
+}
+
+// CallContext classifies the calls that a symb is the function of.
+type CallContext int
+
+const (
+	NoCall    CallContext = iota // not the function of a call
+	PlainCall                    // the function of an ordinary call
+	DeferCall                    // the function of a deferred call
+	GoCall                       // the function of a call in a go statement
+)
+
+func (c CallContext) String() string {
+	switch c {
+	case NoCall:
+		return "NoCall"
+	case PlainCall:
+		return "PlainCall"
+	case DeferCall:
+		return "DeferCall"
+	case GoCall:
+		return "GoCall"
+	}
+	return "CallContext(?)"
+}
+
+// SelectionForm distinguishes references to methods that are not calls.
+type SelectionForm int
+
+const (
+	OrdinarySelection SelectionForm = iota // not a method selector, or a method selector that is called
+	MethodValue                            // an uncalled method value, such as buf.WriteString
+	MethodExpr                             // a method expression, such as (*bytes.Buffer).WriteString
+)
+
+func (f SelectionForm) String() string {
+	switch f {
+	case OrdinarySelection:
+		return "OrdinarySelection"
+	case MethodValue:
+		return "MethodValue"
+	case MethodExpr:
+		return "MethodExpr"
+	}
+	return "SelectionForm(?)"
 }
 
 // Context holds the context for IterateSymbs.
@@ -33,60 +210,449 @@ type Context struct {
 	// FileSet holds the fileset used when importing packages.
 	FileSet *token.FileSet
 
-	// idObjs stores off go/types typecheck results for each ident.
-	idObjs map[*ast.Ident]types.Object
+	// BaseDir, if set, makes the filenames of the positions reported by
+	// Position, by the symbs the Context emits (their ReferFile and their
+	// JSON encoding), and by WriteCtags and WriteEtags for those symbs
+	// relative to it. Files outside BaseDir keep their absolute names.
+	BaseDir string
+
+	// AdjustedPositions causes the positions reported by Position, by the
+	// symbs the Context emits (their ReferFile, Position, ReferPosition,
+	// and JSON encoding), and by WriteCtags and Definition to honor
+	// //line directives, as token.FileSet.Position does: the filename and
+	// line (and column) of a position are both those the directive
+	// assigns, as in the original source of generated code. Otherwise
+	// both are those of the file as parsed. Byte offsets are always
+	// within the file as parsed, and edits (RenameEdits) and Emacs tags
+	// (WriteEtags), which address the parsed files, always use raw
+	// positions. NewContext sets AdjustedPositions.
+	AdjustedPositions bool
+
+	// info holds the type checker's results for the package being walked,
+	// or most recently walked. It and the other per-package state below
+	// are replaced by each call to IterateSymbs, so that the results for
+	// earlier packages can be garbage collected.
+	info types.Info
+
+	// typeSwitchVars maps the identifier declared in each type switch
+	// guard of the package being walked to the object implicitly declared
+	// for it in the switch's first clause.
+	typeSwitchVars map[*ast.Ident]types.Object
+
+	// errs holds the Errors found while walking the package.
+	errs []Error
+
+	// declForms maps the names bound by the statements and declarations of
+	// the package being walked to how they are bound.
+	declForms map[*ast.Ident]DeclForm
+
+	// assertedTypes maps the identifiers that name the named type in each
+	// type of a type assertion or type switch case of the package being
+	// walked (Foo in x.(*Foo)) to that whole type (*Foo), which is the
+	// ExprType of their symbs.
+	assertedTypes map[*ast.Ident]types.Type
+
+	// embeds holds the identifiers that name the embedded fields and
+	// interfaces of the struct and interface types of the package being
+	// walked (for pkg.T, the identifier T).
+	embeds map[*ast.Ident]bool
+
+	// recvVars holds the named receivers of the methods of the package
+	// being walked.
+	recvVars map[types.Object]bool
+
+	// implicits maps the identifiers synthesized for the composite
+	// literals of the package being walked whose types are elided to the
+	// named types they have, and those synthesized for the elements of
+	// unkeyed struct literals to the fields they initialize.
+	implicits map[*ast.Ident]types.Object
+
+	// commentIdents maps the identifiers synthesized for the mentions of
+	// objects in the doc comments of the package being walked to those
+	// objects.
+	commentIdents map[*ast.Ident]types.Object
 
-	// exprTypes stores off go/types typecheck results for each expr.
-	exprTypes map[ast.Expr]types.Type
+	// fieldTags maps the names declared by the tagged struct fields of
+	// the package being walked (including embedded fields) to their tags.
+	fieldTags map[*ast.Ident]string
+
+	// funcLits holds the function literals enclosing the node being
+	// walked, innermost last.
+	funcLits []*ast.FuncLit
+
+	// litFuncs holds the objects synthesized for the function literals of
+	// the package being walked, and litCounts the number of literals
+	// named so far directly inside each function or literal, by name.
+	litFuncs  map[*ast.FuncLit]*types.Func
+	litCounts map[string]int
+
+	currentFuncDecl *ast.FuncDecl // the function declaration being walked, if any
+
+	// callees maps the functions of the calls in the package being walked
+	// (without parentheses) to how they are called, and conversions maps
+	// the identifiers and selectors that are the type of a conversion to
+	// the conversion.
+	callees     map[ast.Expr]CallContext
+	conversions map[ast.Expr]*ast.CallExpr
+
+	// constSpecs maps the names declared in the const declarations of the
+	// package being walked to their place in those declarations.
+	constSpecs map[*ast.Ident]constSpec
+
+	// declSpecs maps the names declared in the const, var, and type
+	// declarations of the package being walked to their place in those
+	// declarations.
+	declSpecs map[*ast.Ident]declSpec
+
+	// declNodes maps the names declared in the package being walked to
+	// the nodes that declare them: the *ast.FuncDecl of a function or
+	// method, the *ast.GenDecl of an import, const, var, or type, the
+	// *ast.Field of a field, method, parameter, or result, and the
+	// *ast.AssignStmt, *ast.RangeStmt, *ast.TypeSwitchStmt, or
+	// *ast.LabeledStmt of the names they declare. Function literals are
+	// declared by themselves, and packages by the files of their package
+	// clauses.
+	declNodes map[*ast.Ident]ast.Node
 
 	// stores true if the object was defined in a function-local scope
 	locals map[types.Object]bool
 
-	typesCtxt      types.Context
+	// containers caches the Container of each object.
+	containers map[types.Object]types.Object
+
+	// packages caches imported packages by import path so that packages
+	// checked by the same Context share their dependencies, and pkgDirs
+	// caches the directories that packages outside GOROOT were found in
+	// ("" for the others).
+	packages   map[string]*types.Package
+	pkgDirs    map[string]string
+	packagesMu sync.Mutex // guards packages and pkgDirs
+
+	// checked caches the result of type-checking packages by import path
+	// (see check and Invalidate).
+	checked   map[string]*checkResult
+	checkedMu sync.Mutex // guards checked
+
+	typesConfig    types.Config
 	currentPackage *types.Package // the last package that was returned by types.Check
+	currentPkgName *types.PkgName // the object that package clauses refer to
 	currentFile    *ast.File      // the file whose AST we're currently walking
+	currentTest    bool           // whether currentFile is a _test.go file
+	currentVariant Variant        // the variant of the package being walked
+	currentFiles   []*ast.File    // the files of the package being walked
+	declsOnly      bool           // whether only declarations are emitted (see IterateDecls)
+
+	// IncludeTests causes the directory-based iteration methods to analyze
+	// _test.go files: in-package tests are checked together with the
+	// package, and the external test package is checked separately against
+	// it. The symbs found in test files have FromTestFile set. If
+	// IncludeTests is false, test files are ignored.
+	IncludeTests bool
+
+	// GOOS, GOARCH, and BuildTags determine which files of a directory are
+	// analyzed by the directory-based iteration methods, using the same
+	// file name and build constraint rules as the go tool. NewContext
+	// initializes GOOS and GOARCH to those of the host.
+	GOOS      string
+	GOARCH    string
+	BuildTags []string
+
+	// Overlay holds the contents of files that ParseFile and the
+	// directory-based iteration methods use instead of the files'
+	// contents on disk, keyed by absolute filename. A file in the
+	// overlay is analyzed even if it does not exist on disk. Positions
+	// in overlay files report the overlay filenames.
+	Overlay map[string][]byte
 
-	// Logf is used to print warning messages.
-	// If it is nil, no warning messages will be printed.
+	// SkipGenerated causes IterateSymbs to skip files that IsGenerated
+	// reports as machine-generated. Such files are still type-checked, so
+	// references to their declarations from other files resolve.
+	SkipGenerated bool
+
+	// IndexSymbs causes IterateSymbs to retain a copy of each emitted symb
+	// in an index by position, for use by FindSymbolAt. The index grows
+	// with every iteration.
+	IndexSymbs bool
+
+	posIndex       []*Symb // retained symbs, if IndexSymbs is set
+	posIndexSorted bool    // whether posIndex is sorted by Ident position
+
+	// TrackReferences causes IterateSymbs to retain a copy of each emitted
+	// symb keyed by the object it refers to, for use by FindReferences.
+	TrackReferences bool
+
+	refsByObj map[types.Object][]*Symb // retained symbs, if TrackReferences is set
+
+	// KeepSymbols causes IterateSymbs to retain a copy of each emitted
+	// symb that has syntax (see Synthetic), by file, for SymbolsInFile,
+	// SymbAt, and AllSymbols. Each retained symb costs a few hundred bytes
+	// and keeps the AST of its file and the type information of its
+	// package reachable, so the retained symbs of a large program can
+	// take as much memory as the analysis itself. They accumulate over
+	// every iteration (iterating over a file again retains its symbs
+	// again) until Reset is called.
+	KeepSymbols bool
+
+	kept      map[string]*keptFile // retained symbs by filename, if KeepSymbols is set
+	keptOrder []string             // filenames in kept, in the order of their first retained symbs
+	lastKept  *keptFile            // the keptFile of the last retained symb
+
+	// EmitImportedDecls causes IterateSymbs, after walking the files of a
+	// package, to emit a declaration symb for each exported package-level
+	// object of each package that the package imports directly, in order
+	// of import path and then of name. These packages are typically
+	// imported from export data, so there is no syntax for their objects:
+	// the symbs have Synthetic set and nil Expr, Ident, and File, and their
+	// ReferPos is whatever position the importer recorded. Synthetic symbs
+	// are not indexed for FindSymbolAt.
+	EmitImportedDecls bool
+
+	// TransitiveImportedDecls extends EmitImportedDecls to every package
+	// that the package imports, directly or indirectly.
+	TransitiveImportedDecls bool
+
+	// RetainChecks causes the Context to keep the result of type-checking
+	// every package it iterates over, for reuse by later iterations over
+	// the same files. Otherwise only the result for the most recently
+	// checked package is kept.
+	RetainChecks bool
+
+	// Parallelism is the maximum number of packages that IterateMany and
+	// IterateTree type-check at once; if it is 0, runtime.GOMAXPROCS(0) is
+	// used. The symbs of the packages are emitted in the same order
+	// regardless, and visitf is always called from the goroutine that
+	// called the iteration method.
+	Parallelism int
+
+	// Debug causes the Context to number each emitted symb and warning in
+	// emission order and to record them for Timeline.
+	Debug bool
+
+	seq      int     // sequence number of the last recorded event
+	timeline []Event // recorded events, if Debug is set
+
+	// sources holds the contents of analyzed files as they were when parsed.
+	sources   map[string]*source
+	sourcesMu sync.Mutex // guards sources and their stale flags
+
+	// Progress, if not nil, is called by IterateSymbs at each phase
+	// boundary (see ProgressPhase). It is called synchronously, from the
+	// goroutine that is iterating, and receives a copy of the event; it
+	// must not call the Context's iteration methods.
+	Progress func(event ProgressEvent)
+
+	// Filter, if not nil, is called with each symb before it is emitted;
+	// symbs for which it returns false are dropped without stopping the
+	// iteration. Dropped symbs are not passed to visitf (so a Graph, Index,
+	// or slice of symbs for WriteCtags built by visitf does not contain
+	// them), and are neither indexed for FindSymbolAt and FindReferences
+	// nor recorded for Timeline. See DeclsOnly, ExportedOnly, and NonLocal.
+	Filter func(symb *Symb) bool
+
+	// OnlyRefsTo, if not empty, restricts the symbs emitted to those that
+	// refer to objects of the packages with the listed import paths.
+	// Package names refer to the packages they import, so the qualifiers
+	// of references (and named imports) are included. Objects of the
+	// universe scope are included only if "" or "builtin" is listed, and
+	// function-local objects only if "" is listed. It is applied before
+	// Filter.
+	OnlyRefsTo []string
+
+	// Strict makes identifiers that have no object (usually because the
+	// package does not type-check) errors of kind Unresolved, rather than
+	// just warnings.
+	Strict bool
+
+	// EmitUnresolved causes identifiers that have no object to be emitted
+	// as symbs with Unresolved set (and a nil ReferObj), rather than being
+	// dropped, so that references in code that does not type-check are
+	// still recorded by name. Blank identifiers are never emitted.
+	EmitUnresolved bool
+
+	// CommentRefs causes the words of doc comments (of the package and of
+	// declarations, including those of specs and fields) that name
+	// objects to be emitted as symbs with InComment set, so that
+	// documentation can link to them. Only the names of the package scope
+	// and of imported packages are matched, as in "Use NewContext" or
+	// "see ast.File", along with the fields and methods of package-level
+	// types, as in "Context.Filter"; the names of locals and of the
+	// universe scope are not, as they are too likely to be ordinary words.
+	CommentRefs bool
+
+	// ReuseSymbs causes the iteration methods to pass the same *Symb to
+	// every call of visitf (and of Filter), overwriting it for each symb,
+	// to save allocating one per symb. visitf must then copy any symb it
+	// retains, as Index.Add and Graph.Add do, rather than keep the
+	// pointer.
+	ReuseSymbs bool
+
+	reused Symb // the symb passed to visitf, if ReuseSymbs is set
+
+	// Log, if not nil, is called with each message logged while
+	// iterating, including the Errors found while walking a package, and
+	// its severity. The message is formatted from msg and args as by
+	// fmt.Sprintf.
+	Log func(level Level, pos token.Pos, msg string, args ...interface{})
+
+	// Logf is used to print warning messages: those that Log receives at
+	// LevelWarn and above. If it is nil, no warning messages will be
+	// printed.
 	Logf func(pos token.Pos, f string, a ...interface{})
+
+	logCounts map[Level]int // number of messages logged, by level
+	path      []ast.Node    // the nodes being walked, outermost first (see astVisitor)
+	emitting  *ast.Ident    // the Ident of the symb being emitted, which path encloses
+	enclosing []ast.Node    // the last result of EnclosingNodes, reused by the next call
 }
 
 func NewContext() *Context {
 	var ctxt *Context
 	ctxt = &Context{
-		FileSet:   token.NewFileSet(),
-		idObjs:    make(map[*ast.Ident]types.Object, 0),
-		exprTypes: make(map[ast.Expr]types.Type, 0),
-		locals:    make(map[types.Object]bool, 0),
-		typesCtxt: types.Context{
-			Ident: func(id *ast.Ident, obj types.Object) {
-				ctxt.idObjs[id] = obj
-			},
-			Expr: func(e ast.Expr, typ types.Type, val exact.Value) {
-				ctxt.exprTypes[astBaseType(e)] = typeBaseType(typ)
-			},
+		FileSet:           token.NewFileSet(),
+		AdjustedPositions: true,
+		packages:          make(map[string]*types.Package),
+		pkgDirs:           make(map[string]string),
+		checked:           make(map[string]*checkResult),
+		sources:           make(map[string]*source),
+		refsByObj:         make(map[types.Object][]*Symb),
+		logCounts:         make(map[Level]int),
+		GOOS:              build.Default.GOOS,
+		GOARCH:            build.Default.GOARCH,
+		typesConfig: types.Config{
+			// Keep checking after the first error so that the rest of
+			// the package still resolves. Check returns the first error.
+			Error: func(err error) {},
 		},
 	}
+	ctxt.typesConfig.Import = ctxt.importPackage
 
 	return ctxt
 }
 
-func (ctxt *Context) logf(pos token.Pos, f string, a ...interface{}) {
-	if ctxt.Logf == nil {
-		return
+// importPackage imports a package, consulting the Context's cache of
+// previously imported packages first. Packages are located like the go
+// tool does, in GOROOT and then in each element of GOPATH; if a package
+// is in none of them, the error lists the directories searched.
+func (ctxt *Context) importPackage(imports map[string]*types.Package, path string) (*types.Package, error) {
+	ctxt.packagesMu.Lock()
+	defer ctxt.packagesMu.Unlock()
+	if pkg, present := ctxt.packages[path]; present {
+		imports[path] = pkg
+		return pkg, nil
+	}
+	pkg, err := types.GcImport(imports, path)
+	if err != nil {
+		if _, findErr := build.Default.Import(path, "", build.FindOnly); findErr != nil {
+			return nil, findErr
+		}
+		return nil, err
+	}
+	ctxt.packages[path] = pkg
+	return pkg, nil
+}
+
+func (ctxt *Context) logf(level Level, pos token.Pos, f string, a ...interface{}) {
+	ctxt.warnf(MiscWarning, level, pos, f, a...)
+}
+
+func (ctxt *Context) warnf(kind WarningKind, level Level, pos token.Pos, f string, a ...interface{}) {
+	if ctxt.Debug {
+		ctxt.seq++
+		w := &Warning{Seq: ctxt.seq, Kind: kind, Level: level, Pos: pos, Msg: fmt.Sprintf(f, a...)}
+		ctxt.timeline = append(ctxt.timeline, Event{Seq: w.Seq, Warning: w})
+	}
+	ctxt.logCounts[level]++
+	if ctxt.Log != nil {
+		ctxt.Log(level, pos, f, a...)
+	}
+	if ctxt.Logf != nil && level >= LevelWarn {
+		ctxt.Logf(pos, f, a...)
 	}
-	ctxt.Logf(pos, f, a...)
 }
 
 // IterateSymbs calls visitf for each symb in the given file.  If
 // visitf returns false, the iteration stops.
-func (ctxt *Context) IterateSymbs(importPath string, files []*ast.File, visitf func(symb *Symb) bool) (err error) {
-	ctxt.currentPackage, err = ctxt.typesCtxt.Check(importPath, ctxt.FileSet, files...)
+//
+// The result of type-checking the files is cached on the Context, and
+// later calls for the same import path and the same files (by identity)
+// reuse it; see RetainChecks and Invalidate.
+//
+// The files are walked in order of filename (as recorded in the Context's
+// FileSet), whatever their order in files, and the symbs of each file are
+// emitted in order of position, so the order of emission is deterministic.
+//
+// The symbs of a package that does not type-check are still emitted, as
+// far as the checker resolved them. The error returned, if any, is an
+// Errors listing the type checker's errors and the problems found while
+// walking the files (see AsErrors).
+func (ctxt *Context) IterateSymbs(importPath string, files []*ast.File, visitf func(symb *Symb) bool) error {
+	return ctxt.iterate(&checkJob{importPath: importPath, files: ctxt.sortFiles(files)}, visitf)
+}
+
+// IterateDecls calls visitf for each declaration among the symbs that
+// IterateSymbs would emit for files, in the same order and with the same
+// fields; references are skipped without looking up what they refer to.
+// It is otherwise like IterateSymbs.
+func (ctxt *Context) IterateDecls(importPath string, files []*ast.File, visitf func(symb *Symb) bool) error {
+	return ctxt.iterate(&checkJob{importPath: importPath, files: ctxt.sortFiles(files), declsOnly: true}, visitf)
+}
+
+// iterate calls visitf for each symb in the files of job, type-checking
+// them first unless that has already been done.
+func (ctxt *Context) iterate(job *checkJob, visitf func(symb *Symb) bool) error {
+	if job.err != nil {
+		return job.err
+	}
+	importPath, files := job.importPath, job.files
+	var nsymbs int
+	if ctxt.Progress != nil {
+		inner := visitf
+		visitf = func(symb *Symb) bool {
+			nsymbs++
+			return inner(symb)
+		}
+		ctxt.Progress(ProgressEvent{Phase: CheckStarted, ImportPath: importPath})
+	}
+	if job.checked == nil {
+		ctxt.runCheck(job)
+	}
+	checked := job.checked
+	ctxt.info = checked.info
+	ctxt.currentPackage = checked.pkg
+	ctxt.errs = nil
+	ctxt.currentPkgName = checked.pkgName
+	// Most of the per-walk maps hold an entry for some of the names the
+	// package declares, so size the busiest of them for that many.
+	ndefs := len(ctxt.info.Defs)
+	ctxt.typeSwitchVars = make(map[*ast.Ident]types.Object)
+	ctxt.implicits = make(map[*ast.Ident]types.Object)
+	ctxt.commentIdents = make(map[*ast.Ident]types.Object)
+	ctxt.recvVars = make(map[types.Object]bool)
+	ctxt.embeds = make(map[*ast.Ident]bool)
+	ctxt.assertedTypes = make(map[*ast.Ident]types.Type)
+	ctxt.declForms = make(map[*ast.Ident]DeclForm, ndefs)
+	ctxt.constSpecs = make(map[*ast.Ident]constSpec)
+	ctxt.declSpecs = make(map[*ast.Ident]declSpec)
+	ctxt.declNodes = make(map[*ast.Ident]ast.Node, ndefs)
+	ctxt.fieldTags = make(map[*ast.Ident]string)
+	ctxt.funcLits = nil
+	ctxt.litFuncs = make(map[*ast.FuncLit]*types.Func)
+	ctxt.litCounts = make(map[string]int)
+	ctxt.callees = make(map[ast.Expr]CallContext)
+	ctxt.conversions = make(map[ast.Expr]*ast.CallExpr)
+	ctxt.locals = make(map[types.Object]bool, ndefs)
+	ctxt.containers = make(map[types.Object]types.Object, ndefs)
+	if ctxt.Progress != nil {
+		ctxt.Progress(ProgressEvent{Phase: CheckFinished, ImportPath: importPath, Duration: job.checkTime})
+	}
+	ctxt.currentVariant = ctxt.variant(files)
+	ctxt.currentFiles = files
+	ctxt.declsOnly = job.declsOnly
 
-	var visit astVisitor
+	visit := astVisitor{ctxt: ctxt}
 	ok := true
 	local := false // TODO set to true inside function body
-	visit = func(n ast.Node) bool {
+	visit.f = func(n ast.Node) bool {
 		if !ok {
 			return false
 		}
@@ -95,19 +661,36 @@ func (ctxt *Context) IterateSymbs(importPath string, files []*ast.File, visitf f
 			// If the file imports a package to ".", abort
 			// because we don't support that (yet).
 			if n.Name != nil && n.Name.Name == "." {
-				ctxt.logf(n.Pos(), "import to . not supported")
+				ctxt.errorf(Unsupported, n.Pos(), "import to . not supported")
 				ok = false
 				return false
 			}
 			return true
 
 		case *ast.FuncDecl:
-			// add object for init functions
-			if n.Recv == nil && n.Name.Name == "init" {
-				n.Name.Obj = ast.NewObj(ast.Fun, "init")
+			if n.Recv == nil && n.Name.Name == "init" && ctxt.info.Defs[n.Name] == nil {
+				// init functions aren't declared in any scope, so the
+				// checker may not report an object for them. Give each
+				// one its own.
+				ctxt.info.Defs[n.Name] = types.NewFunc(n.Name.Pos(), ctxt.currentPackage, "init", types.NewSignature(nil, nil, nil, false))
+			}
+			if !ctxt.visitDoc(n.Doc, visitf) {
+				ok = false
+				return false
 			}
 			local = true
+			ctxt.currentFuncDecl = n
+			ctxt.declNodes[n.Name] = n
+			defer func() { ctxt.currentFuncDecl = nil }()
 			if n.Recv != nil {
+				for _, field := range n.Recv.List {
+					for _, name := range field.Names {
+						if obj := ctxt.info.Defs[name]; obj != nil {
+							ctxt.recvVars[obj] = true
+						}
+						ctxt.declForms[name] = Param
+					}
+				}
 				ast.Walk(visit, n.Recv)
 			}
 			var e ast.Expr = n.Name
@@ -116,8 +699,9 @@ func (ctxt *Context) IterateSymbs(importPath string, files []*ast.File, visitf f
 				// selector expression so that visitExpr doesn't
 				// just see a blank name.
 				if len(n.Recv.List) != 1 {
-					ctxt.logf(n.Pos(), "expected one receiver only!")
-					return true
+					ctxt.errorf(Unsupported, n.Pos(), "expected one receiver only!")
+					local = false
+					return false
 				}
 				e = &ast.SelectorExpr{
 					X:   n.Recv.List[0].Type,
@@ -136,22 +720,223 @@ func (ctxt *Context) IterateSymbs(importPath string, files []*ast.File, visitf f
 			ok = ctxt.visitExpr(n, local, visitf)
 			return false
 
-		case *ast.KeyValueExpr:
-			// don't try to resolve the key part of a key-value
-			// because it might be a map key which doesn't
-			// need resolving, and we can't tell without being
-			// complicated with types.
-			ast.Walk(visit, n.Value)
+		case *ast.TypeAssertExpr:
+			if n.Type != nil {
+				ctxt.addAssertedType(n.Type)
+			}
+			return true
+
+		case *ast.TypeSwitchStmt:
+			for _, clause := range n.Body.List {
+				for _, e := range clause.(*ast.CaseClause).List {
+					ctxt.addAssertedType(e)
+				}
+			}
+			// The variable declared in the guard has a distinct
+			// implicit object in each clause, and none of its own.
+			if assign, isAssign := n.Assign.(*ast.AssignStmt); isAssign && len(assign.Lhs) == 1 {
+				if id, isIdent := assign.Lhs[0].(*ast.Ident); isIdent {
+					ctxt.declForms[id] = TypeSwitch
+					ctxt.declNodes[id] = n
+					for _, clause := range n.Body.List {
+						if obj := ctxt.info.Implicits[clause]; obj != nil {
+							ctxt.typeSwitchVars[id] = obj
+							break
+						}
+					}
+				}
+			}
+			return true
+
+		case *ast.GenDecl:
+			if !ctxt.visitDoc(n.Doc, visitf) {
+				ok = false
+				return false
+			}
+			ctxt.addDeclSpecs(n)
+			switch n.Tok {
+			case token.CONST:
+				ctxt.addConstSpecs(n)
+			case token.VAR:
+				for _, spec := range n.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						ctxt.declForms[name] = VarKeyword
+					}
+				}
+			}
+			return true
+
+		case *ast.FuncType:
+			for _, fields := range []*ast.FieldList{n.Params, n.Results} {
+				if fields == nil {
+					continue
+				}
+				for _, field := range fields.List {
+					for _, name := range field.Names {
+						ctxt.declForms[name] = Param
+					}
+				}
+			}
+			return true
+
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if id, isIdent := lhs.(*ast.Ident); isIdent {
+					if _, present := ctxt.declForms[id]; present {
+						// A type switch guard.
+						continue
+					}
+					switch {
+					case n.Tok != token.DEFINE:
+						ctxt.declForms[id] = Assign
+					case ctxt.info.Defs[id] != nil:
+						ctxt.declForms[id] = ShortDecl
+						ctxt.declNodes[id] = n
+					default:
+						// A := statement only assigns to the names
+						// that are already declared in the same
+						// scope.
+						ctxt.declForms[id] = ShortDeclReuse
+					}
+				}
+			}
+			return true
+
+		case *ast.RangeStmt:
+			form := Assign
+			if n.Tok == token.DEFINE {
+				form = Range
+			}
+			for _, e := range []ast.Expr{n.Key, n.Value} {
+				if id, isIdent := e.(*ast.Ident); isIdent {
+					ctxt.declForms[id] = form
+					if form == Range {
+						ctxt.declNodes[id] = n
+					}
+				}
+			}
+			return true
+
+		case *ast.TypeSpec:
+			if !ctxt.visitDoc(n.Doc, visitf) {
+				ok = false
+				return false
+			}
+			return true
+
+		case *ast.ValueSpec:
+			if !ctxt.visitDoc(n.Doc, visitf) {
+				ok = false
+				return false
+			}
+			return true
+
+		case *ast.Field:
+			if !ctxt.visitDoc(n.Doc, visitf) {
+				ok = false
+				return false
+			}
+			for _, name := range n.Names {
+				ctxt.declNodes[name] = n
+			}
+			if id := embeddedFieldName(n.Type); id != nil && len(n.Names) == 0 {
+				ctxt.declNodes[id] = n
+			}
+			return true
+
+		case *ast.LabeledStmt:
+			ctxt.declNodes[n.Label] = n
+			return true
+
+		case *ast.StructType:
+			ctxt.addFieldTags(n)
+			ctxt.addEmbeds(n.Fields)
+			return true
+
+		case *ast.InterfaceType:
+			ctxt.addEmbeds(n.Methods)
+			return true
+
+		case *ast.FuncLit:
+			ok = ctxt.visitFuncLit(n, visitf)
+			ctxt.funcLits = append(ctxt.funcLits, n)
+			ast.Walk(visit, n.Type)
+			ast.Walk(visit, n.Body)
+			ctxt.funcLits = ctxt.funcLits[:len(ctxt.funcLits)-1]
 			return false
 
+		case *ast.CompositeLit:
+			if n.Type != nil {
+				ast.Walk(visit, n.Type)
+			} else if id := ctxt.implicitTypeIdent(n); id != nil {
+				ok = ctxt.visitExpr(id, local, visitf)
+			}
+			// The keys of a struct literal are field names, which
+			// the checker resolves; the keys of other literals are
+			// ordinary expressions. The elements of an unkeyed struct
+			// literal initialize the fields in order, so each gets an
+			// implicit reference to its field.
+			var st *types.Struct
+			if tv, present := ctxt.info.Types[n]; present && tv.Type != nil {
+				st, _ = derefType(tv.Type).Underlying().(*types.Struct)
+			}
+			for i, elt := range n.Elts {
+				if kv, isKV := elt.(*ast.KeyValueExpr); isKV {
+					if key, isIdent := kv.Key.(*ast.Ident); isIdent && st != nil {
+						if ok {
+							ok = ctxt.visitExpr(key, local, visitf)
+						}
+					} else {
+						ast.Walk(visit, kv.Key)
+					}
+					elt = kv.Value
+				} else if st != nil && i < st.NumFields() && ok {
+					field := st.Field(i)
+					id := &ast.Ident{NamePos: elt.Pos(), Name: field.Name()}
+					ctxt.implicits[id] = field
+					ok = ctxt.visitExpr(id, local, visitf)
+				}
+				ast.Walk(visit, elt)
+			}
+			return false
+
+		case *ast.CallExpr:
+			fun := unparen(n.Fun)
+			if ctxt.info.Types[n.Fun].IsType() {
+				switch fun.(type) {
+				case *ast.Ident, *ast.SelectorExpr:
+					ctxt.conversions[fun] = n
+				}
+			} else if _, present := ctxt.callees[fun]; !present {
+				ctxt.callees[fun] = PlainCall
+			}
+			return true
+
+		case *ast.DeferStmt:
+			ctxt.callees[unparen(n.Call.Fun)] = DeferCall
+			return true
+
+		case *ast.GoStmt:
+			ctxt.callees[unparen(n.Call.Fun)] = GoCall
+			return true
+
 		case *ast.SelectorExpr:
+			// In a chain like a.B.C, walking X emits a and a.B
+			// (innermost first) before a.B.C is emitted here. Sel is
+			// never walked on its own, so each component of the
+			// chain is emitted exactly once.
 			ast.Walk(visit, n.X)
 			ok = ctxt.visitExpr(n, local, visitf)
 			return false
 
 		case *ast.File:
-			ctxt.currentFile = n
-			ok = ctxt.visitExpr(n.Name, false, visitf)
+			if ctxt.SkipGenerated && IsGenerated(n) {
+				// Generated files are type-checked along with the rest
+				// of the package, but no symbs are emitted for them.
+				return false
+			}
+			ctxt.currentFile, ctxt.currentTest = n, isTestFilename(ctxt.Filename(n))
+			ok = ctxt.visitDoc(n.Doc, visitf) && ctxt.visitPackageClause(n, visitf)
 			for _, d := range n.Decls {
 				ast.Walk(visit, d)
 			}
@@ -162,35 +947,536 @@ func (ctxt *Context) IterateSymbs(importPath string, files []*ast.File, visitf f
 		return true
 	}
 
-	// We sorted pkg.Files by name into pkgFiles above. It needs to be
-	// sorted, or else our walk order is nondeterministic.
-	for _, file := range files {
-		ast.Walk(visit, file)
+	walkFile := func(file *ast.File) {
+		if !job.exportedOnly {
+			ast.Walk(visit, file)
+			return
+		}
+		if !ok || ctxt.SkipGenerated && IsGenerated(file) {
+			return
+		}
+		ctxt.currentFile, ctxt.currentTest = file, isTestFilename(ctxt.Filename(file))
+		ok = ctxt.visitExportedDecls(file, visitf)
+		ctxt.currentFile = nil
+	}
+
+	walk := files
+	if job.walk != nil {
+		walk = job.walk
+	}
+	for _, file := range walk {
+		if ctxt.Progress == nil {
+			walkFile(file)
+			continue
+		}
+		if !ok {
+			break
+		}
+		filename := ctxt.Filename(file)
+		ctxt.Progress(ProgressEvent{Phase: FileStarted, ImportPath: importPath, Filename: filename, Symbs: nsymbs})
+		start := time.Now()
+		walkFile(file)
+		ctxt.Progress(ProgressEvent{Phase: FileFinished, ImportPath: importPath, Filename: filename, Symbs: nsymbs, Duration: time.Since(start)})
+	}
+	if ok && ctxt.EmitImportedDecls && ctxt.currentPackage != nil && job.walk == nil && !job.exportedOnly {
+		ctxt.emitImportedDecls(visitf)
+	}
+
+	return joinErrors(job.syntaxErrs, checked.errs, ctxt.errs)
+}
+
+// constSpec describes the place of a constant's name in a const
+// declaration.
+type constSpec struct {
+	group     token.Pos // position of the const declaration
+	index     int       // index of the spec in the declaration
+	iotaBased bool      // whether the spec's (possibly implicit) values use iota
+}
+
+// addConstSpecs records the place of each name declared in decl, a const
+// declaration, in ctxt.constSpecs.
+func (ctxt *Context) addConstSpecs(decl *ast.GenDecl) {
+	iota := types.Universe.Lookup("iota")
+	var values []ast.Expr
+	for i, spec := range decl.Specs {
+		vspec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if len(vspec.Values) > 0 {
+			values = vspec.Values
+		}
+		// A spec without values implicitly repeats the values of the
+		// last spec that has them.
+		iotaBased := false
+		for _, v := range values {
+			ast.Inspect(v, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && ctxt.info.Uses[id] == iota {
+					iotaBased = true
+				}
+				return !iotaBased
+			})
+		}
+		for _, name := range vspec.Names {
+			ctxt.constSpecs[name] = constSpec{group: decl.Pos(), index: i, iotaBased: iotaBased}
+		}
+	}
+}
+
+// declSpec describes the place of a name in a const, var, or type
+// declaration.
+type declSpec struct {
+	group token.Pos // position of the declaration
+	spec  int       // index of the spec in the declaration
+	name  int       // index of the name in the spec
+}
+
+// addDeclSpecs records the place of each name declared in decl, a general
+// declaration, in ctxt.declSpecs (unless it is an import), and decl as the
+// node that declares it in ctxt.declNodes.
+func (ctxt *Context) addDeclSpecs(decl *ast.GenDecl) {
+	for i, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.ImportSpec:
+			if spec.Name != nil {
+				ctxt.declNodes[spec.Name] = decl
+			}
+		case *ast.ValueSpec:
+			for j, name := range spec.Names {
+				ctxt.declSpecs[name] = declSpec{group: decl.Pos(), spec: i, name: j}
+				ctxt.declNodes[name] = decl
+			}
+		case *ast.TypeSpec:
+			ctxt.declSpecs[spec.Name] = declSpec{group: decl.Pos(), spec: i}
+			ctxt.declNodes[spec.Name] = decl
+		}
+	}
+}
+
+// visitFuncLit emits a declaration symb for lit, whose object is a
+// function synthesized for it. The function and the symb's Ident are named
+// after the enclosing function, in the style of the gc compiler: the first
+// literal in F is "F.func1", and the first literal inside that is
+// "F.func1.1". Literals outside any function are named after "glob".
+func (ctxt *Context) visitFuncLit(lit *ast.FuncLit, visitf func(*Symb) bool) bool {
+	var name string
+	if len(ctxt.funcLits) > 0 {
+		outer := ctxt.litFuncs[ctxt.funcLits[len(ctxt.funcLits)-1]].Name()
+		ctxt.litCounts[outer]++
+		name = fmt.Sprintf("%s.%d", outer, ctxt.litCounts[outer])
+	} else {
+		outer := "glob"
+		if f := ctxt.currentFuncDecl; f != nil {
+			outer = f.Name.Name
+			if f.Recv != nil && len(f.Recv.List) == 1 {
+				outer = recvExprName(f.Recv.List[0].Type) + "." + outer
+			}
+		}
+		ctxt.litCounts[outer]++
+		name = fmt.Sprintf("%s.func%d", outer, ctxt.litCounts[outer])
+	}
+
+	sig, _ := ctxt.info.Types[lit].Type.(*types.Signature)
+	if sig == nil {
+		sig = types.NewSignature(nil, nil, nil, false)
 	}
+	fn := types.NewFunc(lit.Pos(), ctxt.currentPackage, name, sig)
+	ctxt.litFuncs[lit] = fn
+	ctxt.locals[fn] = true
 
-	return err
+	id := &ast.Ident{NamePos: lit.Pos(), Name: name}
+	symb := Symb{
+		Expr:         id,
+		Ident:        id,
+		ExprType:     sig,
+		Pkg:          ctxt.currentPackage,
+		File:         ctxt.currentFile,
+		FromTestFile: ctxt.currentTest,
+		ReferPos:     lit.Pos(),
+		ReferFile:    ctxt.Position(lit.Pos()).Filename,
+		ReferObj:     fn,
+		Local:        true,
+		Anonymous:    true,
+		DeclNode:     lit,
+		CallContext:  ctxt.callees[lit],
+		Variant:      ctxt.currentVariant,
+		fset:         ctxt.FileSet,
+		baseDir:      ctxt.BaseDir,
+		rawPositions: !ctxt.AdjustedPositions,
+	}
+	symb.Container = ctxt.container(&symb)
+	return ctxt.emit(&symb, visitf)
+}
+
+// isCaptured reports whether obj, referred to from the node being walked,
+// is a variable declared in a function that encloses the innermost
+// function literal around the node, and so is captured by the literal.
+func (ctxt *Context) isCaptured(obj types.Object) bool {
+	if len(ctxt.funcLits) == 0 {
+		return false
+	}
+	v, isVar := obj.(*types.Var)
+	if !isVar || v.IsField() || v.Pkg() != ctxt.currentPackage || !v.Pos().IsValid() {
+		return false
+	}
+	if ctxt.currentPackage.Scope().Lookup(v.Name()) == obj {
+		// A package-level variable.
+		return false
+	}
+	lit := ctxt.funcLits[len(ctxt.funcLits)-1]
+	return v.Pos() < lit.Pos() || v.Pos() >= lit.End()
+}
+
+// addFieldTags records the tags of the tagged fields of st in
+// ctxt.fieldTags, under each name that the field declares. The name of an
+// embedded field is that of its type.
+func (ctxt *Context) addFieldTags(st *ast.StructType) {
+	for _, field := range st.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		names := field.Names
+		if len(names) == 0 {
+			if id := embeddedFieldName(field.Type); id != nil {
+				names = []*ast.Ident{id}
+			}
+		}
+		for _, name := range names {
+			ctxt.fieldTags[name] = tag
+		}
+	}
+}
+
+// addAssertedType records the type denoted by e, a type in a type
+// assertion or type switch case, for the identifier that names its named
+// component: T in T, *T, []T, [n]*T, pkg.T, and so on. Types without a
+// named component (such as func() or nil) are ignored.
+func (ctxt *Context) addAssertedType(e ast.Expr) {
+	tv, present := ctxt.info.Types[e]
+	if !present || !tv.IsType() {
+		return
+	}
+	for {
+		switch t := e.(type) {
+		case *ast.ParenExpr:
+			e = t.X
+		case *ast.StarExpr:
+			e = t.X
+		case *ast.ArrayType:
+			e = t.Elt
+		case *ast.Ident:
+			ctxt.assertedTypes[t] = tv.Type
+			return
+		case *ast.SelectorExpr:
+			ctxt.assertedTypes[t.Sel] = tv.Type
+			return
+		default:
+			return
+		}
+	}
+}
+
+// addEmbeds records the identifiers that name the embedded fields or
+// interfaces in fields, a struct type's fields or an interface type's
+// methods.
+func (ctxt *Context) addEmbeds(fields *ast.FieldList) {
+	if fields == nil {
+		return
+	}
+	for _, field := range fields.List {
+		if len(field.Names) == 0 {
+			if id := embeddedFieldName(field.Type); id != nil {
+				ctxt.embeds[id] = true
+			}
+		}
+	}
+}
+
+// embeddedFieldName returns the identifier that names the embedded field
+// whose type is t (T, *T, pkg.T, or *pkg.T), or nil.
+func embeddedFieldName(t ast.Expr) *ast.Ident {
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	switch t := t.(type) {
+	case *ast.Ident:
+		return t
+	case *ast.SelectorExpr:
+		return t.Sel
+	}
+	return nil
+}
+
+// checkResult holds the results of type-checking the files of a package.
+type checkResult struct {
+	files   []*ast.File               // the files that were checked, sorted by filename
+	deps    map[string]*types.Package // packages imported from other checks instead of the import cache
+	pkg     *types.Package            // the checked package, or nil
+	pkgName *types.PkgName            // the object that package clauses refer to, or nil
+	info    types.Info
+	errs    []Error // the errors found by the type checker
+
+	funcBodiesIgnored bool // whether function bodies were left unchecked
+}
+
+// check type-checks files, which must be sorted by filename, as the
+// package with the given import path. Imports of the packages in deps are
+// satisfied by them rather than by the Context's import cache. If the same
+// files (by identity) were checked as that package against the same deps
+// before, and the result is still cached (see RetainChecks and
+// Invalidate), the earlier result is returned instead. If
+// ignoreFuncBodies is set, the bodies of functions are not checked (and a
+// cached full check will do). check may be called concurrently.
+func (ctxt *Context) check(importPath string, files []*ast.File, deps map[string]*types.Package, ignoreFuncBodies bool) *checkResult {
+	r := ctxt.cachedCheck(importPath)
+	if r != nil && sameFiles(r.files, files) && samePackages(r.deps, deps) && (ignoreFuncBodies || !r.funcBodiesIgnored) {
+		return r
+	}
+
+	conf := ctxt.typesConfig
+	conf.IgnoreFuncBodies = ignoreFuncBodies
+	imp := conf.Import
+	srcDir := ctxt.srcDir(files)
+	conf.Import = func(imports map[string]*types.Package, path string) (*types.Package, error) {
+		if pkg, present := deps[path]; present {
+			imports[path] = pkg
+			return pkg, nil
+		}
+		return imp(imports, ctxt.vendoredPath(path, srcDir))
+	}
+	r = &checkResult{
+		files:             files,
+		deps:              deps,
+		funcBodiesIgnored: ignoreFuncBodies,
+		info: types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		},
+	}
+	conf.Error = func(err error) {
+		r.errs = addTypeError(r.errs, err)
+	}
+	var err error
+	r.pkg, err = conf.Check(importPath, ctxt.FileSet, files, &r.info)
+	if err != nil && len(r.errs) == 0 {
+		r.errs = []Error{typeError(err)}
+	}
+	if r.pkg != nil && len(files) > 0 {
+		r.pkgName = types.NewPkgName(files[0].Name.Pos(), r.pkg, r.pkg.Name(), r.pkg)
+	}
+
+	ctxt.checkedMu.Lock()
+	defer ctxt.checkedMu.Unlock()
+	if !ctxt.RetainChecks {
+		ctxt.checked = make(map[string]*checkResult)
+	}
+	ctxt.checked[importPath] = r
+	return r
+}
+
+// sameFiles reports whether a and b hold the same files in the same order.
+func sameFiles(a, b []*ast.File) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// samePackages reports whether a and b map the same paths to the same
+// packages.
+func samePackages(a, b map[string]*types.Package) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, pkg := range a {
+		if b[path] != pkg {
+			return false
+		}
+	}
+	return true
+}
+
+// Invalidate discards the cached result of type-checking the package with
+// the given import path, so that the next iteration over it checks it
+// again. IterateSymbs reuses the result of checking a package when it is
+// passed the same *ast.File values as before, so callers that modify those
+// ASTs in place, or whose package's dependencies have changed, must call
+// Invalidate.
+func (ctxt *Context) Invalidate(importPath string) {
+	ctxt.checkedMu.Lock()
+	defer ctxt.checkedMu.Unlock()
+	delete(ctxt.checked, importPath)
+}
+
+// IterateSymbsPkg calls visitf for each symb in the files of pkg, as
+// returned by parser.ParseDir. It is otherwise like IterateSymbs.
+func (ctxt *Context) IterateSymbsPkg(importPath string, pkg *ast.Package, visitf func(symb *Symb) bool) error {
+	return ctxt.IterateSymbs(importPath, pkgFiles(pkg), visitf)
+}
+
+// variant determines the package variant that files make up.
+func (ctxt *Context) variant(files []*ast.File) Variant {
+	v := PkgVariant
+	for _, f := range files {
+		if strings.HasSuffix(f.Name.Name, "_test") {
+			return XTestVariant
+		}
+		if isTestFilename(ctxt.Filename(f)) {
+			v = TestVariant
+		}
+	}
+	return v
+}
+
+// sortFiles returns a copy of files sorted by filename.
+func (ctxt *Context) sortFiles(files []*ast.File) []*ast.File {
+	sorted := make([]*ast.File, len(files))
+	copy(sorted, files)
+	sort.Stable(filesByName{sorted, ctxt})
+	return sorted
+}
+
+type filesByName struct {
+	files []*ast.File
+	ctxt  *Context
 }
 
-func (ctxt *Context) filename(f *ast.File) string {
-	return ctxt.FileSet.Position(f.Package).Filename
+func (s filesByName) Len() int      { return len(s.files) }
+func (s filesByName) Swap(i, j int) { s.files[i], s.files[j] = s.files[j], s.files[i] }
+func (s filesByName) Less(i, j int) bool {
+	return s.ctxt.Filename(s.files[i]) < s.ctxt.Filename(s.files[j])
 }
 
-func (ctxt *Context) exprInfo(e ast.Expr) (obj types.Object, typ types.Type) {
-	if id, ok := e.(*ast.Ident); ok {
-		obj = ctxt.idObjs[id]
+// pkgFiles returns the files of pkg sorted by filename, which makes the
+// order of iteration over them deterministic.
+func pkgFiles(pkg *ast.Package) []*ast.File {
+	filenames := make([]string, 0, len(pkg.Files))
+	for filename := range pkg.Files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	files := make([]*ast.File, len(filenames))
+	for i, filename := range filenames {
+		files[i] = pkg.Files[filename]
+	}
+	return files
+}
+
+// Filename returns the name of f as recorded in the Context's FileSet,
+// whatever //line directives it contains.
+func (ctxt *Context) Filename(f *ast.File) string {
+	if tf := ctxt.FileSet.File(f.Package); tf != nil {
+		return tf.Name()
+	}
+	return ""
+}
+
+// Position resolves pos through the Context's FileSet, adjusted by //line
+// directives if ctxt.AdjustedPositions is set, with the filename made
+// relative to ctxt.BaseDir if it is set. It returns the zero Position for
+// NoPos.
+func (ctxt *Context) Position(pos token.Pos) token.Position {
+	if !pos.IsValid() {
+		return token.Position{}
+	}
+	p := ctxt.FileSet.PositionFor(pos, ctxt.AdjustedPositions)
+	p.Filename = relativeTo(ctxt.BaseDir, p.Filename)
+	return p
+}
+
+// relativeTo returns filename relative to baseDir, or filename itself if
+// baseDir is empty. Both are made absolute and cleaned first, and, if
+// filename is not under baseDir as given, symlinks in both are evaluated,
+// so that a symlinked GOPATH does not produce a path up through the link
+// target. A filename outside baseDir is returned absolute.
+func relativeTo(baseDir, filename string) string {
+	if baseDir == "" || filename == "" {
+		return filename
+	}
+	base, err := filepath.Abs(baseDir)
+	if err != nil {
+		return filename
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filename
+	}
+	if rel, ok := relativeUnder(base, abs); ok {
+		return rel
+	}
+	if realBase, err := filepath.EvalSymlinks(base); err == nil {
+		if realAbs, err := filepath.EvalSymlinks(abs); err == nil {
+			if rel, ok := relativeUnder(realBase, realAbs); ok {
+				return rel
+			}
+		}
+	}
+	return abs
+}
+
+// relativeUnder returns the path of abs relative to base, and whether abs
+// is under base. Both paths must be absolute.
+func relativeUnder(base, abs string) (string, bool) {
+	rel, err := filepath.Rel(base, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// exprInfo returns the object that id declares or refers to, and the base
+// type of id (see typeBaseType) if it is an expression, or the type of the
+// object otherwise.
+func (ctxt *Context) exprInfo(id *ast.Ident) (obj types.Object, typ types.Type) {
+	obj = ctxt.info.Defs[id]
+	if obj == nil {
+		obj = ctxt.info.Uses[id]
+	}
+	if obj == nil {
+		obj = ctxt.typeSwitchVars[id]
+	}
+	if obj == nil {
+		obj = ctxt.implicits[id]
+	}
+	if obj == nil {
+		obj = ctxt.commentIdents[id]
+	}
+	if tv, present := ctxt.info.Types[id]; present && tv.Type != nil {
+		typ = typeBaseType(tv.Type)
 	}
-	typ = ctxt.exprTypes[e]
 	if typ == nil && obj != nil && obj.Type() != types.Typ[types.Invalid] {
 		typ = obj.Type()
 	}
 	return
 }
 
+// mayDeclare reports whether id may be the name of a declaration, so that
+// IterateDecls can skip the references without looking up their objects.
+func (ctxt *Context) mayDeclare(id *ast.Ident) bool {
+	_, isDef := ctxt.info.Defs[id]
+	return isDef || ctxt.typeSwitchVars[id] != nil
+}
+
 func (ctxt *Context) visitExpr(e ast.Expr, local bool, visitf func(*Symb) bool) bool {
 	var symb Symb
 	symb.Expr = e
 	symb.Pkg = ctxt.currentPackage
-	symb.File = ctxt.currentFile
+	symb.File, symb.FromTestFile = ctxt.currentFile, ctxt.currentTest
+	symb.Variant = ctxt.currentVariant
+	symb.fset, symb.baseDir, symb.rawPositions = ctxt.FileSet, ctxt.BaseDir, !ctxt.AdjustedPositions
 	switch e := e.(type) {
 	case *ast.Ident:
 		if e.Name == "_" {
@@ -200,22 +1486,109 @@ func (ctxt *Context) visitExpr(e ast.Expr, local bool, visitf func(*Symb) bool)
 	case *ast.SelectorExpr:
 		symb.Ident = e.Sel
 	}
+	if symb.Ident == nil {
+		ctxt.logf(LevelWarn, e.Pos(), "no identifier in %s", ctxt.logPretty(e))
+		return true
+	}
+	if ctxt.declsOnly && !ctxt.mayDeclare(symb.Ident) {
+		return true
+	}
 	obj, t := ctxt.exprInfo(symb.Ident)
 	if obj == nil {
-		ctxt.logf(symb.Ident.Pos(), "no object for %s", pretty(e))
-		return true
+		if ctxt.Strict {
+			ctxt.errorf(Unresolved, symb.Ident.Pos(), "no object for %s", pretty(e))
+		} else {
+			ctxt.logf(LevelWarn, symb.Ident.Pos(), "no object for %s", ctxt.logPretty(e))
+		}
+		if !ctxt.EmitUnresolved {
+			return true
+		}
+		symb.ExprType = t
+		symb.Unresolved = true
+		return ctxt.emit(&symb, visitf)
 	}
 	symb.ExprType = t
 	symb.ReferObj = obj
-	if types.Universe.Lookup(obj.Pkg(), obj.Name()) != obj {
-		if _, isConst := obj.(*types.Const); isConst {
-			// workaround for http://code.google.com/p/go/issues/detail?id=5143
-			// TODO(sqs): remove this when the issue is fixed
-			return true
+	_, symb.Implicit = ctxt.implicits[symb.Ident]
+	_, symb.InComment = ctxt.commentIdents[symb.Ident]
+	symb.IsRecv = ctxt.recvVars[obj]
+	symb.Embedded = ctxt.embeds[symb.Ident]
+	symb.DeclForm = ctxt.declForms[symb.Ident]
+	if t, present := ctxt.assertedTypes[symb.Ident]; present {
+		symb.ExprType = t
+	}
+	if fn, isFunc := obj.(*types.Func); isFunc && ctxt.info.Defs[symb.Ident] == fn {
+		if sig, ok := fn.Type().(*types.Signature); ok && sig.Recv() != nil {
+			symb.Recv = derefType(sig.Recv().Type())
+		}
+	}
+	if call, present := ctxt.conversions[e]; present {
+		symb.IsConversion = true
+		if tv, present := ctxt.info.Types[call]; present && tv.Type != nil {
+			symb.ExprType = tv.Type
+		}
+	} else {
+		symb.CallContext = ctxt.callees[e]
+	}
+	symb.Captured = ctxt.isCaptured(obj)
+	if tag, present := ctxt.fieldTags[symb.Ident]; present {
+		symb.Tag = tag
+	}
+	if spec, present := ctxt.constSpecs[symb.Ident]; present {
+		symb.ConstGroup = spec.group
+		symb.ConstIndex = spec.index
+		symb.IotaBased = spec.iotaBased
+	}
+	if spec, present := ctxt.declSpecs[symb.Ident]; present {
+		symb.DeclGroup = spec.group
+		symb.SpecIndex = spec.spec
+		symb.NameIndex = spec.name
+	}
+	if obj == types.Universe.Lookup("iota") {
+		// The expressions of a constant spec are evaluated again for
+		// each spec that implicitly repeats them, so an iota has no
+		// single value.
+	} else if tv := ctxt.info.Types[symb.Ident]; tv.Value != nil {
+		symb.ConstVal = tv.Value
+	} else if c, isConst := obj.(*types.Const); isConst {
+		// The checker records no value for the name in a declaration.
+		symb.ConstVal = c.Val()
+	}
+	if sel, ok := e.(*ast.SelectorExpr); ok {
+		symb.Selection = ctxt.info.Selections[sel]
+		if symb.Selection != nil {
+			if tv, present := ctxt.info.Types[sel]; present && tv.Type != nil {
+				// The type of the whole selector: a method
+				// expression's type has the receiver as its first
+				// parameter, which the method's own type does not.
+				symb.ExprType = tv.Type
+			}
+			symb.Recv = selectionRecv(symb.Selection)
+			symb.Indirect = symb.Selection.Indirect()
+			switch {
+			case symb.Selection.Kind() == types.MethodExpr:
+				symb.Form = MethodExpr
+			case symb.Selection.Kind() == types.MethodVal && symb.CallContext == NoCall:
+				symb.Form = MethodValue
+			}
 		}
+	}
+	if _, isConst := obj.(*types.Const); isConst && obj.Pkg() == ctxt.currentPackage && !obj.Pos().IsValid() {
+		// Older checkers lose the position of some constants
+		// (http://code.google.com/p/go/issues/detail?id=5143).
+		// Leave the reference unresolved rather than emit a bogus
+		// ReferPos.
+		ctxt.logf(LevelInfo, symb.Ident.Pos(), "no position for constant %s", obj.Name())
+		symb.Unresolved = true
+	} else if !isUniverse(obj) {
 		symb.ReferPos = obj.Pos()
+		if symb.ReferPos.IsValid() {
+			symb.ReferFile = ctxt.Position(symb.ReferPos).Filename
+		}
+		symb.ReferPkgDir = ctxt.referPkgDir(obj)
 	} else {
 		symb.Universe = true
+		symb.Builtin = isBuiltin(obj)
 	}
 
 	if local {
@@ -226,15 +1599,190 @@ func (ctxt *Context) visitExpr(e ast.Expr, local bool, visitf func(*Symb) bool)
 			symb.Local = ctxt.locals[symb.ReferObj]
 		}
 	}
-	return visitf(&symb)
+	if symb.IsDecl() {
+		symb.DeclNode = ctxt.declNodes[symb.Ident]
+	}
+	symb.Container = ctxt.container(&symb)
+	return ctxt.emit(&symb, visitf)
 }
 
-type astVisitor func(n ast.Node) bool
+// visitPackageClause emits a symb for the package name in file's package
+// clause, referring to the package being checked. The package clause of
+// the first file of the package is its declaration, and those of the
+// other files refer to it, so that exactly one symb per package is a
+// declaration of the package.
+func (ctxt *Context) visitPackageClause(file *ast.File, visitf func(*Symb) bool) bool {
+	if ctxt.currentPkgName == nil {
+		ctxt.logf(LevelWarn, file.Name.Pos(), "no package for %s", file.Name.Name)
+		return true
+	}
+	symb := Symb{
+		Expr:         file.Name,
+		Ident:        file.Name,
+		Pkg:          ctxt.currentPackage,
+		File:         file,
+		FromTestFile: ctxt.currentTest,
+		ReferPos:     ctxt.currentPkgName.Pos(),
+		ReferObj:     ctxt.currentPkgName,
+		Variant:      ctxt.currentVariant,
+		fset:         ctxt.FileSet,
+		baseDir:      ctxt.BaseDir,
+		rawPositions: !ctxt.AdjustedPositions,
+	}
+	symb.ReferFile = ctxt.Position(symb.ReferPos).Filename
+	if symb.IsDecl() {
+		symb.DeclNode = file
+		symb.PkgDoc = ctxt.PackageDoc()
+	}
+	return ctxt.emit(&symb, visitf)
+}
+
+// PackageDoc returns the package documentation of the package most
+// recently iterated over by ctxt, as go/doc determines it: the text of the
+// package comments of its files, in order of filename, separated by blank
+// lines (by convention, only one file, often doc.go, has one). Comments
+// are only available if the files were parsed with parser.ParseComments,
+// as ParseFile and the directory-based iteration methods do.
+func (ctxt *Context) PackageDoc() string {
+	var doc string
+	for _, file := range ctxt.currentFiles {
+		if file.Doc == nil {
+			continue
+		}
+		if doc != "" {
+			doc += "\n"
+		}
+		doc += file.Doc.Text()
+	}
+	return doc
+}
 
-func (f astVisitor) Visit(n ast.Node) ast.Visitor {
-	if f(n) {
-		return f
+// emitImportedDecls emits a synthetic declaration symb for each exported
+// object in the scope of each package imported by the current package (or,
+// if ctxt.TransitiveImportedDecls is set, imported by it indirectly). It
+// returns false if visitf stopped the iteration.
+func (ctxt *Context) emitImportedDecls(visitf func(*Symb) bool) bool {
+	seen := map[*types.Package]bool{ctxt.currentPackage: true}
+	var pkgs []*types.Package
+	var add func(imports []*types.Package)
+	add = func(imports []*types.Package) {
+		for _, pkg := range imports {
+			if seen[pkg] {
+				continue
+			}
+			seen[pkg] = true
+			pkgs = append(pkgs, pkg)
+			if ctxt.TransitiveImportedDecls {
+				add(pkg.Imports())
+			}
+		}
 	}
+	add(ctxt.currentPackage.Imports())
+	sort.Sort(packagesByPath(pkgs))
+
+	for _, pkg := range pkgs {
+		if pkg == types.Unsafe {
+			// unsafe has no declarations, only builtins.
+			continue
+		}
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			if !ast.IsExported(name) {
+				continue
+			}
+			obj := scope.Lookup(name)
+			symb := Symb{
+				ExprType:     obj.Type(),
+				Pkg:          pkg,
+				ReferPos:     obj.Pos(),
+				ReferObj:     obj,
+				Synthetic:    true,
+				fset:         ctxt.FileSet,
+				baseDir:      ctxt.BaseDir,
+				rawPositions: !ctxt.AdjustedPositions,
+			}
+			if symb.ReferPos.IsValid() {
+				symb.ReferFile = ctxt.Position(symb.ReferPos).Filename
+			}
+			symb.ReferPkgDir = ctxt.referPkgDir(obj)
+			if !ctxt.emit(&symb, visitf) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type packagesByPath []*types.Package
+
+func (p packagesByPath) Len() int           { return len(p) }
+func (p packagesByPath) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p packagesByPath) Less(i, j int) bool { return p[i].Path() < p[j].Path() }
+
+// emit records symb as configured by the Context's options and then calls
+// visitf with a copy of it, unless it is a reference and only declarations
+// are being emitted, or ctxt.OnlyRefsTo or ctxt.Filter drops it.
+func (ctxt *Context) emit(symb *Symb, visitf func(*Symb) bool) bool {
+	if ctxt.declsOnly && !symb.IsDecl() {
+		return true
+	}
+	if len(ctxt.OnlyRefsTo) > 0 && !refersToPackage(symb, ctxt.OnlyRefsTo) {
+		return true
+	}
+	// symb is usually on the caller's stack; Filter and visitf see a
+	// copy, so that only the symbs that get that far are allocated.
+	var x *Symb
+	if ctxt.ReuseSymbs {
+		x = &ctxt.reused
+	} else {
+		x = new(Symb)
+	}
+	*x = *symb
+	ctxt.emitting = x.Ident
+	if ctxt.Filter != nil && !ctxt.Filter(x) {
+		return true
+	}
+	if ctxt.Debug {
+		ctxt.seq++
+		x.Seq = ctxt.seq
+		recorded := *x
+		ctxt.timeline = append(ctxt.timeline, Event{Seq: x.Seq, Symb: &recorded})
+	}
+	if ctxt.IndexSymbs && !x.Synthetic && !x.Anonymous && !x.Implicit {
+		indexed := *x
+		ctxt.posIndex = append(ctxt.posIndex, &indexed)
+		ctxt.posIndexSorted = false
+	}
+	if ctxt.TrackReferences && x.ReferObj != nil {
+		tracked := *x
+		ctxt.refsByObj[x.ReferObj] = append(ctxt.refsByObj[x.ReferObj], &tracked)
+	}
+	if ctxt.KeepSymbols && !x.Synthetic {
+		ctxt.keep(x)
+	}
+	return visitf(x)
+}
+
+// An astVisitor walks a syntax tree, calling f on each node, and keeps
+// ctxt.path, the stack of nodes being walked (for EnclosingNodes), up to
+// date. f walks the children of the nodes for which it returns false
+// itself, if at all, before it returns.
+type astVisitor struct {
+	ctxt *Context
+	f    func(n ast.Node) bool
+}
+
+func (v astVisitor) Visit(n ast.Node) ast.Visitor {
+	if n == nil {
+		// The children of the last node f returned true for are done.
+		v.ctxt.path = v.ctxt.path[:len(v.ctxt.path)-1]
+		return nil
+	}
+	v.ctxt.path = append(v.ctxt.path, n)
+	if v.f(n) {
+		return v
+	}
+	v.ctxt.path = v.ctxt.path[:len(v.ctxt.path)-1]
 	return nil
 }
 
@@ -246,17 +1794,60 @@ func pretty(n ast.Node) string {
 	return b.String()
 }
 
-// astBaseType returns the base type expr for AST type expr x.
-func astBaseType(e ast.Expr) ast.Expr {
-	switch t := e.(type) {
-	case *ast.ArrayType:
-		return astBaseType(t.Elt)
-	case *ast.MapType:
-		return astBaseType(t.Value)
-	case *ast.StarExpr:
-		return astBaseType(t.X)
+// logPretty returns pretty(n) for a logged message, or "" if the message
+// will not be seen (no Log or Logf is set and Debug is off), as printing a
+// node is costly and only LogCount would learn of the message.
+func (ctxt *Context) logPretty(n ast.Node) string {
+	if ctxt.Log == nil && ctxt.Logf == nil && !ctxt.Debug {
+		return ""
+	}
+	return pretty(n)
+}
+
+// implicitTypeIdent returns an identifier, synthesized at the opening
+// brace of lit, for the named type of lit, whose type is elided (as in the
+// inner literal of []T{{}}), and records its object in ctxt.implicits.
+// It returns nil if the type of lit is not a named type (or a pointer to
+// one).
+func (ctxt *Context) implicitTypeIdent(lit *ast.CompositeLit) *ast.Ident {
+	tv, present := ctxt.info.Types[lit]
+	if !present || tv.Type == nil {
+		return nil
+	}
+	named, ok := derefType(tv.Type).(*types.Named)
+	if !ok {
+		return nil
+	}
+	id := &ast.Ident{NamePos: lit.Lbrace, Name: named.Obj().Name()}
+	ctxt.implicits[id] = named.Obj()
+	return id
+}
+
+// selectionRecv returns the type that declares the member selected by sel,
+// with pointers removed: the receiver type of sel, or the type of the
+// embedded field that the selection goes through last.
+func selectionRecv(sel *types.Selection) types.Type {
+	t := sel.Recv()
+	index := sel.Index()
+	for _, i := range index[:len(index)-1] {
+		st, ok := derefType(t).Underlying().(*types.Struct)
+		if !ok {
+			break
+		}
+		t = st.Field(i).Type()
+	}
+	return derefType(t)
+}
+
+// unparen returns e with any enclosing parentheses stripped.
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
 	}
-	return e
 }
 
 // typeBaseType returns the base type for a types.Type.
@@ -265,7 +1856,7 @@ func typeBaseType(t types.Type) types.Type {
 	case *types.Array:
 		return typeBaseType(t.Elem())
 	case *types.Pointer:
-		return typeBaseType(t.Deref())
+		return typeBaseType(t.Elem())
 	case *types.Map:
 		return typeBaseType(t.Elem()) // TODO(sqs): also return Key type; typeBaseType needs to return multiple results?
 	case *types.Slice:
@@ -274,8 +1865,49 @@ func typeBaseType(t types.Type) types.Type {
 	return t
 }
 
+// IsDecl reports whether x is the declaration of the object it refers to.
+// Synthetic symbs are always declarations.
 func (x *Symb) IsDecl() bool {
-	return x.ReferPos == x.Ident.Pos()
+	return x.Synthetic || x.ReferPos == x.Ident.Pos()
+}
+
+// name returns the name of x's identifier, or of the object it declares if
+// x is synthetic.
+func (x *Symb) name() string {
+	if x.Synthetic {
+		return x.ReferObj.Name()
+	}
+	return x.Ident.Name
+}
+
+// pos returns the position of x's identifier, or the position of the
+// object it declares if x is synthetic.
+func (x *Symb) pos() token.Pos {
+	if x.Synthetic {
+		return x.ReferPos
+	}
+	return x.Ident.Pos()
+}
+
+// Span returns the positions of the start of x.Ident and of the byte
+// immediately after it. A synthetic symb has no Ident, so both are its
+// ReferPos.
+func (x *Symb) Span() (start, end token.Pos) {
+	if x.Synthetic {
+		return x.ReferPos, x.ReferPos
+	}
+	return x.Ident.Pos(), x.Ident.End()
+}
+
+// ExprSpan returns the positions of the start of x.Expr and of the byte
+// immediately after it. For a qualified identifier or selector, the span
+// includes the qualifier. A synthetic symb has no Expr, so both are its
+// ReferPos.
+func (x *Symb) ExprSpan() (start, end token.Pos) {
+	if x.Synthetic {
+		return x.ReferPos, x.ReferPos
+	}
+	return x.Expr.Pos(), x.Expr.End()
 }
 
 func (x *Symb) String() string {
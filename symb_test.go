@@ -13,7 +13,10 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -24,11 +27,25 @@ var fset = token.NewFileSet()
 var testPkgPaths = []string{
 	"foo",
 	"bar",
+	"crossfile",
+	"builtins",
+	"consts",
+	"inits",
+	"selections",
+	"enums",
+	"funclits",
+	"methodrefs",
+	"methods",
+	"typeexprs",
+	"linedir",
 }
 
 func TestSymb(t *testing.T) {
 	build.Default.GOPATH, _ = filepath.Abs("testdata/")
 	for _, pkgPath := range testPkgPaths {
+		// collectSymbs sets BaseDir to the test GOPATH, so the filenames
+		// in the marshalled positions don't depend on where the tests are
+		// run.
 		pkgs, err := parser.ParseDir(fset, filepath.Join(build.Default.GOPATH, "src", pkgPath), goFilesOnly, parser.AllErrors|parser.DeclarationErrors)
 		if err != nil {
 			t.Errorf("Error parsing %s: %v", pkgPath, err)
@@ -39,7 +56,7 @@ func TestSymb(t *testing.T) {
 			symbs := collectSymbs(pkgPath, pkg)
 			symbsByFilename := make(map[string][]Symb, 0)
 			for _, x := range symbs {
-				filename := fset.Position(x.Ident.Pos()).Filename
+				filename := fset.File(x.Ident.Pos()).Name()
 				if symbsByFilename[filename] == nil {
 					symbsByFilename[filename] = make([]Symb, 0)
 				}
@@ -53,6 +70,947 @@ func TestSymb(t *testing.T) {
 	}
 }
 
+// TestSymb_rawPositions checks the positions of the symbs in a file with
+// //line directives when AdjustedPositions is unset; TestSymb checks them
+// with it set.
+func TestSymb_rawPositions(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+	pkg, err := parseTestPkg("linedir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.BaseDir = build.Default.GOPATH
+	c.AdjustedPositions = false
+	var symbs []Symb
+	if err := c.IterateSymbsPkg("linedir", pkg, func(x *Symb) bool {
+		symbs = append(symbs, *x)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	checkOutput(filepath.Join(build.Default.GOPATH, "src", "linedir", "calc.go_raw"), symbs, t)
+
+	// The filename and line of a position come from the same mode.
+	for _, x := range symbs {
+		if x.Ident.Name != "Eval" {
+			continue
+		}
+		if pos := x.Position(nil); pos.Filename != "src/linedir/calc.go" || pos.Line != 6 {
+			t.Errorf("got raw position %s for Eval, want src/linedir/calc.go:6", pos)
+		}
+		c.AdjustedPositions = true
+		if pos := c.Position(x.Ident.Pos()); pos.Filename != "src/linedir/calc.y" || pos.Line != 10 {
+			t.Errorf("got adjusted position %s for Eval, want src/linedir/calc.y:10", pos)
+		}
+	}
+}
+
+func TestSymb_reversedFiles(t *testing.T) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := sortedFiles(pkg.Files)
+	for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+		files[i], files[j] = files[j], files[i]
+	}
+
+	c := NewContext()
+	c.FileSet = fset
+	c.BaseDir = build.Default.GOPATH
+	symbsByFilename := make(map[string][]Symb)
+	err = c.IterateSymbs("foo", files, func(symb *Symb) bool {
+		filename := fset.File(symb.Ident.Pos()).Name()
+		symbsByFilename[filename] = append(symbsByFilename[filename], *symb)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for filename, symbs := range symbsByFilename {
+		checkOutput(filename, symbs, t)
+	}
+}
+
+// TestSymb_noDuplicateIdents checks that each identifier in the source is
+// emitted at most once: method names (visited through a synthesized
+// selector), receiver types, and the Sel of selector chains are not
+// visited again by the walk. Implicit symbs have synthesized identifiers
+// that may share a position with a real one, and synthetic and anonymous
+// symbs have no identifier in the source.
+func TestSymb_noDuplicateIdents(t *testing.T) {
+	pkgPaths := append([]string{"chains", "receivers", "complits", "closures", "shadow", "embedfields"}, testPkgPaths...)
+	for _, pkgPath := range pkgPaths {
+		pkg, err := parseTestPkg(pkgPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewContext()
+		c.FileSet = fset
+		seen := make(map[token.Pos]string)
+		c.IterateSymbsPkg(pkgPath, pkg, func(x *Symb) bool {
+			if x.Synthetic || x.Implicit || x.Anonymous {
+				return true
+			}
+			if prev, dup := seen[x.Ident.Pos()]; dup {
+				t.Errorf("%s: %s emitted twice, as %s and %s", pkgPath, fset.Position(x.Ident.Pos()), prev, pretty(x.Expr))
+			}
+			seen[x.Ident.Pos()] = pretty(x.Expr)
+			return true
+		})
+		if len(seen) == 0 {
+			t.Errorf("%s: no symbs", pkgPath)
+		}
+	}
+}
+
+func TestSymb_file(t *testing.T) {
+	pkg, err := parseTestPkg("crossfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]int)
+	for _, x := range collectSymbs("crossfile", pkg) {
+		if x.File == nil || !(x.File.Pos() <= x.Ident.Pos() && x.Ident.Pos() < x.File.End()) {
+			t.Errorf("%s: got a File that does not contain the symb", shortPosition(x.Ident.Pos()))
+			continue
+		}
+		files[filepath.Base(fset.Position(x.File.Pos()).Filename)]++
+	}
+	if len(files) != 2 {
+		t.Errorf("got symbs in files %v, want both files of the package", files)
+	}
+}
+
+func TestSymb_init(t *testing.T) {
+	pkg, err := parseTestPkg("inits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inits []Symb
+	for _, x := range collectSymbs("inits", pkg) {
+		if x.Ident.Name == "init" {
+			inits = append(inits, x)
+		}
+	}
+	if len(inits) != 2 {
+		t.Fatalf("got %d init symbs, want 2", len(inits))
+	}
+	for _, x := range inits {
+		if _, isFunc := x.ReferObj.(*types.Func); !isFunc || !x.IsDecl() {
+			t.Errorf("%s: got init ReferObj %v (decl %v), want a declared *types.Func", shortPosition(x.Ident.Pos()), x.ReferObj, x.IsDecl())
+		}
+	}
+	if inits[0].ReferObj == inits[1].ReferObj {
+		t.Errorf("got the same ReferObj for both init functions")
+	}
+}
+
+func TestSymb_selection(t *testing.T) {
+	pkg, err := parseTestPkg("selections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sels := make(map[string]*types.Selection)
+	for _, x := range collectSymbs("selections", pkg) {
+		if _, isSel := x.Expr.(*ast.SelectorExpr); isSel && !x.IsDecl() {
+			sels[pretty(x.Expr)] = x.Selection
+		}
+	}
+
+	tests := []struct {
+		expr     string
+		kind     types.SelectionKind
+		indirect bool
+	}{
+		{"d.Describe", types.MethodVal, true},
+		{"d.ID", types.FieldVal, true},
+		{"d.Name", types.FieldVal, false},
+	}
+	for _, test := range tests {
+		sel := sels[test.expr]
+		if sel == nil {
+			t.Errorf("%s: no selection", test.expr)
+			continue
+		}
+		if sel.Kind() != test.kind || sel.Indirect() != test.indirect {
+			t.Errorf("%s: got selection kind %v (indirect %v), want %v (indirect %v)", test.expr, sel.Kind(), sel.Indirect(), test.kind, test.indirect)
+		}
+	}
+}
+
+func TestSymb_constGroups(t *testing.T) {
+	pkg, err := parseTestPkg("enums")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type constDecl struct {
+		group     string
+		index     int
+		iotaBased bool
+	}
+	decls := make(map[string]constDecl)
+	for _, x := range collectSymbs("enums", pkg) {
+		if _, isConst := x.ReferObj.(*types.Const); isConst && x.IsDecl() {
+			decls[x.Ident.Name] = constDecl{shortPosition(x.ConstGroup), x.ConstIndex, x.IotaBased}
+		}
+	}
+
+	want := map[string]constDecl{
+		"KindA": {"enums.go:5", 0, true},
+		"KindB": {"enums.go:5", 1, true},
+		"KindD": {"enums.go:5", 3, true},
+		"Size":  {"enums.go:14", 0, false},
+		"FlagX": {"enums.go:14", 1, true},
+		"FlagY": {"enums.go:14", 2, true},
+		"Count": {"enums.go:14", 3, false},
+		"Other": {"enums.go:14", 4, false},
+	}
+	if !reflect.DeepEqual(decls, want) {
+		t.Errorf("got const decls %v, want %v", decls, want)
+	}
+}
+
+func TestSymb_declGroups(t *testing.T) {
+	pkg, err := parseTestPkg("groups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type declPlace struct {
+		group      string
+		spec, name int
+	}
+	decls := make(map[string]declPlace)
+	for _, x := range collectSymbs("groups", pkg) {
+		if x.IsDecl() && x.DeclGroup.IsValid() {
+			decls[x.Ident.Name] = declPlace{shortPosition(x.DeclGroup), x.SpecIndex, x.NameIndex}
+		}
+	}
+
+	want := map[string]declPlace{
+		"Red":    {"groups.go:4", 0, 0},
+		"Green":  {"groups.go:4", 1, 0},
+		"Blue":   {"groups.go:4", 2, 0},
+		"x":      {"groups.go:10", 0, 0},
+		"y":      {"groups.go:10", 0, 1},
+		"z":      {"groups.go:10", 1, 0},
+		"Point":  {"groups.go:15", 0, 0},
+		"Line":   {"groups.go:15", 1, 0},
+		"single": {"groups.go:20", 0, 0},
+		"a":      {"groups.go:23", 0, 0},
+		"b":      {"groups.go:23", 0, 1},
+	}
+	if !reflect.DeepEqual(decls, want) {
+		t.Errorf("got decl places %v, want %v", decls, want)
+	}
+}
+
+func TestSymb_declNodes(t *testing.T) {
+	pkg, err := parseTestPkg("declnodes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := make(map[string]string)
+	for _, x := range collectSymbs("declnodes", pkg) {
+		if !x.IsDecl() {
+			if x.DeclNode != nil {
+				t.Errorf("%s: got DeclNode %T for a reference", shortPosition(x.Ident.Pos()), x.DeclNode)
+			}
+			continue
+		}
+		if x.DeclNode == nil {
+			t.Errorf("%s: got no DeclNode for the declaration of %s", shortPosition(x.Ident.Pos()), x.Ident.Name)
+			continue
+		}
+		nodes[x.Ident.Name] = fmt.Sprintf("%T %s", x.DeclNode, shortPosition(x.DeclNode.Pos()))
+	}
+
+	want := map[string]string{
+		"declnodes": "*ast.File declnodes.go:1",
+		"T":         "*ast.GenDecl declnodes.go:3",
+		"Name":      "*ast.Field declnodes.go:4",
+		"Greet":     "*ast.FuncDecl declnodes.go:7",
+		"t":         "*ast.Field declnodes.go:7",
+		"greeting":  "*ast.Field declnodes.go:7",
+		"msg":       "*ast.AssignStmt declnodes.go:8",
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("got decl nodes %v, want %v", nodes, want)
+	}
+}
+
+func TestSymb_captured(t *testing.T) {
+	pkg, err := parseTestPkg("closures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var captured []string
+	for _, x := range collectSymbs("closures", pkg) {
+		if x.Captured {
+			captured = append(captured, fmt.Sprintf("%s %s", shortPosition(x.Ident.Pos()), x.Ident.Name))
+		}
+	}
+	want := []string{
+		"closures.go:10 i",
+		"closures.go:27 x",
+		"closures.go:29 x",
+		"closures.go:29 y",
+	}
+	if !reflect.DeepEqual(captured, want) {
+		t.Errorf("got captured %v, want %v", captured, want)
+	}
+}
+
+func TestSymb_funcLits(t *testing.T) {
+	pkg, err := parseTestPkg("funclits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lits []string
+	for _, x := range collectSymbs("funclits", pkg) {
+		if x.Anonymous {
+			if !x.IsDecl() || !x.Local {
+				t.Errorf("%s: got IsDecl %v, Local %v, want both true", x.Ident.Name, x.IsDecl(), x.Local)
+			}
+			if _, ok := x.ExprType.(*types.Signature); !ok {
+				t.Errorf("%s: got ExprType %v, want a signature", x.Ident.Name, x.ExprType)
+			}
+			lits = append(lits, fmt.Sprintf("%s %s in %s", shortPosition(x.Ident.Pos()), x.Ident.Name, x.ContainerName()))
+		}
+	}
+	want := []string{
+		"funclits.go:5 glob.func1 in ",
+		"funclits.go:12 Upper.func1 in Upper",
+		"funclits.go:21 T.Twice.func1 in Twice",
+		"funclits.go:22 T.Twice.func1.1 in T.Twice.func1",
+		"funclits.go:25 T.Twice.func2 in Twice",
+	}
+	if !reflect.DeepEqual(lits, want) {
+		t.Errorf("got function literals %v, want %v", lits, want)
+	}
+}
+
+func TestSymb_selectionForms(t *testing.T) {
+	pkg, err := parseTestPkg("methodrefs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var forms []string
+	for _, x := range collectSymbs("methodrefs", pkg) {
+		if x.Selection != nil {
+			sig, ok := x.ExprType.(*types.Signature)
+			if !ok {
+				t.Errorf("%s: got ExprType %v, want a signature", pretty(x.Expr), x.ExprType)
+				continue
+			}
+			forms = append(forms, fmt.Sprintf("%s %s %s params=%d", shortPosition(x.Ident.Pos()), pretty(x.Expr), x.Form, sig.Params().Len()))
+		}
+	}
+	want := []string{
+		"methodrefs.go:10 buf.WriteString MethodValue params=1",
+		"methodrefs.go:11 (*bytes.Buffer).WriteString MethodExpr params=2",
+		"methodrefs.go:12 w.WriteString MethodValue params=1",
+		"methodrefs.go:13 buf.WriteString OrdinarySelection params=1",
+		"methodrefs.go:14 buf.WriteString OrdinarySelection params=1",
+	}
+	if !reflect.DeepEqual(forms, want) {
+		t.Errorf("got selections %v, want %v", forms, want)
+	}
+}
+
+func TestSymb_selectorChains(t *testing.T) {
+	c := NewContext()
+	c.Debug = true
+	file, err := c.ParseFile(filepath.Join("testdata", "src", "chains", "chains.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refs []string
+	err = c.IterateSymbs("chains", []*ast.File{file}, func(x *Symb) bool {
+		if line := c.FileSet.Position(x.Ident.Pos()).Line; line == 14 || line == 18 {
+			refs = append(refs, fmt.Sprintf("%s %s", pretty(x.Expr), x.ReferObj))
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"a var a chains.A",
+		"a.B field B chains.B",
+		"a.B.C field C *chains.C",
+		"a.B.C.D field D chains.D",
+		"a.B.C.D.Get func (chains.D).Get() int",
+		"a var a *chains.A",
+		"a.B field B chains.B",
+		"a.B.C field C *chains.C",
+		"a.B.C.D field D chains.D",
+		"a.B.C.D.n field n int",
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got chain refs %v, want %v", refs, want)
+	}
+	for _, e := range c.Timeline() {
+		if e.Warning != nil {
+			t.Errorf("got warning %q at %s", e.Warning.Msg, c.FileSet.Position(e.Warning.Pos))
+		}
+	}
+}
+
+func TestSymb_selectionRecv(t *testing.T) {
+	pkg, err := parseTestPkg("receivers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sels []string
+	for _, x := range collectSymbs("receivers", pkg) {
+		if x.Selection != nil && fset.Position(x.Ident.Pos()).Line >= 15 {
+			sels = append(sels, fmt.Sprintf("%s %s %s indirect=%v", shortPosition(x.Ident.Pos()), pretty(x.Expr), x.Recv, x.Indirect))
+		}
+	}
+	want := []string{
+		"receivers.go:15 v.Name receivers.Person indirect=false",
+		"receivers.go:16 p.Name receivers.Person indirect=true",
+		"receivers.go:17 v.Rename receivers.Person indirect=false",
+		"receivers.go:18 p.Rename receivers.Person indirect=true",
+		"receivers.go:19 p.ID receivers.Base indirect=true",
+		"receivers.go:20 v.Key receivers.Base indirect=false",
+		"receivers.go:21 m[\"x\"].Name receivers.Person indirect=true",
+	}
+	if !reflect.DeepEqual(sels, want) {
+		t.Errorf("got selections %v, want %v", sels, want)
+	}
+}
+
+func TestSymb_embeddedFields(t *testing.T) {
+	pkg, err := parseTestPkg("embedfields")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sels []string
+	for _, x := range collectSymbs("embedfields", pkg) {
+		if x.Selection == nil {
+			continue
+		}
+		anonymous := false
+		if v, isVar := x.ReferObj.(*types.Var); isVar {
+			anonymous = v.Anonymous()
+		}
+		sels = append(sels, fmt.Sprintf("%s %s %s anonymous=%v index=%v recv=%s", shortPosition(x.Ident.Pos()), pretty(x.Expr), x.ReferObj, anonymous, x.Selection.Index(), x.Recv))
+	}
+	// Selecting an embedded field refers to the field, not to its type,
+	// and does not count as a hop on the way to a promoted member.
+	want := []string{
+		"embedfields.go:9 b.N field N int anonymous=false index=[0] recv=embedfields.Base",
+		"embedfields.go:18 w.Base field Base embedfields.Base anonymous=true index=[0] recv=embedfields.Wrapper",
+		"embedfields.go:19 w.N field N int anonymous=false index=[0 0] recv=embedfields.Base",
+		"embedfields.go:20 w.Base field Base embedfields.Base anonymous=true index=[0] recv=embedfields.Wrapper",
+		"embedfields.go:20 w.Base.N field N int anonymous=false index=[0] recv=embedfields.Base",
+		"embedfields.go:21 w.Base field Base embedfields.Base anonymous=true index=[0] recv=embedfields.Wrapper",
+		"embedfields.go:22 w.Inc func (*embedfields.Base).Inc() anonymous=false index=[0 0] recv=embedfields.Base",
+		"embedfields.go:23 w.Buffer field Buffer bytes.Buffer anonymous=true index=[1] recv=embedfields.Wrapper",
+	}
+	if !reflect.DeepEqual(sels, want) {
+		t.Errorf("got selections\n%s\nwant\n%s", strings.Join(sels, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSymb_compositeLits(t *testing.T) {
+	pkg, err := parseTestPkg("complits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refs []string
+	for _, x := range collectSymbs("complits", pkg) {
+		if line := fset.Position(x.Ident.Pos()).Line; line >= 15 && !x.IsDecl() {
+			ref := fmt.Sprintf("%s %s", shortPosition(x.Ident.Pos()), x.ReferObj)
+			if x.Implicit {
+				ref += " (implicit)"
+			}
+			refs = append(refs, ref)
+		}
+	}
+	want := []string{
+		"complits.go:15 type complits.Foo struct{Bar int; Baz string}",
+		"complits.go:15 type complits.Foo struct{Bar int; Baz string} (implicit)",
+		"complits.go:15 field Bar int",
+		"complits.go:15 type complits.Foo struct{Bar int; Baz string} (implicit)",
+		"complits.go:15 field Bar int (implicit)",
+		"complits.go:15 field Baz string (implicit)",
+		"complits.go:16 type string",
+		"complits.go:16 type complits.Foo struct{Bar int; Baz string}",
+		"complits.go:16 var complits.key string",
+		"complits.go:16 type complits.Foo struct{Bar int; Baz string} (implicit)",
+		"complits.go:16 field Bar int",
+		"complits.go:17 type complits.Pair struct{A complits.Foo; B complits.Foo}",
+		"complits.go:17 type complits.Pair struct{A complits.Foo; B complits.Foo} (implicit)",
+		"complits.go:17 field A complits.Foo",
+		"complits.go:17 type complits.Foo struct{Bar int; Baz string}",
+		"complits.go:17 field Bar int",
+		"complits.go:17 field B complits.Foo",
+		"complits.go:17 type complits.Foo struct{Bar int; Baz string}",
+		"complits.go:17 field Baz string",
+		"complits.go:17 type complits.Pair struct{A complits.Foo; B complits.Foo} (implicit)",
+		"complits.go:17 field A complits.Foo (implicit)",
+		"complits.go:17 type complits.Foo struct{Bar int; Baz string}",
+		"complits.go:17 field B complits.Foo (implicit)",
+		"complits.go:17 type complits.Foo struct{Bar int; Baz string}",
+		"complits.go:17 field Bar int (implicit)",
+		"complits.go:17 field Baz string (implicit)",
+		"complits.go:18 type int",
+		"complits.go:19 type complits.Foo struct{Bar int; Baz string}",
+		"complits.go:19 type complits.Foo struct{Bar int; Baz string} (implicit)",
+		"complits.go:19 field Bar int",
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got refs\n%s\nwant\n%s", strings.Join(refs, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSymb_conversions(t *testing.T) {
+	pkg, err := parseTestPkg("conversions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var convs []string
+	for _, x := range collectSymbs("conversions", pkg) {
+		if line := fset.Position(x.Ident.Pos()).Line; line < 10 || x.Ident.Name == "f" || x.Ident.Name == "s" {
+			continue
+		}
+		conv := fmt.Sprintf("%s %s", shortPosition(x.Ident.Pos()), pretty(x.Expr))
+		if x.IsConversion {
+			conv += fmt.Sprintf(" converts to %s", x.ExprType)
+		}
+		convs = append(convs, conv)
+	}
+	want := []string{
+		"conversions.go:10 Celsius converts to conversions.Celsius",
+		"conversions.go:11 time",
+		"conversions.go:11 time.Duration converts to time.Duration",
+		"conversions.go:12 byte",
+		"conversions.go:13 Celsius converts to conversions.Celsius",
+		"conversions.go:14 half",
+	}
+	if !reflect.DeepEqual(convs, want) {
+		t.Errorf("got symbs %v, want %v", convs, want)
+	}
+}
+
+func TestSymb_callContext(t *testing.T) {
+	pkg, err := parseTestPkg("callctx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	for _, x := range collectSymbs("callctx", pkg) {
+		if x.CallContext != NoCall {
+			calls = append(calls, fmt.Sprintf("%s %s %s", shortPosition(x.Ident.Pos()), pretty(x.Expr), x.CallContext))
+		}
+	}
+	want := []string{
+		"callctx.go:8 f.Close DeferCall",
+		"callctx.go:9 worker GoCall",
+		"callctx.go:10 run.func1 DeferCall",
+		"callctx.go:11 worker PlainCall",
+		"callctx.go:13 worker DeferCall",
+		"callctx.go:13 make PlainCall",
+		"callctx.go:14 f.Sync GoCall",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+}
+
+func TestSymb_methodDecls(t *testing.T) {
+	pkg, err := parseTestPkg("methods")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var symbs []string
+	for _, x := range collectSymbs("methods", pkg) {
+		if fset.Position(x.Ident.Pos()).Line < 5 {
+			continue
+		}
+		symb := fmt.Sprintf("%s %s in %s", shortPosition(x.Ident.Pos()), pretty(x.Expr), x.ContainerName())
+		if x.IsDecl() {
+			symb += " decl"
+		}
+		if x.IsRecv {
+			symb += " recv"
+		}
+		if x.Recv != nil {
+			symb += " of " + x.Recv.String()
+		}
+		symbs = append(symbs, symb)
+	}
+	want := []string{
+		"methods.go:5 s in Addr decl recv",
+		"methods.go:5 Server in ",
+		"methods.go:5 Server.Addr in Server decl of methods.Server",
+		"methods.go:5 string in ",
+		"methods.go:5 s in Addr recv",
+		"methods.go:5 s.addr in Server of methods.Server",
+		"methods.go:7 s in Run decl recv",
+		"methods.go:7 Server in ",
+		"methods.go:7 (*Server).Run in Server decl of methods.Server",
+		"methods.go:7 s in Run recv",
+		"methods.go:7 s.addr in Server of methods.Server",
+		"methods.go:9 Server in ",
+		"methods.go:9 (*Server).Stop in Server decl of methods.Server",
+		"methods.go:11 Server in ",
+		"methods.go:11 Server.Name in Server decl of methods.Server",
+		"methods.go:11 string in ",
+	}
+	if !reflect.DeepEqual(symbs, want) {
+		t.Errorf("got symbs\n%s\nwant\n%s", strings.Join(symbs, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSymb_embeddedInterfaces(t *testing.T) {
+	pkg, err := parseTestPkg("ifaces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var symbs []string
+	for _, x := range collectSymbs("ifaces", pkg) {
+		if fset.Position(x.Ident.Pos()).Line < 9 {
+			continue
+		}
+		symb := fmt.Sprintf("%s %s %s in %s", shortPosition(x.Ident.Pos()), pretty(x.Expr), x.QualifiedName(), x.ContainerName())
+		if x.IsDecl() {
+			symb += " decl"
+		}
+		if x.Embedded {
+			symb += " embedded"
+		}
+		symbs = append(symbs, symb)
+	}
+	want := []string{
+		"ifaces.go:9 ReadCloser ifaces.ReadCloser in  decl",
+		"ifaces.go:10 io io in ",
+		"ifaces.go:10 io.Reader io.Reader in  embedded",
+		"ifaces.go:11 Closer ifaces.Closer in  embedded",
+		"ifaces.go:12 Reset ifaces.ReadCloser.Reset in ReadCloser decl",
+		"ifaces.go:13 Len ifaces.ReadCloser.Len in ReadCloser decl",
+		"ifaces.go:13 int int in ",
+	}
+	if !reflect.DeepEqual(symbs, want) {
+		t.Errorf("got symbs\n%s\nwant\n%s", strings.Join(symbs, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSymb_assertedTypes(t *testing.T) {
+	pkg, err := parseTestPkg("asserts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refs []string
+	for _, x := range collectSymbs("asserts", pkg) {
+		if _, isType := x.ReferObj.(*types.TypeName); isType && fset.Position(x.Ident.Pos()).Line >= 10 {
+			refs = append(refs, fmt.Sprintf("%s %s %s", shortPosition(x.Ident.Pos()), x.QualifiedName(), x.ExprType))
+		}
+	}
+	want := []string{
+		"asserts.go:10 io.Reader io.Reader",
+		"asserts.go:11 asserts.Foo *asserts.Foo",
+		"asserts.go:14 asserts.Bar []asserts.Bar",
+		"asserts.go:18 asserts.Foo *asserts.Foo",
+		"asserts.go:18 asserts.Bar []*asserts.Bar",
+		"asserts.go:18 io.Writer io.Writer",
+		"asserts.go:20 asserts.Foo asserts.Foo",
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got type refs %v, want %v", refs, want)
+	}
+}
+
+func TestSymb_declForms(t *testing.T) {
+	pkg, err := parseTestPkg("declforms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var forms []string
+	for _, x := range collectSymbs("declforms", pkg) {
+		if x.DeclForm != NoDeclForm {
+			forms = append(forms, fmt.Sprintf("%s %s %s", shortPosition(x.Ident.Pos()), x.Ident.Name, x.DeclForm))
+		}
+	}
+	want := []string{
+		"declforms.go:5 global VarKeyword",
+		"declforms.go:7 s Param",
+		"declforms.go:7 n Param",
+		"declforms.go:7 err Param",
+		"declforms.go:8 a ShortDecl",
+		"declforms.go:8 err ShortDeclReuse",
+		"declforms.go:12 b ShortDecl",
+		"declforms.go:12 err ShortDecl",
+		"declforms.go:13 a Assign",
+		"declforms.go:14 c ShortDecl",
+		"declforms.go:14 err ShortDecl",
+		"declforms.go:15 a Assign",
+		"declforms.go:15 err Assign",
+		"declforms.go:17 total VarKeyword",
+		"declforms.go:18 i Range",
+		"declforms.go:18 r Range",
+		"declforms.go:19 total Assign",
+		"declforms.go:21 global Assign",
+		"declforms.go:23 v TypeSwitch",
+		"declforms.go:25 n Assign",
+	}
+	if !reflect.DeepEqual(forms, want) {
+		t.Errorf("got decl forms\n%s\nwant\n%s", strings.Join(forms, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSymb_unusualSyntax(t *testing.T) {
+	// Neither walk may panic, and every symb must have an Ident.
+	for _, pkgPath := range []string{"parens", "grabbag"} {
+		pkg, err := parseTestPkg(pkgPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		symbs := collectSymbs(pkgPath, pkg)
+		if len(symbs) == 0 {
+			t.Errorf("%s: got no symbs", pkgPath)
+		}
+		for _, x := range symbs {
+			if x.Ident == nil {
+				t.Errorf("%s: got symb with no Ident for %s", pkgPath, pretty(x.Expr))
+			}
+		}
+	}
+}
+
+func TestVisitExpr_noIdent(t *testing.T) {
+	c := NewContext()
+	var logged string
+	c.Logf = func(pos token.Pos, f string, a ...interface{}) {
+		logged = fmt.Sprintf(f, a...)
+	}
+	e := &ast.ParenExpr{X: ast.NewIdent("x")}
+	if !c.visitExpr(e, false, func(*Symb) bool {
+		t.Error("got a symb for an expression with no identifier")
+		return true
+	}) {
+		t.Error("visitExpr stopped the iteration")
+	}
+	if want := "no identifier in (x)"; logged != want {
+		t.Errorf("got diagnostic %q, want %q", logged, want)
+	}
+}
+
+func TestSymb_emitImportedDecls(t *testing.T) {
+	pkg, err := parseTestPkg("imported")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, transitive := range []bool{false, true} {
+		c := NewContext()
+		c.FileSet = fset
+		c.EmitImportedDecls = true
+		c.TransitiveImportedDecls = transitive
+		var ref, decl *Symb
+		declPkgs := make(map[string]bool)
+		err = c.IterateSymbsPkg("imported", pkg, func(symb *Symb) bool {
+			if symb.Synthetic {
+				if symb.Ident != nil || symb.Expr != nil || !symb.IsDecl() {
+					t.Errorf("got synthetic symb %+v, want a decl without syntax", symb)
+				}
+				if start, end := symb.Span(); start != symb.ReferPos || end != symb.ReferPos {
+					t.Errorf("got Span %d-%d of synthetic symb %+v, want its ReferPos %d", start, end, symb, symb.ReferPos)
+				}
+				if start, end := symb.ExprSpan(); start != symb.ReferPos || end != symb.ReferPos {
+					t.Errorf("got ExprSpan %d-%d of synthetic symb %+v, want its ReferPos %d", start, end, symb, symb.ReferPos)
+				}
+				declPkgs[symb.Pkg.Path()] = true
+				if symb.Pkg.Path() == "fmt" && symb.ReferObj.Name() == "Println" {
+					decl = symb
+				}
+			} else if symb.Ident.Name == "Println" {
+				ref = symb
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ref == nil || decl == nil {
+			t.Fatalf("transitive=%v: got Println ref %v and decl %v, want both", transitive, ref, decl)
+		}
+		if ref.ReferObj != decl.ReferObj {
+			t.Errorf("transitive=%v: ref and synthetic decl refer to different objects", transitive)
+		}
+		if declPkgs["io"] != transitive {
+			t.Errorf("transitive=%v: got synthetic decls in packages %v", transitive, declPkgs)
+		}
+		if _, err := json.Marshal(decl); err != nil {
+			t.Errorf("transitive=%v: marshal synthetic decl: %s", transitive, err)
+		}
+	}
+}
+
+func TestSymb_checkCache(t *testing.T) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	declOfA := func() types.Object {
+		var obj types.Object
+		err := c.IterateSymbsPkg("foo", pkg, func(symb *Symb) bool {
+			if symb.Ident.Name == "A" && symb.IsDecl() {
+				obj = symb.ReferObj
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+
+	first := declOfA()
+	if first == nil {
+		t.Fatal("no decl of A")
+	}
+	if second := declOfA(); second != first {
+		t.Errorf("got a new object for A when iterating over the same files again, want the cached one")
+	}
+	c.Invalidate("foo")
+	if third := declOfA(); third == first || third == nil {
+		t.Errorf("got object %v for A after Invalidate, want a newly checked one", third)
+	}
+}
+
+func benchmarkIterateSymbs(b *testing.B, invalidate, reuse bool) {
+	pkg, err := parseTestPkg("foo")
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	c.ReuseSymbs = reuse
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if invalidate {
+			c.Invalidate("foo")
+		}
+		if err := c.IterateSymbsPkg("foo", pkg, func(*Symb) bool { return true }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIterateSymbs(b *testing.B)              { benchmarkIterateSymbs(b, true, false) }
+func BenchmarkIterateSymbs_cached(b *testing.B)       { benchmarkIterateSymbs(b, false, false) }
+func BenchmarkIterateSymbs_cachedReused(b *testing.B) { benchmarkIterateSymbs(b, false, true) }
+
+func TestContext_ReuseSymbs(t *testing.T) {
+	for _, pkgPath := range testPkgPaths {
+		pkg, err := parseTestPkg(pkgPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewContext()
+		c.FileSet = fset
+		var want, got []Symb
+		c.IterateSymbsPkg(pkgPath, pkg, func(x *Symb) bool {
+			want = append(want, *x)
+			return true
+		})
+		c.ReuseSymbs = true
+		var prev *Symb
+		c.IterateSymbsPkg(pkgPath, pkg, func(x *Symb) bool {
+			if prev != nil && x != prev {
+				t.Errorf("%s: got a new *Symb for %s, want the reused one", pkgPath, x.Ident.Name)
+			}
+			prev = x
+			got = append(got, *x)
+			return true
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got symbs %v with ReuseSymbs, want %v", pkgPath, pp(got), pp(want))
+		}
+	}
+}
+
+func TestIterateDecls(t *testing.T) {
+	for _, pkgPath := range testPkgPaths {
+		pkg, err := parseTestPkg(pkgPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewContext()
+		c.FileSet = fset
+		var want, got []Symb
+		err = c.IterateSymbsPkg(pkgPath, pkg, func(x *Symb) bool {
+			if x.IsDecl() {
+				want = append(want, *x)
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.IterateDecls(pkgPath, pkgFiles(pkg), func(x *Symb) bool {
+			got = append(got, *x)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got decls %v, want %v", pkgPath, pp(got), pp(want))
+		}
+	}
+}
+
+// benchmarkDecls collects the declarations of a file with many references
+// per declaration, using IterateDecls or filtering the output of
+// IterateSymbs.
+func benchmarkDecls(b *testing.B, iterateDecls bool) {
+	src := "package refs\n\nvar a, b int\n\nfunc f() int {\n"
+	for i := 0; i < 1000; i++ {
+		src += "\ta, b = b+a, a*b-a\n"
+	}
+	src += "\treturn a + b\n}\n"
+	c := NewContext()
+	file, err := parser.ParseFile(c.FileSet, "refs.go", src, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	files := []*ast.File{file}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var n int
+		if iterateDecls {
+			err = c.IterateDecls("refs", files, func(*Symb) bool { n++; return true })
+		} else {
+			err = c.IterateSymbs("refs", files, func(x *Symb) bool {
+				if x.IsDecl() {
+					n++
+				}
+				return true
+			})
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		if n != 4 {
+			b.Fatalf("got %d decls, want 4", n)
+		}
+	}
+}
+
+func BenchmarkIterateDecls(b *testing.B)          { benchmarkDecls(b, true) }
+func BenchmarkIterateDecls_filtered(b *testing.B) { benchmarkDecls(b, false) }
+
 func goFilesOnly(file os.FileInfo) bool {
 	return file.Mode().IsRegular() && path.Ext(file.Name()) == ".go"
 }
@@ -62,7 +1020,7 @@ func checkOutput(srcFilename string, symbs []Symb, t *testing.T) {
 	expectedFilename := srcFilename + "_expected.json"
 
 	// write actual output
-	writeJson(actualFilename, symbsToJson(symbs))
+	writeJson(actualFilename, symbs)
 
 	// diff
 	cmd := exec.Command("diff", "-u", expectedFilename, actualFilename)
@@ -97,15 +1055,16 @@ func writeJson(filename string, v interface{}) {
 func collectSymbs(importPath string, pkg *ast.Package) (symbs []Symb) {
 	c := NewContext()
 	c.FileSet = fset
+	c.BaseDir = build.Default.GOPATH
 	c.Logf = func(pos token.Pos, f string, a ...interface{}) {
 		if !verbose {
 			return
 		}
-		log.Printf("%v: %s", c.position(pos), fmt.Sprintf(f, a...))
+		log.Printf("%v: %s", c.Position(pos), fmt.Sprintf(f, a...))
 	}
 
 	symbs = make([]Symb, 0)
-	err := c.IterateSymbs(importPath, sortedFiles(pkg.Files), func(symb *Symb) bool {
+	err := c.IterateSymbsPkg(importPath, pkg, func(symb *Symb) bool {
 		symbs = append(symbs, *symb)
 		return true
 	})
@@ -115,10 +1074,6 @@ func collectSymbs(importPath string, pkg *ast.Package) (symbs []Symb) {
 	return symbs
 }
 
-func (ctxt *Context) position(pos token.Pos) token.Position {
-	return ctxt.FileSet.Position(pos)
-}
-
 func pp(symbs []Symb) string {
 	s := "["
 	for i, x := range symbs {
@@ -130,120 +1085,6 @@ func pp(symbs []Symb) string {
 	return s + "]"
 }
 
-func symbsToJson(symbs []Symb) []interface{} {
-	js := make([]interface{}, 0)
-	for _, x := range symbs {
-		var exprType string
-		if x.ExprType != nil {
-			exprType = x.ExprType.String()
-		}
-		j := struct {
-			Expr     string
-			Ident    string
-			IdentPos interface{}
-			ExprType string
-			Pkg      interface{}
-			FileName string
-			ReferPos token.Position
-			ReferObj interface{}
-			Local    bool
-			Universe bool
-			IsDecl   bool
-		}{
-			Expr:     pretty(x.Expr),
-			Ident:    pretty(x.Ident),
-			IdentPos: relativePosition(fset.Position(x.Ident.Pos())),
-			ExprType: exprType,
-			Pkg:      typePackageToJson(x.Pkg),
-			FileName: x.File.Name.Name,
-			ReferPos: relativePosition(fset.Position(x.ReferPos)),
-			ReferObj: typeObjectToJson(&x.ReferObj),
-			Local:    x.Local,
-			Universe: x.Universe,
-			IsDecl:   x.IsDecl(),
-		}
-		js = append(js, j)
-	}
-	return js
-}
-
-func relativePosition(p token.Position) token.Position {
-	cwd, _ := os.Getwd()
-	p.Filename, _ = filepath.Rel(cwd, p.Filename)
-	return p
-}
-
-func typePackageToJson(p *types.Package) interface{} {
-	if p == nil {
-		return nil
-	} else {
-		return struct {
-			Isa, Name, ImportPath string
-		}{
-			"Package", p.Name(), p.Path(),
-		}
-	}
-}
-
-func typeTypeToJson(t types.Type) interface{} {
-	if t != nil {
-		return t.String()
-	} else {
-		return nil
-	}
-}
-
-func typeObjectToJson(o *types.Object) interface{} {
-	switch o := (*o).(type) {
-	case *types.Package:
-		return typePackageToJson(o)
-	case *types.Const:
-		return struct {
-			Isa  string
-			Pkg  interface{}
-			Name string
-			Type interface{}
-			Val  interface{}
-		}{
-			"Const", typePackageToJson(o.Pkg()), o.Name(), typeTypeToJson(o.Type()), o.Val(),
-		}
-	case *types.TypeName:
-		return struct {
-			Isa  string
-			Pkg  interface{}
-			Name string
-			Type interface{}
-		}{
-			"TypeName", typePackageToJson(o.Pkg()), o.Name(), typeTypeToJson(o.Type()),
-		}
-	case *types.Var:
-		return struct {
-			Isa  string
-			Pkg  interface{}
-			Name string
-			Type interface{}
-		}{
-			"Var", typePackageToJson(o.Pkg()), o.Name(), typeTypeToJson(o.Type()),
-		}
-	case *types.Func:
-		return struct {
-			Isa  string
-			Pkg  interface{}
-			Name string
-			Type interface{}
-		}{
-			"Func", typePackageToJson(o.Pkg()), o.Name(), typeTypeToJson(o.Type()),
-		}
-	default:
-		if o != nil {
-			return nil
-		} else {
-			return "UNKNOWN"
-		}
-	}
-	return nil
-}
-
 func prettys(symbs []Symb) string {
 	s := "["
 	for i, x := range symbs {
@@ -270,3 +1111,93 @@ func sortedFiles(m map[string]*ast.File) []*ast.File {
 	}
 	return vallist
 }
+
+// parseTestPkg parses the package with the given import path in
+// testdata/src, relative to the current directory, into fset.
+func parseTestPkg(pkgPath string) (*ast.Package, error) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+	pkgs, err := parser.ParseDir(fset, filepath.Join("testdata", "src", pkgPath), goFilesOnly, parser.AllErrors|parser.DeclarationErrors|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range pkgs {
+		return pkg, nil
+	}
+	return nil, fmt.Errorf("no package in %s", pkgPath)
+}
+
+// retainedSink keeps the Context of BenchmarkIterateSymbs_retained alive
+// while its heap is measured.
+var retainedSink *Context
+
+// BenchmarkIterateSymbs_retained iterates over each of the golden test
+// packages with a single Context and reports how much heap remains in use
+// afterwards, while the Context is still reachable.
+func BenchmarkIterateSymbs_retained(b *testing.B) {
+	pkgs := make(map[string]*ast.Package)
+	for _, pkgPath := range testPkgPaths {
+		pkg, err := parseTestPkg(pkgPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pkgs[pkgPath] = pkg
+	}
+
+	var before, after runtime.MemStats
+	var retained uint64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		retainedSink = NewContext()
+		retainedSink.FileSet = fset
+		for _, pkgPath := range testPkgPaths {
+			retainedSink.IterateSymbsPkg(pkgPath, pkgs[pkgPath], func(*Symb) bool { return true })
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		if after.HeapAlloc > before.HeapAlloc {
+			retained += after.HeapAlloc - before.HeapAlloc
+		}
+		retainedSink = nil
+	}
+	b.Logf("%d bytes retained per Context after iterating over %d packages", retained/uint64(b.N), len(testPkgPaths))
+}
+
+func TestSymb_pkgDoc(t *testing.T) {
+	pkg, err := parseTestPkg("pkgdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewContext()
+	c.FileSet = fset
+	const want = "Package pkgdoc is documented in doc.go, which is not its first file.\n\nThe second paragraph.\n"
+	var docs []string
+	err = c.IterateSymbsPkg("pkgdoc", pkg, func(x *Symb) bool {
+		if x.PkgDoc != "" {
+			docs = append(docs, shortPosition(x.Ident.Pos())+" "+x.PkgDoc)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.go:1 " + want}; !reflect.DeepEqual(docs, want) {
+		t.Errorf("got package docs %q, want %q", docs, want)
+	}
+	if got := c.PackageDoc(); got != want {
+		t.Errorf("got PackageDoc %q, want %q", got, want)
+	}
+
+	// Like go/doc, the comments of several documented files are joined.
+	err = c.ParseAndIterate("two", map[string][]byte{
+		"/two/b.go": []byte("// Second.\npackage two\n"),
+		"/two/a.go": []byte("// First.\npackage two\n"),
+	}, func(*Symb) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.PackageDoc(), "First.\n\nSecond.\n"; got != want {
+		t.Errorf("got PackageDoc %q for two documented files, want %q", got, want)
+	}
+}
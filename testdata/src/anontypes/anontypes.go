@@ -0,0 +1,27 @@
+package anontypes
+
+// x has an anonymous struct type.
+var x struct {
+	N int
+}
+
+// Config has a field of a nested anonymous struct type.
+type Config struct {
+	Opts struct {
+		Debug bool
+		Trace struct {
+			Level int
+		}
+	}
+}
+
+// Run takes a parameter of an anonymous interface type.
+func Run(cfg Config, w interface {
+	Write(p []byte) (int, error)
+}) {
+	x.N++
+	if cfg.Opts.Debug {
+		w.Write(nil)
+	}
+	println(cfg.Opts.Trace.Level)
+}
@@ -0,0 +1,65 @@
+package api
+
+import "io"
+
+const (
+	Version = "1.0"
+	build   = 3
+)
+
+const (
+	A Mode = iota
+	B
+	c
+)
+
+var (
+	Default  = New()
+	internal = 1
+	X, y     = 1, 2
+)
+
+// Mode is exported.
+type Mode int
+
+func (m Mode) String() string { return "mode" }
+
+func (m Mode) name() string { return "" }
+
+// Config has exported and unexported fields, and a nested struct.
+type Config struct {
+	Name   string `json:"name"`
+	secret string
+	Limits struct {
+		Max, min int
+	}
+	*Mode
+	io.Reader
+	hidden
+}
+
+type hidden struct {
+	Visible int
+}
+
+func (h hidden) Exported() {}
+
+// Doer has exported and unexported methods and an embedded interface.
+type Doer interface {
+	io.Closer
+	Do(n int) error
+	undo()
+}
+
+func New() *Config {
+	type Local struct{ Field int }
+	var l Local
+	_ = func() int { return l.Field }
+	return &Config{}
+}
+
+func helper() {}
+
+// The body of broken does not type-check, which IterateExportedDecls does
+// not notice.
+func broken() { undefinedInBody() }
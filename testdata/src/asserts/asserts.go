@@ -0,0 +1,24 @@
+package asserts
+
+import "io"
+
+type Foo struct{}
+
+type Bar struct{}
+
+func kinds(x interface{}) int {
+	r, ok := x.(io.Reader)
+	if _, isFoo := x.(*Foo); isFoo && ok && r != nil {
+		return 1
+	}
+	if x.([]Bar) != nil {
+		return 2
+	}
+	switch x.(type) {
+	case *Foo, []*Bar, io.Writer:
+		return 3
+	case nil, Foo:
+		return 4
+	}
+	return 0
+}
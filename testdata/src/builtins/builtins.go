@@ -0,0 +1,12 @@
+package builtins
+
+const Zero = iota
+
+func grow(s []int) []int {
+	return append(s, len(s))
+}
+
+func check(err error) string {
+	defer recover()
+	panic(err.Error())
+}
@@ -0,0 +1,15 @@
+package callctx
+
+import "os"
+
+func worker(ch chan int) {}
+
+func run(f *os.File, ch chan int) {
+	defer f.Close()
+	go worker(ch)
+	defer func() {
+		worker(ch)
+	}()
+	defer (worker)(make(chan int))
+	go f.Sync()
+}
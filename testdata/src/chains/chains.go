@@ -0,0 +1,19 @@
+package chains
+
+type D struct{ n int }
+
+func (d D) Get() int { return d.n }
+
+type C struct{ D D }
+
+type B struct{ C *C }
+
+type A struct{ B B }
+
+func chain(a A) int {
+	return a.B.C.D.Get()
+}
+
+func field(a *A) int {
+	return a.B.C.D.n
+}
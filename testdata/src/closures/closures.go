@@ -0,0 +1,32 @@
+package closures
+
+var total int
+
+func use(int) {}
+
+func captures(n int) {
+	for i := 0; i < n; i++ {
+		go func() {
+			use(i)
+			total++
+		}()
+	}
+}
+
+func shadows(n int) {
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			use(i)
+		}(i)
+	}
+}
+
+func nested() func() func() int {
+	x := 1
+	return func() func() int {
+		y := x
+		return func() int {
+			return x + y
+		}
+	}
+}
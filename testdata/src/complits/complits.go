@@ -0,0 +1,20 @@
+package complits
+
+type Foo struct {
+	Bar int
+	Baz string
+}
+
+type Pair struct {
+	A, B Foo
+}
+
+var key = "a"
+
+var (
+	slice   = []Foo{{Bar: 1}, {2, "b"}}
+	ptrs    = map[string]*Foo{key: {Bar: 3}}
+	nested  = []Pair{{A: Foo{Bar: 4}, B: Foo{Baz: "c"}}, {Foo{}, Foo{5, "d"}}}
+	arrays  = [][2]int{{1, 2}}
+	indexed = [...]Foo{1: {Bar: 6}}
+)
@@ -0,0 +1,11 @@
+package consts
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+const Max = 10
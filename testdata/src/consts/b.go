@@ -0,0 +1,9 @@
+package consts
+
+var limit int = Max
+
+const almost = Max - 1
+
+func favorite() Color {
+	return Green
+}
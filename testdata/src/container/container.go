@@ -0,0 +1,19 @@
+package container
+
+type Config struct {
+	Name    string
+	Options struct {
+		Verbose bool
+	}
+}
+
+func (c *Config) Describe() string {
+	prefix := "config "
+	return prefix + c.Name
+}
+
+var Default Config
+
+var anon struct {
+	Loose int
+}
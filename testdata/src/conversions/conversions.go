@@ -0,0 +1,15 @@
+package conversions
+
+import "time"
+
+type Celsius float64
+
+func half(f float64) float64 { return f / 2 }
+
+func convert(f float64, s string) {
+	_ = Celsius(f)
+	_ = time.Duration(f)
+	_ = []byte(s)
+	_ = (Celsius)(f)
+	_ = half(f)
+}
@@ -0,0 +1,3 @@
+package crossfile
+
+var V T
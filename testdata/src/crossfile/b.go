@@ -0,0 +1,3 @@
+package crossfile
+
+type T int
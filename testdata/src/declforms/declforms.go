@@ -0,0 +1,28 @@
+package declforms
+
+import "strconv"
+
+var global int
+
+func parse(s string) (n int, err error) {
+	a, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if b, err := strconv.Atoi(s); err == nil {
+		a += b
+	} else if c, err := strconv.Atoi(s); err == nil {
+		a, err = c, nil
+	}
+	var total int
+	for i, r := range s {
+		total += i + int(r)
+	}
+	for _, global = range []int{a} {
+	}
+	switch v := interface{}(a).(type) {
+	case int:
+		n = v
+	}
+	return n + total, err
+}
@@ -0,0 +1,10 @@
+package declnodes
+
+type T struct {
+	Name string
+}
+
+func Greet(t T, greeting string) string {
+	msg := greeting + ", " + t.Name
+	return msg
+}
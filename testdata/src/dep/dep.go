@@ -0,0 +1,4 @@
+package dep
+
+// Which is shadowed by the copy of dep vendored in vend.
+func Which() string { return "GOPATH" }
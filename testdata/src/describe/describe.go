@@ -0,0 +1,23 @@
+package describe
+
+// Greeting is said to each visitor.
+const Greeting = "hello"
+
+// A Visitor is someone who visits.
+type Visitor struct {
+	Name string
+}
+
+// Greet returns the greeting for v.
+func (v *Visitor) Greet(punct string) string {
+	return Greeting + ", " + v.Name + punct
+}
+
+func Welcome(names []string) []string {
+	var greetings []string
+	for _, name := range names {
+		visitor := &Visitor{Name: name}
+		greetings = append(greetings, visitor.Greet("!"))
+	}
+	return greetings
+}
@@ -0,0 +1,12 @@
+package diff
+
+type Config struct {
+	Name    string
+	Verbose bool
+}
+
+func (c *Config) Describe() string { return c.Name }
+
+func Load(path string) (*Config, error) { return nil, nil }
+
+func init() {}
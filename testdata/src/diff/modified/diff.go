@@ -0,0 +1,15 @@
+// Package diff is a modified copy of the diff test package, with a field
+// removed, a function added, and a signature changed.
+package diff
+
+type Config struct {
+	Name string
+}
+
+func (c *Config) Describe() string { return c.Name }
+
+func Load(path string, strict bool) (*Config, error) { return nil, nil }
+
+func Save(c *Config, path string) error { return nil }
+
+func init() {}
@@ -0,0 +1,20 @@
+// Package docrefs mentions Config in its documentation.
+package docrefs
+
+import "strings"
+
+// Config holds settings. Use New to make one, and Config.Describe or
+// strings.ToUpper on its Name; the settings are never nil.
+type Config struct {
+	// Name is set by New.
+	Name string
+}
+
+// Describe returns the Name of c, not the name of a local.
+func (c *Config) Describe() string {
+	local := strings.TrimSpace(c.Name)
+	return local
+}
+
+// New returns a Config.
+func New() *Config { return &Config{} }
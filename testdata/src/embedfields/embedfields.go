@@ -0,0 +1,25 @@
+package embedfields
+
+import "bytes"
+
+type Base struct {
+	N int
+}
+
+func (b *Base) Inc() { b.N++ }
+
+type Wrapper struct {
+	Base
+	bytes.Buffer
+	Name string
+}
+
+func use(w *Wrapper) Base {
+	b := w.Base
+	w.N++
+	w.Base.N++
+	w.Base = Base{}
+	w.Inc()
+	_ = w.Buffer
+	return b
+}
@@ -0,0 +1,20 @@
+package enums
+
+type Kind int
+
+const (
+	KindA Kind = iota
+	KindB
+	_
+	KindD
+)
+
+type Flag uint
+
+const (
+	Size  = 4
+	FlagX Flag = 1 << iota
+	FlagY
+	Count = 2
+	Other
+)
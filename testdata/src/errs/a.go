@@ -0,0 +1,5 @@
+package errs
+
+func f() int {
+	return missing + 1
+}
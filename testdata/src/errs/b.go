@@ -0,0 +1,5 @@
+package errs
+
+import . "strings"
+
+var upper = ToUpper("x")
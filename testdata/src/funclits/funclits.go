@@ -0,0 +1,26 @@
+package funclits
+
+import "strings"
+
+var less = func(a, b int) bool { return a < b }
+
+type T struct{}
+
+func (T) apply(f func(int) int) int { return f(1) }
+
+func Upper(s string) string {
+	return strings.Map(func(r rune) rune {
+		if less(int(r), 'a') {
+			return r
+		}
+		return r - 'a' + 'A'
+	}, s)
+}
+
+func (t T) Twice() int {
+	double := func(x int) int {
+		add := func(y int) int { return x + y }
+		return add(x)
+	}
+	return t.apply(double) + t.apply(func(x int) int { return x })
+}
@@ -0,0 +1,3 @@
+package generated
+
+var Handwritten GeneratedType = GeneratedFunc()
@@ -0,0 +1,80 @@
+package grabbag
+
+import (
+	"fmt"
+	_ "unsafe"
+)
+
+type (
+	Point struct{ X, Y int }
+	Op    func(a, b int) int
+	Nest  struct {
+		_     int
+		Inner struct{ A, B []map[string]*Point }
+		Point
+	}
+)
+
+const (
+	_  = iota
+	KB = 1 << (10 * iota)
+	MB
+)
+
+var ops = [...]Op{func(a, b int) int { return a + b }, nil}
+
+func variadic(prefix string, xs ...interface{}) {
+	fmt.Println(append([]interface{}{prefix}, xs...)...)
+}
+
+func control(ch chan int, done <-chan struct{}) (n int) {
+	defer func() { recover() }()
+outer:
+	for i := 0; ; i++ {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				break outer
+			}
+			n += v
+		case <-done:
+			goto end
+		default:
+			continue outer
+		}
+		switch x := i % 3; {
+		case x == 0:
+			fallthrough
+		case x == 1:
+			;
+		}
+	}
+end:
+	return
+}
+
+func literals() interface{} {
+	var nest Nest
+	nest.Inner.A = append(nest.Inner.A, map[string]*Point{"o": {}})
+	arr := [2][]struct{ P *Point }{{{&Point{1, 2}}}, nil}
+	c := complex(1, 2)
+	s := []byte("héllo")[1:3:3]
+	_ = s
+	return struct {
+		Nest
+		arr interface{}
+		c   complex128
+	}{nest, arr, c * complex(real(c), 0)}
+}
+
+func methods() {
+	var p Point
+	f := Point.String
+	g := (*Point).String
+	_, _ = f(p), g(&p)
+	_ = struct{}{}
+	func() {}()
+	_ = (func(int) int)(nil)
+}
+
+func (p Point) String() string { return fmt.Sprint(p.X, p.Y) }
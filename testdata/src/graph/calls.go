@@ -0,0 +1,6 @@
+package graph
+
+func calls() {
+	Helper()
+	_ = Helper() + one
+}
@@ -0,0 +1,7 @@
+package graph
+
+func Helper() int {
+	return 1
+}
+
+var one = Helper()
@@ -0,0 +1,25 @@
+package groups
+
+// Colors.
+const (
+	Red = iota
+	Green
+	Blue
+)
+
+var (
+	x, y = 1, 2
+	z    string
+)
+
+type (
+	Point struct{ X, Y int }
+	Line  [2]Point
+)
+
+var single int
+
+func f() {
+	var a, b = 1, 2
+	_, _ = a, b
+}
@@ -0,0 +1,14 @@
+package ifaces
+
+import "io"
+
+type Closer interface {
+	Close() error
+}
+
+type ReadCloser interface {
+	io.Reader
+	Closer
+	Reset()
+	Len() int
+}
@@ -0,0 +1,39 @@
+package implby
+
+type Store interface {
+	Get(key string) string
+	Put(key, value string)
+}
+
+// MemStore implements Store.
+type MemStore map[string]string
+
+func (s MemStore) Get(key string) string { return s[key] }
+
+func (s MemStore) Put(key, value string) { s[key] = value }
+
+// DiskStore implements Store only through a pointer.
+type DiskStore struct {
+	dir string
+}
+
+func (s DiskStore) Get(key string) string { return "" }
+
+func (s *DiskStore) Put(key, value string) {}
+
+// NearMiss has the methods of Store, but Put has the wrong signature.
+type NearMiss struct{}
+
+func (NearMiss) Get(key string) string { return "" }
+
+func (NearMiss) Put(key string, value int) {}
+
+// CachedStore is also a Store, which is not an implementer.
+type CachedStore interface {
+	Store
+	Flush()
+}
+
+type Any interface{}
+
+var count int
@@ -0,0 +1,7 @@
+package imported
+
+import "fmt"
+
+func hello() {
+	fmt.Println("hello")
+}
@@ -0,0 +1,5 @@
+package other
+
+func New() int {
+	return 0
+}
@@ -0,0 +1,13 @@
+package shapes
+
+type Circle struct {
+	Radius float64
+}
+
+func (c Circle) Area() float64 {
+	return 3 * c.Radius * c.Radius
+}
+
+func New(radius float64) Circle {
+	return Circle{Radius: radius}
+}
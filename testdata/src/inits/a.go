@@ -0,0 +1,7 @@
+package inits
+
+var ready bool
+
+func init() {
+	ready = true
+}
@@ -0,0 +1,14 @@
+// Generated from calc.y; the //line directives point at the grammar.
+
+package linedir
+
+//line calc.y:10
+func Eval(s string) int {
+	return len(s) + base
+}
+
+//line calc.y:20
+var base = 1
+
+//line calc.go:15
+type Op int
@@ -0,0 +1,18 @@
+package methodrefs
+
+import "bytes"
+
+type Writer interface {
+	WriteString(s string) (int, error)
+}
+
+func refs(buf *bytes.Buffer, w Writer) {
+	f := buf.WriteString
+	g := (*bytes.Buffer).WriteString
+	h := w.WriteString
+	buf.WriteString("x")
+	(buf.WriteString)("y")
+	f("a")
+	g(buf, "b")
+	h("c")
+}
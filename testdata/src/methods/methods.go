@@ -0,0 +1,11 @@
+package methods
+
+type Server struct{ addr string }
+
+func (s Server) Addr() string { return s.addr }
+
+func (s *Server) Run() { s.addr = "" }
+
+func (*Server) Stop() {}
+
+func (Server) Name() string { return "server" }
@@ -0,0 +1,37 @@
+package methodset
+
+import "bytes"
+
+type Base struct {
+	name string
+}
+
+func (b Base) Name() string { return b.name }
+
+func (b *Base) SetName(name string) { b.name = name }
+
+type Logger struct{}
+
+func (l *Logger) Log(msg string) {}
+
+// Derived has methods of its own and promoted from Base and, through an
+// embedded pointer, from Logger.
+type Derived struct {
+	Base
+	*Logger
+}
+
+func (d Derived) Describe() string { return d.Name() }
+
+func (d *Derived) Reset() { d.SetName("") }
+
+type Shape interface {
+	Area() float64
+	Perimeter() float64
+}
+
+type Buffered struct {
+	bytes.Buffer
+}
+
+var count int
@@ -0,0 +1,5 @@
+package b
+
+import "monorepo/a/internal/secret"
+
+var Legal = secret.Value()
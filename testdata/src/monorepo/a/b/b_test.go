@@ -0,0 +1,8 @@
+package b_test
+
+import (
+	"monorepo/a/b/internal/detail"
+	"monorepo/a/internal/secret"
+)
+
+var AlsoLegal = secret.Value() + detail.Value()
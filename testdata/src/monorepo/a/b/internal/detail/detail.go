@@ -0,0 +1,3 @@
+package detail
+
+func Value() int { return 2 }
@@ -0,0 +1,5 @@
+package secret
+
+func Value() int {
+	return 42
+}
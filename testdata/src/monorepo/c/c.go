@@ -0,0 +1,5 @@
+package c
+
+import "monorepo/a/internal/secret"
+
+var Illegal = secret.Value()
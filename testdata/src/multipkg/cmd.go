@@ -0,0 +1,6 @@
+package main
+
+// Name is declared in all three packages.
+var Name = "main"
+
+func main() { println(Name) }
@@ -0,0 +1,6 @@
+package multipkg
+
+// Name is declared in all three packages.
+var Name = "lib"
+
+func Greet() string { return "hello, " + Name }
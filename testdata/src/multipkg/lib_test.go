@@ -0,0 +1,8 @@
+package multipkg_test
+
+import "multipkg"
+
+// Name is declared in all three packages.
+var Name = "test"
+
+var greeting = multipkg.Greet() + Name + multipkg.Name
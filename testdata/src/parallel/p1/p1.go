@@ -0,0 +1,23 @@
+package p1
+
+import (
+	"fmt"
+	"strings"
+)
+
+type T1 struct {
+	Name  string
+	Count int
+}
+
+func (t *T1) String() string {
+	return fmt.Sprintf("%s=%d", strings.ToUpper(t.Name), t.Count)
+}
+
+func New1(names ...string) []*T1 {
+	var ts []*T1
+	for i, name := range names {
+		ts = append(ts, &T1{Name: name, Count: i})
+	}
+	return ts
+}
@@ -0,0 +1,23 @@
+package p2
+
+import (
+	"fmt"
+	"strings"
+)
+
+type T2 struct {
+	Name  string
+	Count int
+}
+
+func (t *T2) String() string {
+	return fmt.Sprintf("%s=%d", strings.ToUpper(t.Name), t.Count)
+}
+
+func New2(names ...string) []*T2 {
+	var ts []*T2
+	for i, name := range names {
+		ts = append(ts, &T2{Name: name, Count: i})
+	}
+	return ts
+}
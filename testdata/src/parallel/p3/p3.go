@@ -0,0 +1,23 @@
+package p3
+
+import (
+	"fmt"
+	"strings"
+)
+
+type T3 struct {
+	Name  string
+	Count int
+}
+
+func (t *T3) String() string {
+	return fmt.Sprintf("%s=%d", strings.ToUpper(t.Name), t.Count)
+}
+
+func New3(names ...string) []*T3 {
+	var ts []*T3
+	for i, name := range names {
+		ts = append(ts, &T3{Name: name, Count: i})
+	}
+	return ts
+}
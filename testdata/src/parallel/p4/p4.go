@@ -0,0 +1,23 @@
+package p4
+
+import (
+	"fmt"
+	"strings"
+)
+
+type T4 struct {
+	Name  string
+	Count int
+}
+
+func (t *T4) String() string {
+	return fmt.Sprintf("%s=%d", strings.ToUpper(t.Name), t.Count)
+}
+
+func New4(names ...string) []*T4 {
+	var ts []*T4
+	for i, name := range names {
+		ts = append(ts, &T4{Name: name, Count: i})
+	}
+	return ts
+}
@@ -0,0 +1,23 @@
+package p5
+
+import (
+	"fmt"
+	"strings"
+)
+
+type T5 struct {
+	Name  string
+	Count int
+}
+
+func (t *T5) String() string {
+	return fmt.Sprintf("%s=%d", strings.ToUpper(t.Name), t.Count)
+}
+
+func New5(names ...string) []*T5 {
+	var ts []*T5
+	for i, name := range names {
+		ts = append(ts, &T5{Name: name, Count: i})
+	}
+	return ts
+}
@@ -0,0 +1,23 @@
+package p6
+
+import (
+	"fmt"
+	"strings"
+)
+
+type T6 struct {
+	Name  string
+	Count int
+}
+
+func (t *T6) String() string {
+	return fmt.Sprintf("%s=%d", strings.ToUpper(t.Name), t.Count)
+}
+
+func New6(names ...string) []*T6 {
+	var ts []*T6
+	for i, name := range names {
+		ts = append(ts, &T6{Name: name, Count: i})
+	}
+	return ts
+}
@@ -0,0 +1,15 @@
+package parens
+
+type T struct{ f (int) }
+
+func (t (*T)) m() (int) { return ((t).f) }
+
+var (
+	v     = ((T{}))
+	p     = (&(v))
+	x     = ((*p).f) + (((p)).m)()
+	g     = ((*T).m)
+	fn    (func() (int))
+	ch    = (make)((chan (int)), (1))
+	slice = ([]int)((nil))
+)
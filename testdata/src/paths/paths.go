@@ -0,0 +1,14 @@
+package paths
+
+type T struct {
+	Ready bool
+}
+
+func check(ok bool) bool { return ok }
+
+func run(t *T) int {
+	if check(t.Ready) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,4 @@
+package pkgdoc
+
+// A is the first file by name, but has no package comment.
+func A() {}
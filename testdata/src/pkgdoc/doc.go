@@ -0,0 +1,4 @@
+// Package pkgdoc is documented in doc.go, which is not its first file.
+//
+// The second paragraph.
+package pkgdoc
@@ -0,0 +1,6 @@
+// A comment on z.go that is separated from its package clause is not
+// package documentation.
+
+package pkgdoc
+
+func Z() {}
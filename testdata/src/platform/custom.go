@@ -0,0 +1,5 @@
+// +build custom
+
+package platform
+
+var Custom = true
@@ -0,0 +1,3 @@
+package platform
+
+var Current = Name()
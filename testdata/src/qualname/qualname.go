@@ -0,0 +1,30 @@
+package qualname
+
+import "strings"
+
+var Global = strings.ToUpper("x")
+
+type Shape interface {
+	Perimeter() float64
+}
+
+type Rect struct {
+	Width  float64
+	Corner struct {
+		X float64
+	}
+}
+
+func (r *Rect) Area() float64 {
+	area := r.Width * r.Corner.X
+	return area
+}
+
+func NewRect(width float64) *Rect {
+	rect := &Rect{Width: width}
+	return rect
+}
+
+func Count(s string) int {
+	return len(s)
+}
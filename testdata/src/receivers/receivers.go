@@ -0,0 +1,22 @@
+package receivers
+
+type Base struct{ ID int }
+
+func (b Base) Key() int { return b.ID }
+
+type Person struct {
+	Base
+	Name string
+}
+
+func (p *Person) Rename(name string) { p.Name = name }
+
+func use(v Person, p *Person, m map[string]*Person) {
+	_ = v.Name
+	_ = p.Name
+	v.Rename("v")
+	p.Rename("p")
+	_ = p.ID
+	_ = v.Key()
+	_ = m["x"].Name
+}
@@ -0,0 +1,9 @@
+package redecl
+
+// Dup is declared again in b.go, as if by two generators.
+func Dup() int {
+	n := len("a")
+	return n
+}
+
+func A() int { return Dup() }
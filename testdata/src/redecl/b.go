@@ -0,0 +1,8 @@
+package redecl
+
+func Dup() int {
+	m := cap([]int{})
+	return m
+}
+
+func B() int { return Dup() + A() }
@@ -0,0 +1,25 @@
+package refcount
+
+// helper is called three times: twice by Use and once by itself.
+func helper(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return helper(n - 1)
+}
+
+func Use() int {
+	return helper(1) + helper(2)
+}
+
+type (
+	// List refers to itself; Ring refers to List.
+	List struct {
+		Next *List
+	}
+	Ring struct {
+		Head List
+	}
+)
+
+func unused() {}
@@ -0,0 +1,7 @@
+package refs
+
+var Counter int
+
+func incr() {
+	Counter++
+}
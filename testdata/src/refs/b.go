@@ -0,0 +1,10 @@
+package refs
+
+func get() int {
+	local := Counter
+	return local + Counter
+}
+
+func size() int {
+	return len("refs")
+}
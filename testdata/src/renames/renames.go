@@ -0,0 +1,19 @@
+package renames
+
+import "strings"
+
+type T struct {
+	A int
+	B int
+}
+
+func (t T) M() int { return t.A }
+
+func (t *T) N() {}
+
+var X = strings.ToUpper("x")
+
+func use(t T) int {
+	local := t.B
+	return local + t.M()
+}
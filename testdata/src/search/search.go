@@ -0,0 +1,19 @@
+package search
+
+type Context struct {
+	ctx int
+}
+
+func NewContext() *Context { return &Context{} }
+
+func newContextCache() map[string]*Context { return nil }
+
+func (c *Context) Next() {}
+
+type Config struct{}
+
+func NewConfig() Config { return Config{} }
+
+func CountTokens() int { return nextToken }
+
+var nextToken int
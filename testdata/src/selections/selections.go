@@ -0,0 +1,22 @@
+package selections
+
+type Base struct {
+	ID int `json:"id"`
+}
+
+func (b *Base) Describe() string { return "base" }
+
+type Derived struct {
+	*Base `json:"base,omitempty"`
+	Name  string `json:"name"`
+	A, B  int    `json:"-"`
+}
+
+func use(d Derived, v interface{}) int {
+	d.Describe()
+	switch x := v.(type) {
+	case int:
+		return x + d.ID
+	}
+	return len(d.Name)
+}
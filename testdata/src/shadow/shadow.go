@@ -0,0 +1,61 @@
+package shadow
+
+func f() (int, error) { return 0, nil }
+
+func g() error { return nil }
+
+func nested() error {
+	n, err := f()
+	if n > 0 {
+		err := g()
+		if err != nil {
+			return err
+		}
+	}
+	for i := 0; i < n; i++ {
+		n := i
+		_ = n
+	}
+	return err
+}
+
+func siblings() {
+	if true {
+		x := 1
+		_ = x
+	}
+	if true {
+		x := 2
+		_ = x
+	}
+}
+
+func reuse() error {
+	a, err := f()
+	b, err := f()
+	_, _ = a, b
+	return err
+}
+
+func universe(s []int) int {
+	len := 3
+	return len + cap(s)
+}
+
+func ranges(s []int) {
+	for _, s := range s {
+		_ = s
+	}
+}
+
+func typeSwitch(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return v
+	}
+	return nil
+}
+
+type error2 error
+
+var new = 1
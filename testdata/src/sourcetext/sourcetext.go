@@ -0,0 +1,31 @@
+package sourcetext
+
+// Level is a logging level.
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn // warnings
+)
+
+var Default = Config{
+	Name: "default",
+}
+
+// Config configures a Logger.
+type Config struct {
+	// Name is the name of the logger.
+	Name  string `json:"name"`
+	Level Level  // minimum level logged
+}
+
+// Log logs msg at the given level.
+func (c *Config) Log(level Level,
+	msg string) bool {
+	enabled := level >= c.Level
+	if enabled {
+		println(c.Name, msg)
+	}
+	return enabled
+}
@@ -0,0 +1,9 @@
+package span
+
+type Maß struct {
+	Größe int
+}
+
+var m Maß
+
+var n = m.Größe
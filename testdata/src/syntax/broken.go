@@ -0,0 +1,12 @@
+package syntax
+
+func Before() int { return 1 }
+
+type T struct {
+	N int
+}
+
+func Broken(t T) {
+	if t.N > 0 {
+		Before()
+}
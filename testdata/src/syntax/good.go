@@ -0,0 +1,3 @@
+package syntax
+
+func Good() int { return Before() }
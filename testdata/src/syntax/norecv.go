@@ -0,0 +1,5 @@
+package syntax
+
+func () NoRecv() {}
+
+var After int
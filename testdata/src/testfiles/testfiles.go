@@ -0,0 +1,9 @@
+package testfiles
+
+func Public() int {
+	return helper()
+}
+
+func helper() int {
+	return 1
+}
@@ -0,0 +1,5 @@
+package testfiles
+
+func checkHelper() bool {
+	return helper() == Public()
+}
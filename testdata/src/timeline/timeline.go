@@ -0,0 +1,7 @@
+package timeline
+
+var before = 1
+
+var x = undefined
+
+var after = 2
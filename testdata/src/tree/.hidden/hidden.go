@@ -0,0 +1,3 @@
+package hidden
+
+var InHiddenDir int
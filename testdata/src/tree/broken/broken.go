@@ -0,0 +1,7 @@
+package broken
+
+func Broken() int {
+	return undefined
+}
+
+func Fine() {}
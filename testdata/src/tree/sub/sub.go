@@ -0,0 +1,7 @@
+package sub
+
+var Leaf = "leaf"
+
+func Use() string {
+	return Leaf
+}
@@ -0,0 +1,3 @@
+package skipped
+
+var InTestdata int
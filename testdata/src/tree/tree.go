@@ -0,0 +1,7 @@
+package tree
+
+type Root int
+
+func NewRoot() Root {
+	return Root(1)
+}
@@ -0,0 +1,19 @@
+package typeexprs
+
+type Config struct {
+	Name string
+}
+
+type T int
+
+func (T) M() {}
+
+var (
+	c  = &Config{Name: "x"}
+	cs = []*Config{&Config{}, {}}
+	p  = (*T)(nil)
+	pp = ((*T))(nil)
+	f  = (func())(nil)
+	ch = (chan T)(nil)
+	m  = (*T).M
+)
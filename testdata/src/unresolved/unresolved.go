@@ -0,0 +1,9 @@
+package unresolved
+
+var counts = map[string]int{"a": 1}
+
+func f() int {
+	_ = Missing
+	n := undefinedFunc(counts["a"])
+	return n + counts["a"]
+}
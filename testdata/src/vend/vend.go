@@ -0,0 +1,5 @@
+package vend
+
+import "dep"
+
+var which, only = dep.Which(), dep.OnlyVendored
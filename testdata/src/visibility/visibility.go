@@ -0,0 +1,53 @@
+package visibility
+
+import "fmt"
+
+// Sealed is an exported interface that only this package can implement.
+type Sealed interface {
+	sealed()
+}
+
+// Stringer is satisfied by the unexported type impl.
+type Stringer interface {
+	String() string
+}
+
+type Public struct {
+	Field   int
+	private int
+}
+
+func (Public) Method() {}
+
+type impl struct {
+	Name  string
+	count int
+}
+
+func (i impl) String() string { return i.Name }
+
+func (impl) sealed() {}
+
+var Loose struct {
+	Inner int
+}
+
+var loose struct {
+	Hidden int
+}
+
+var shared, Shared struct {
+	Both int
+}
+
+// New returns an impl, making its exported members reachable from other
+// packages.
+func New() Stringer {
+	type local struct {
+		Exported int
+	}
+	l := local{Exported: 1}
+	return impl{Name: fmt.Sprint(l.Exported)}
+}
+
+var helper = len("x")
@@ -0,0 +1,3 @@
+package withtests
+
+var ExportedForTest = unexported
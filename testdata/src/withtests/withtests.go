@@ -0,0 +1,9 @@
+package withtests
+
+func Exported() int {
+	return unexported()
+}
+
+func unexported() int {
+	return 1
+}
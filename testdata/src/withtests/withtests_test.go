@@ -0,0 +1,8 @@
+package withtests_test
+
+import "withtests"
+
+var (
+	a = withtests.Exported()
+	b = withtests.ExportedForTest()
+)
@@ -0,0 +1,28 @@
+package lib
+
+// Count returns the number of strings in s.
+func Count(s []string) int {
+	return len(s)
+}
+
+// CountAll returns the number of strings in all of ss.
+func CountAll(ss ...[]string) int {
+	n := 0
+	for _, s := range ss {
+		n += Count(s)
+	}
+	return n
+}
+
+type Counter struct {
+	n int
+}
+
+func (c *Counter) Count() int {
+	c.n++
+	return c.n
+}
+
+func init() {}
+
+func init() {}
@@ -0,0 +1,11 @@
+package use
+
+import "xref/lib"
+
+func Total(a, b []string) int {
+	return lib.Count(a) + lib.Count(b)
+}
+
+func Next(c *lib.Counter) int {
+	return c.Count()
+}
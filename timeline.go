@@ -0,0 +1,38 @@
+package symb
+
+import (
+	"go/token"
+)
+
+// A Warning is a problem encountered while iterating over symbs, such as an
+// identifier that could not be resolved.
+type Warning struct {
+	Seq   int         // sequence number in the Context's timeline
+	Kind  WarningKind // kind of problem
+	Level Level       // severity with which it was logged
+	Pos   token.Pos   // position the warning refers to
+	Msg   string
+}
+
+// WarningKind classifies Warnings.
+type WarningKind int
+
+const (
+	MiscWarning WarningKind = iota // a warning of no particular kind
+	StaleFile                      // a file changed on disk after it was parsed
+)
+
+// An Event is an entry in a Context's timeline: either a symb that was
+// emitted or a warning that was raised.
+type Event struct {
+	Seq     int      // sequence number, increasing in emission order
+	Symb    *Symb    // the emitted symb, or nil
+	Warning *Warning // the raised warning, or nil
+}
+
+// Timeline returns the symbs emitted and warnings raised by ctxt, in the
+// order in which they occurred. Events are only recorded while ctxt.Debug
+// is set.
+func (ctxt *Context) Timeline() []Event {
+	return ctxt.timeline
+}
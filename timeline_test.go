@@ -0,0 +1,59 @@
+package symb
+
+import (
+	"go/ast"
+	"go/parser"
+	"path/filepath"
+	"testing"
+)
+
+func TestTimeline(t *testing.T) {
+	c := NewContext()
+	c.Debug = true
+	file, err := parser.ParseFile(c.FileSet, filepath.Join("testdata", "src", "timeline", "timeline.go"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var symbs []*Symb
+	c.IterateSymbs("timeline", []*ast.File{file}, func(symb *Symb) bool {
+		symbs = append(symbs, symb)
+		return true
+	})
+
+	events := c.Timeline()
+	for i, e := range events {
+		if i > 0 && e.Seq <= events[i-1].Seq {
+			t.Errorf("event %d has seq %d, not greater than previous seq %d", i, e.Seq, events[i-1].Seq)
+		}
+		if (e.Symb == nil) == (e.Warning == nil) {
+			t.Errorf("event %d must have exactly one of Symb and Warning", i)
+		}
+	}
+	if len(events) != len(symbs)+1 {
+		t.Fatalf("got %d events for %d symbs, want exactly one warning", len(events), len(symbs))
+	}
+
+	var w int
+	for w = range events {
+		if events[w].Warning != nil {
+			break
+		}
+	}
+	if w == 0 || w == len(events)-1 {
+		t.Fatalf("warning is not between two symbs: %v", events)
+	}
+	if name := events[w-1].Symb.Ident.Name; name != "x" {
+		t.Errorf("got symb %q before the warning, want x", name)
+	}
+	if name := events[w+1].Symb.Ident.Name; name != "after" {
+		t.Errorf("got symb %q after the warning, want after", name)
+	}
+	if pos := c.FileSet.Position(events[w].Warning.Pos); pos.Line != 5 {
+		t.Errorf("got warning at %s, want line 5", pos)
+	}
+	for _, symb := range symbs {
+		if symb.Seq == 0 {
+			t.Errorf("symb %s has no seq", symb.Ident.Name)
+		}
+	}
+}
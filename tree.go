@@ -0,0 +1,386 @@
+package symb
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PackageError records an error encountered while analyzing one package
+// of a multi-package iteration.
+type PackageError struct {
+	ImportPath string
+	Err        error
+}
+
+func (e *PackageError) Error() string {
+	return e.ImportPath + ": " + e.Err.Error()
+}
+
+// PackageErrors is the list of per-package errors returned by IterateTree,
+// sorted by import path.
+type PackageErrors []*PackageError
+
+func (e PackageErrors) Error() string {
+	switch len(e) {
+	case 0:
+		return "no errors"
+	case 1:
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e[0], len(e)-1)
+}
+
+// IterateTree calls visitf for each symb in each package in the directory
+// tree rooted at root. Directories named testdata and directories whose
+// names begin with "." or "_" are skipped. _test.go files are skipped
+// unless ctxt.IncludeTests is set, in which case each package is checked
+// together with its in-package tests and its external test package (if
+// any) is visited separately, immediately afterwards, with the import path
+// of the package plus "_test".
+//
+// Packages are visited in order of import path, but may be parsed and
+// type-checked concurrently (see ctxt.Parallelism). A package that fails to
+// parse or type-check does not stop the iteration; its error is recorded
+// and returned in a PackageErrors once all packages have been visited. If
+// visitf returns false, the iteration stops.
+func (ctxt *Context) IterateTree(root string, visitf func(pkgPath string, symb *Symb) bool) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	dirs, err := ctxt.packageDirs(root)
+	if err != nil {
+		return err
+	}
+
+	pkgPaths := make([]string, 0, len(dirs))
+	dirsByPkgPath := make(map[string]string, len(dirs))
+	for _, dir := range dirs {
+		pkgPath := importPathForDir(dir)
+		pkgPaths = append(pkgPaths, pkgPath)
+		dirsByPkgPath[pkgPath] = dir
+	}
+	sort.Strings(pkgPaths)
+
+	var errs PackageErrors
+	i := 0
+	next := func() ([]*checkJob, bool) {
+		pkgPath := pkgPaths[i]
+		i++
+		return ctxt.dirJobs(pkgPath, dirsByPkgPath[pkgPath]), i < len(pkgPaths)
+	}
+	walk := func(job *checkJob) bool {
+		ok := true
+		err := ctxt.iterate(job, func(symb *Symb) bool {
+			ok = visitf(job.importPath, symb)
+			return ok
+		})
+		if err != nil {
+			errs = append(errs, &PackageError{job.importPath, err})
+		}
+		return ok
+	}
+	if len(pkgPaths) > 0 {
+		ctxt.checkPipeline(next, walk)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// IteratePackageDir calls visitf for each symb in the package in dir. The
+// files analyzed are those returned by SelectFiles. If ctxt.IncludeTests is
+// set, the external test package in dir (if any) is visited after the
+// package itself. Files with syntax errors are checked and walked as far
+// as they could be parsed (see ParseFile), and the errors are reported as
+// SyntaxErrors. Errors are returned as PackageErrors.
+func (ctxt *Context) IteratePackageDir(dir string, visitf func(symb *Symb) bool) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	_, errs := ctxt.iterateDir(importPathForDir(dir), dir, func(_ string, symb *Symb) bool {
+		return visitf(symb)
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// IterateDirAllPackages calls visitf for each symb in each package in dir,
+// with the name of the package. Unlike IteratePackageDir, it accepts
+// directories that hold several packages, such as a command and a library,
+// or (if ctxt.IncludeTests is set) a package and its external test
+// package. The files analyzed are those returned by SelectFiles, grouped
+// by package name.
+//
+// Each package is checked and walked on its own, in order of name. An
+// external test package named x_test is checked against package x, if dir
+// holds it, and has x's import path plus "_test". Of the other packages,
+// one that is alone in dir, or is the only one there other than main, has
+// the import path of dir; the rest have it followed by their name in
+// parentheses, as in "cmd/foo (main)". Errors are returned as
+// PackageErrors.
+func (ctxt *Context) IterateDirAllPackages(dir string, visitf func(pkgName string, symb *Symb) bool) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	filenames, err := ctxt.SelectFiles(dir)
+	if err != nil {
+		return err
+	}
+	filesByName := make(map[string][]*ast.File)
+	syntaxErrs := make(map[string][]Error)
+	var names []string
+	for _, filename := range filenames {
+		file, err := ctxt.ParseFile(filename)
+		if file == nil {
+			return PackageErrors{{importPathForDir(dir), err}}
+		}
+		name := file.Name.Name
+		if err != nil {
+			syntaxErrs[name] = append(syntaxErrs[name], ctxt.syntaxErrors(err)...)
+		}
+		if filesByName[name] == nil {
+			names = append(names, name)
+		}
+		filesByName[name] = append(filesByName[name], file)
+	}
+	sort.Strings(names)
+
+	// The packages other than external test packages need distinct import
+	// paths, but one that external tests import must keep that of dir.
+	var libs, nonXTests int
+	for _, name := range names {
+		if !isXTest(name, filesByName) {
+			nonXTests++
+			if name != "main" {
+				libs++
+			}
+		}
+	}
+	importPath := func(name string) string {
+		path := importPathForDir(dir)
+		if nonXTests > 1 && (name == "main" || libs > 1) {
+			path = fmt.Sprintf("%s (%s)", path, name)
+		}
+		return path
+	}
+
+	// Jobs for external test packages come after the packages they test
+	// in name order, so their dependencies are always checked first.
+	jobs := make(map[string]*checkJob, len(names))
+	var errs PackageErrors
+	for _, name := range names {
+		job := &checkJob{importPath: importPath(name), files: ctxt.sortFiles(filesByName[name]), syntaxErrs: syntaxErrs[name]}
+		if strings.HasSuffix(name, "_test") {
+			if tested := jobs[strings.TrimSuffix(name, "_test")]; tested != nil {
+				job.importPath = tested.importPath + "_test"
+				job.deps = []*checkJob{tested}
+			}
+		}
+		jobs[name] = job
+
+		ok := true
+		err := ctxt.iterate(job, func(symb *Symb) bool {
+			ok = visitf(name, symb)
+			return ok
+		})
+		if err != nil {
+			errs = append(errs, &PackageError{job.importPath, err})
+		}
+		if !ok {
+			break
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// isXTest reports whether the package with the given name is the external
+// test package of another of the packages in filesByName.
+func isXTest(name string, filesByName map[string][]*ast.File) bool {
+	return strings.HasSuffix(name, "_test") && filesByName[strings.TrimSuffix(name, "_test")] != nil
+}
+
+// IterateImportPath calls visitf for each symb in the package with the
+// given import path, which is located using the Context's build
+// configuration. It is otherwise like IteratePackageDir.
+func (ctxt *Context) IterateImportPath(importPath string, visitf func(symb *Symb) bool) error {
+	pkg, err := ctxt.buildContext().Import(importPath, "", build.FindOnly)
+	if err != nil {
+		return err
+	}
+	_, errs := ctxt.iterateDir(importPath, pkg.Dir, func(_ string, symb *Symb) bool {
+		return visitf(symb)
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// iterateDir calls visitf for each symb in the package in dir, which has
+// the given import path, and in its external test package if
+// ctxt.IncludeTests is set. It returns false if visitf stopped the
+// iteration.
+func (ctxt *Context) iterateDir(importPath, dir string, visitf func(pkgPath string, symb *Symb) bool) (ok bool, errs PackageErrors) {
+	ok = true
+	for _, job := range ctxt.dirJobs(importPath, dir) {
+		err := ctxt.iterate(job, func(symb *Symb) bool {
+			ok = visitf(job.importPath, symb)
+			return ok
+		})
+		if err != nil {
+			errs = append(errs, &PackageError{job.importPath, err})
+		}
+		if !ok {
+			break
+		}
+	}
+	return ok, errs
+}
+
+// dirJobs parses the package in dir, which has the given import path, and
+// returns the jobs for checking it and (if ctxt.IncludeTests is set) its
+// external test package. The external tests are checked against the
+// package including its in-package tests, rather than against the
+// installed package. If the package cannot be parsed at all, the returned
+// job records the error.
+func (ctxt *Context) dirJobs(importPath, dir string) []*checkJob {
+	files, xtestFiles, syntaxErrs, err := ctxt.parseDir(dir)
+	if err != nil {
+		return []*checkJob{{importPath: importPath, err: err}}
+	}
+	if len(files) == 0 && len(xtestFiles) == 0 {
+		// Build constraints exclude all files in dir.
+		return nil
+	}
+
+	job := &checkJob{importPath: importPath, files: ctxt.sortFiles(files), syntaxErrs: syntaxErrs[false]}
+	jobs := []*checkJob{job}
+	if len(xtestFiles) > 0 {
+		jobs = append(jobs, &checkJob{
+			importPath: importPath + "_test",
+			files:      ctxt.sortFiles(xtestFiles),
+			deps:       []*checkJob{job},
+			syntaxErrs: syntaxErrs[true],
+		})
+	}
+	return jobs
+}
+
+// packageDirs returns the directories under root (including root itself)
+// that contain Go source files.
+func (ctxt *Context) packageDirs(root string) (dirs []string, err error) {
+	seen := make(map[string]bool)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && skipDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ctxt.isSourceFile(info) {
+			if dir := filepath.Dir(path); !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// skipDir reports whether a directory with the given name should be
+// excluded from a tree walk, following the conventions of the go tool.
+func skipDir(name string) bool {
+	return name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// isSourceFile reports whether file is a Go source file that should be
+// analyzed. Test files are only analyzed if ctxt.IncludeTests is set.
+func (ctxt *Context) isSourceFile(file os.FileInfo) bool {
+	name := file.Name()
+	if !file.Mode().IsRegular() || filepath.Ext(name) != ".go" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+		return false
+	}
+	return ctxt.IncludeTests || !isTestFilename(name)
+}
+
+// isTestFilename reports whether name is the name of a Go test file.
+func isTestFilename(name string) bool {
+	return strings.HasSuffix(name, "_test.go")
+}
+
+// parseDir parses the Go source files in dir selected by SelectFiles into
+// the Context's FileSet. It returns the files of the package (including
+// in-package tests if ctxt.IncludeTests is set) and the files of the
+// external test package separately, each sorted by filename. Files with
+// syntax errors are returned as far as they could be parsed, and the
+// errors are returned in syntaxErrs, keyed by whether they are in files of
+// the external test package.
+func (ctxt *Context) parseDir(dir string) (files, xtestFiles []*ast.File, syntaxErrs map[bool][]Error, err error) {
+	filenames, err := ctxt.SelectFiles(dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var pkgName, pkgFilename string
+	for _, filename := range filenames {
+		file, err := ctxt.ParseFile(filename)
+		if file == nil {
+			return nil, nil, nil, err
+		}
+
+		name := file.Name.Name
+		xtest := isTestFilename(filename) && strings.HasSuffix(name, "_test")
+		if xtest {
+			xtestFiles = append(xtestFiles, file)
+			name = strings.TrimSuffix(name, "_test")
+		} else {
+			files = append(files, file)
+		}
+		if err != nil {
+			if syntaxErrs == nil {
+				syntaxErrs = make(map[bool][]Error)
+			}
+			syntaxErrs[xtest] = append(syntaxErrs[xtest], ctxt.syntaxErrors(err)...)
+		}
+		if pkgName == "" {
+			pkgName, pkgFilename = name, filepath.Base(filename)
+		} else if name != pkgName {
+			return nil, nil, nil, fmt.Errorf("found packages %s (%s) and %s (%s) in %s", pkgName, pkgFilename, file.Name.Name, filepath.Base(filename), dir)
+		}
+	}
+	return files, xtestFiles, syntaxErrs, nil
+}
+
+// importPathForDir returns the import path of the package in dir, which
+// must be an absolute path. The source directories of the build context
+// are searched in the order the go tool uses: GOROOT, then each element of
+// GOPATH in turn. If dir is not inside any of them, the slash-separated
+// dir itself is returned.
+func importPathForDir(dir string) string {
+	for _, src := range build.Default.SrcDirs() {
+		rel, err := filepath.Rel(src, dir)
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(dir)
+}
@@ -0,0 +1,367 @@
+package symb
+
+import (
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIterateTree(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+
+	c := NewContext()
+	var pkgPaths []string
+	declsByPkg := make(map[string][]string)
+	err := c.IterateTree(filepath.Join(build.Default.GOPATH, "src", "tree"), func(pkgPath string, symb *Symb) bool {
+		if len(pkgPaths) == 0 || pkgPaths[len(pkgPaths)-1] != pkgPath {
+			pkgPaths = append(pkgPaths, pkgPath)
+		}
+		if symb.IsDecl() {
+			declsByPkg[pkgPath] = append(declsByPkg[pkgPath], symb.Ident.Name)
+		}
+		return true
+	})
+
+	wantPkgPaths := []string{"tree", "tree/broken", "tree/sub"}
+	if !reflect.DeepEqual(pkgPaths, wantPkgPaths) {
+		t.Errorf("got packages %v, want %v", pkgPaths, wantPkgPaths)
+	}
+
+	wantDecls := map[string][]string{
+		"tree":        {"tree", "Root", "NewRoot"},
+		"tree/broken": {"broken", "Broken", "Fine"},
+		"tree/sub":    {"sub", "Leaf", "Use"},
+	}
+	if !reflect.DeepEqual(declsByPkg, wantDecls) {
+		t.Errorf("got decls %v, want %v", declsByPkg, wantDecls)
+	}
+
+	errs, ok := err.(PackageErrors)
+	if !ok {
+		t.Fatalf("got error %v, want PackageErrors", err)
+	}
+	if len(errs) != 1 || errs[0].ImportPath != "tree/broken" {
+		t.Errorf("got errors %v, want exactly one error for tree/broken", errs)
+	}
+}
+
+func TestIterateTree_stop(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+
+	c := NewContext()
+	n := 0
+	c.IterateTree(filepath.Join(build.Default.GOPATH, "src", "tree"), func(pkgPath string, symb *Symb) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("got %d symbs after stopping, want 1", n)
+	}
+}
+
+func TestIterateTree_includeTests(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+	root := filepath.Join(build.Default.GOPATH, "src", "withtests")
+
+	type symbInfo struct {
+		pkgPath string
+		variant Variant
+		file    string
+	}
+	symbsByName := func(includeTests bool) map[string][]symbInfo {
+		c := NewContext()
+		c.IncludeTests = includeTests
+		symbs := make(map[string][]symbInfo)
+		err := c.IterateTree(root, func(pkgPath string, symb *Symb) bool {
+			if symb.ReferObj == nil {
+				t.Errorf("%s: unresolved symb %s", c.FileSet.Position(symb.Ident.Pos()), symb.Ident.Name)
+			}
+			symbs[symb.Ident.Name] = append(symbs[symb.Ident.Name], symbInfo{
+				pkgPath: pkgPath,
+				variant: symb.Variant,
+				file:    filepath.Base(c.FileSet.Position(symb.Ident.Pos()).Filename),
+			})
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return symbs
+	}
+
+	symbs := symbsByName(false)
+	if n := len(symbs["ExportedForTest"]); n != 0 {
+		t.Errorf("got %d ExportedForTest symbs without IncludeTests, want 0", n)
+	}
+	for _, info := range symbs["Exported"] {
+		if info.variant != PkgVariant {
+			t.Errorf("got variant %d for Exported in %s, want PkgVariant", info.variant, info.file)
+		}
+	}
+
+	symbs = symbsByName(true)
+	want := []symbInfo{
+		{"withtests", TestVariant, "export_test.go"},
+		{"withtests_test", XTestVariant, "withtests_test.go"},
+	}
+	if got := symbs["ExportedForTest"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got ExportedForTest symbs %v, want %v", got, want)
+	}
+	want = []symbInfo{
+		{"withtests", TestVariant, "withtests.go"},
+		{"withtests_test", XTestVariant, "withtests_test.go"},
+	}
+	if got := symbs["Exported"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got Exported symbs %v, want %v", got, want)
+	}
+}
+
+func TestIteratePackageDir_testFiles(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+	dir := filepath.Join(build.Default.GOPATH, "src", "testfiles")
+
+	for _, includeTests := range []bool{false, true} {
+		c := NewContext()
+		c.IncludeTests = includeTests
+		var decls, refs []*Symb
+		err := c.IteratePackageDir(dir, func(symb *Symb) bool {
+			if symb.Ident.Name != "helper" && symb.Ident.Name != "checkHelper" {
+				return true
+			}
+			if wantTest := filepath.Base(c.Filename(symb.File)) == "testfiles_test.go"; symb.FromTestFile != wantTest {
+				t.Errorf("%s: got FromTestFile %v for %s, want %v", c.FileSet.Position(symb.Ident.Pos()), symb.FromTestFile, symb.Ident.Name, wantTest)
+			}
+			if symb.Ident.Name == "helper" {
+				if symb.IsDecl() {
+					decls = append(decls, symb)
+				} else {
+					refs = append(refs, symb)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(decls) != 1 {
+			t.Fatalf("IncludeTests %v: got %d declarations of helper, want 1", includeTests, len(decls))
+		}
+		wantRefs := 1
+		if includeTests {
+			wantRefs = 2
+		}
+		if len(refs) != wantRefs {
+			t.Fatalf("IncludeTests %v: got %d references to helper, want %d", includeTests, len(refs), wantRefs)
+		}
+		for _, ref := range refs {
+			if ref.ReferObj != decls[0].ReferObj {
+				t.Errorf("%s: reference to helper has object %v, want the declaration's %v", c.FileSet.Position(ref.Ident.Pos()), ref.ReferObj, decls[0].ReferObj)
+			}
+		}
+		if includeTests && !refs[1].FromTestFile {
+			t.Errorf("got the second reference to helper from a non-test file, want the test file")
+		}
+	}
+}
+
+func TestIterateTree_parallel(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+	root := filepath.Join(build.Default.GOPATH, "src", "parallel")
+
+	symbs := func(parallelism int) []string {
+		c := NewContext()
+		c.Parallelism = parallelism
+		var symbs []string
+		err := c.IterateTree(root, func(pkgPath string, symb *Symb) bool {
+			symbs = append(symbs, fmt.Sprintf("%s %s %s", pkgPath, c.FileSet.Position(symb.Ident.Pos()), symb.QualifiedName()))
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return symbs
+	}
+
+	serial := symbs(1)
+	if len(serial) == 0 {
+		t.Fatal("no symbs")
+	}
+	for _, parallelism := range []int{2, 6, 16} {
+		if got := symbs(parallelism); !reflect.DeepEqual(got, serial) {
+			t.Errorf("Parallelism %d: got symbs\n%v\nwant serial order\n%v", parallelism, got, serial)
+		}
+	}
+}
+
+func TestIterateTree_parallelStop(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+
+	c := NewContext()
+	c.Parallelism = 3
+	var pkgPaths []string
+	c.IterateTree(filepath.Join(build.Default.GOPATH, "src", "parallel"), func(pkgPath string, symb *Symb) bool {
+		pkgPaths = append(pkgPaths, pkgPath)
+		return pkgPath != "parallel/p2"
+	})
+	if last := pkgPaths[len(pkgPaths)-1]; last != "parallel/p2" {
+		t.Errorf("got symbs up to package %s after stopping in parallel/p2", last)
+	}
+}
+
+func benchmarkIterateTree(b *testing.B, parallelism int) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+	root := filepath.Join(build.Default.GOPATH, "src", "parallel")
+	for i := 0; i < b.N; i++ {
+		c := NewContext()
+		c.Parallelism = parallelism
+		if err := c.IterateTree(root, func(string, *Symb) bool { return true }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIterateTree_serial(b *testing.B)   { benchmarkIterateTree(b, 1) }
+func BenchmarkIterateTree_parallel(b *testing.B) { benchmarkIterateTree(b, 0) }
+
+func TestIterateDirAllPackages(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+
+	c := NewContext()
+	c.IncludeTests = true
+	var pkgNames []string
+	refs := make(map[string][]string)
+	err := c.IterateDirAllPackages(filepath.Join(build.Default.GOPATH, "src", "multipkg"), func(pkgName string, symb *Symb) bool {
+		if len(pkgNames) == 0 || pkgNames[len(pkgNames)-1] != pkgName {
+			pkgNames = append(pkgNames, pkgName)
+		}
+		if symb.Ident.Name == "Name" && !symb.IsDecl() {
+			pos := c.FileSet.Position(symb.Ident.Pos())
+			ref := fmt.Sprintf("%s:%d %s", filepath.Base(pos.Filename), pos.Line, symb.ReferObj.Pkg().Path())
+			refs[pkgName] = append(refs[pkgName], ref)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"main", "multipkg", "multipkg_test"}; !reflect.DeepEqual(pkgNames, want) {
+		t.Errorf("got packages %v, want %v", pkgNames, want)
+	}
+	// Each reference to Name resolves to the declaration in its own
+	// package, except the qualified one in the external tests.
+	want := map[string][]string{
+		"main":          {"cmd.go:6 multipkg (main)"},
+		"multipkg":      {"lib.go:6 multipkg"},
+		"multipkg_test": {"lib_test.go:8 multipkg_test", "lib_test.go:8 multipkg"},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got refs to Name %v, want %v", refs, want)
+	}
+}
+
+func TestIteratePackageDir_syntaxErrors(t *testing.T) {
+	dir, _ := filepath.Abs("testdata/src/syntax")
+
+	c := NewContext()
+	var decls []string
+	err := c.IteratePackageDir(dir, func(symb *Symb) bool {
+		if symb.IsDecl() {
+			decls = append(decls, symb.Ident.Name)
+			if symb.Ident.Name == "After" && symb.Local {
+				t.Errorf("got After local, want it package-level after the method without a receiver")
+			}
+		}
+		return true
+	})
+
+	// The declarations before and after the syntax errors in broken.go
+	// are still emitted, along with those of the well-formed good.go and
+	// those after the method without a receiver in norecv.go.
+	for _, name := range []string{"Before", "T", "N", "Broken", "t", "Good", "After"} {
+		found := false
+		for _, d := range decls {
+			if d == name {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("got decls %v, want one of %s", decls, name)
+		}
+	}
+
+	errs := AsErrors(err)
+	var nsyntax int
+	for _, e := range errs {
+		if e.Kind == SyntaxError {
+			nsyntax++
+		}
+		if name := filepath.Base(c.FileSet.Position(e.Pos).Filename); name != "broken.go" && name != "norecv.go" {
+			t.Errorf("got error %v outside broken.go and norecv.go", e)
+		}
+	}
+	if nsyntax == 0 {
+		t.Errorf("got errors %v, want SyntaxErrors", errs)
+	}
+}
+
+func TestIterateImportPath_gopathList(t *testing.T) {
+	first, err := ioutil.TempDir("", "gopath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(first)
+	if err := os.MkdirAll(filepath.Join(first, "src", "other"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	second, _ := filepath.Abs("testdata/")
+	defer func(gopath string) { build.Default.GOPATH = gopath }(build.Default.GOPATH)
+	build.Default.GOPATH = first + string(filepath.ListSeparator) + second
+
+	for dir, want := range map[string]string{
+		filepath.Join(first, "src", "other"): "other",
+		filepath.Join(second, "src", "foo"):  "foo",
+		filepath.Join(first, "elsewhere"):    filepath.ToSlash(filepath.Join(first, "elsewhere")),
+	} {
+		if got := importPathForDir(dir); got != want {
+			t.Errorf("got import path %q for %s, want %q", got, dir, want)
+		}
+	}
+
+	// bar, in the second GOPATH element, imports foo, also there.
+	c := NewContext()
+	var refs []string
+	err = c.IterateImportPath("bar", func(symb *Symb) bool {
+		if !symb.IsDecl() && symb.ReferObj != nil && symb.ReferObj.Pkg() != nil && symb.ReferObj.Pkg().Path() == "foo" {
+			refs = append(refs, symb.Ident.Name)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("got references to foo %v, want %v", refs, want)
+	}
+
+	// A package that is in no root is reported with the directories
+	// searched.
+	err = c.ParseAndIterate("lost", map[string][]byte{
+		filepath.Join(first, "src", "lost", "lost.go"): []byte("package lost\n\nimport \"nowhere\"\n\nvar _ = nowhere.X\n"),
+	}, func(*Symb) bool { return true })
+	errs := AsErrors(err)
+	if len(errs) == 0 {
+		t.Fatal("got no error for an import that cannot be found")
+	}
+	for _, root := range []string{first, second} {
+		if !strings.Contains(errs[0].Msg, filepath.Join(root, "src", "nowhere")) {
+			t.Errorf("got error %q, want it to list %s", errs[0].Msg, filepath.Join(root, "src", "nowhere"))
+		}
+	}
+}
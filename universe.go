@@ -0,0 +1,40 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+)
+
+// isUniverse reports whether obj is declared in the universe scope or is
+// a method of a universe type (such as error's Error method).
+func isUniverse(obj types.Object) bool {
+	return types.Universe.Lookup(obj.Name()) == obj || universeOwner(obj) != nil
+}
+
+// isBuiltin reports whether obj is a builtin function, such as len or
+// append, as opposed to a universe type or constant.
+func isBuiltin(obj types.Object) bool {
+	_, isBuiltin := obj.(*types.Builtin)
+	return isBuiltin
+}
+
+// universeOwner returns the universe type that obj is a method of, or nil
+// if there is none.
+func universeOwner(obj types.Object) *types.TypeName {
+	if _, isFunc := obj.(*types.Func); !isFunc || obj.Pkg() != nil {
+		return nil
+	}
+	for _, name := range types.Universe.Names() {
+		tn, ok := types.Universe.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if iface, ok := tn.Type().Underlying().(*types.Interface); ok {
+			for j := 0; j < iface.NumMethods(); j++ {
+				if iface.Method(j) == obj {
+					return tn
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+	"go/build"
+	"path/filepath"
+)
+
+// srcDir returns the absolute directory of the first of files, from which
+// the imports of their package are resolved, or "" if it is unknown.
+func (ctxt *Context) srcDir(files []*ast.File) string {
+	if len(files) == 0 {
+		return ""
+	}
+	dir, err := filepath.Abs(filepath.Dir(ctxt.Filename(files[0])))
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
+// vendoredPath returns the import path that an import of path from a
+// package in srcDir resolves to under the vendor rule of the go tool: the
+// first of srcDir/vendor/path, the vendor/path directories of the parents
+// of srcDir up to the root of its GOPATH element, and then GOROOT and
+// GOPATH as usual. The path of a vendored copy includes the vendor
+// directory, as in "a/vendor/path". Paths that do not resolve are returned
+// unchanged, and reported by the importer.
+func (ctxt *Context) vendoredPath(path, srcDir string) string {
+	if srcDir == "" || build.IsLocalImport(path) || path == "unsafe" || path == "C" {
+		return path
+	}
+	pkg, err := ctxt.buildContext().Import(path, srcDir, build.FindOnly)
+	if err != nil || pkg.ImportPath == "" {
+		return path
+	}
+	return pkg.ImportPath
+}
+
+// referPkgDir returns the directory, relative to ctxt.BaseDir if it is
+// set, that the package of obj was found in, if obj belongs to another
+// package than the one being walked and that package is not in GOROOT.
+func (ctxt *Context) referPkgDir(obj types.Object) string {
+	pkg := obj.Pkg()
+	if pkg == nil || pkg == ctxt.currentPackage {
+		return ""
+	}
+	ctxt.packagesMu.Lock()
+	dir, present := ctxt.pkgDirs[pkg.Path()]
+	ctxt.packagesMu.Unlock()
+	if !present {
+		if bpkg, err := ctxt.buildContext().Import(pkg.Path(), "", build.FindOnly); err == nil && !bpkg.Goroot {
+			dir = bpkg.Dir
+		}
+		ctxt.packagesMu.Lock()
+		ctxt.pkgDirs[pkg.Path()] = dir
+		ctxt.packagesMu.Unlock()
+	}
+	return relativeTo(ctxt.BaseDir, dir)
+}
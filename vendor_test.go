@@ -0,0 +1,36 @@
+package symb
+
+import (
+	"go/build"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIteratePackageDir_vendor(t *testing.T) {
+	build.Default.GOPATH, _ = filepath.Abs("testdata/")
+	c := NewContext()
+	c.BaseDir = build.Default.GOPATH
+	var got []string
+	err := c.IteratePackageDir(filepath.Join(build.Default.GOPATH, "src", "vend"), func(x *Symb) bool {
+		if x.ReferPkgDir != "" {
+			got = append(got, x.ReferObj.Pkg().Path()+"."+x.ReferObj.Name()+" in "+filepath.ToSlash(x.ReferPkgDir))
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"vend/vendor/dep.Which in src/vend/vendor/dep",
+		"vend/vendor/dep.OnlyVendored in src/vend/vendor/dep",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got references to other packages %v, want %v", got, want)
+	}
+
+	// Elsewhere, dep is the GOPATH copy.
+	if got, want := c.vendoredPath("dep", filepath.Join(build.Default.GOPATH, "src", "bar")), "dep"; got != want {
+		t.Errorf("got import path %q for dep outside vend, want %q", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package symb
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"go/ast"
+)
+
+// Visibility classifies how far the object a symb refers to can be seen
+// from. Visibilities are ordered from narrowest to widest, so that v >=
+// MemberOfUnexportedType means the object is reachable from other packages
+// in some way; Universal lies above them all. Go has no file-scoped
+// declarations other than imports, so there is no visibility between
+// FunctionLocal and PackagePrivate.
+type Visibility int
+
+const (
+	NoVisibility           Visibility = iota // not an object with a visibility (a package name, or no object)
+	FunctionLocal                            // declared in a function, including the fields of types declared there
+	PackagePrivate                           // unexported, at package level or as a field or method
+	MemberOfUnexportedType                   // an exported field or method of an unexported package-level type
+	Exported                                 // exported, at package level or as a member of an exported type
+	Universal                                // predeclared in the universe scope
+)
+
+func (v Visibility) String() string {
+	switch v {
+	case NoVisibility:
+		return "NoVisibility"
+	case FunctionLocal:
+		return "FunctionLocal"
+	case PackagePrivate:
+		return "PackagePrivate"
+	case MemberOfUnexportedType:
+		return "MemberOfUnexportedType"
+	case Exported:
+		return "Exported"
+	case Universal:
+		return "Universal"
+	}
+	return "Visibility(?)"
+}
+
+// Visibility returns the visibility of the object x refers to, from
+// whether it is exported, Local, and Universe, and for a field or method,
+// whether its Container is exported. An exported member of an unexported
+// type is MemberOfUnexportedType, as other packages can only reach it
+// through values of the type (such as those returned by functions or held
+// in interfaces); an unexported method is PackagePrivate even if it
+// implements an exported interface. A field of an anonymous struct type
+// is contained in the variable or field of that type (see
+// Context.container), so an exported field of the type of an unexported
+// variable is MemberOfUnexportedType too.
+func (x *Symb) Visibility() Visibility {
+	obj := x.ReferObj
+	if obj == nil {
+		return NoVisibility
+	}
+	if _, isPkg := obj.(*types.PkgName); isPkg {
+		return NoVisibility
+	}
+	switch {
+	case x.Universe:
+		return Universal
+	case x.Local:
+		return FunctionLocal
+	case !ast.IsExported(obj.Name()):
+		return PackagePrivate
+	case x.Container != nil && !ast.IsExported(x.Container.Name()):
+		return MemberOfUnexportedType
+	}
+	return Exported
+}
@@ -0,0 +1,61 @@
+package symb
+
+import (
+	"testing"
+)
+
+func TestSymb_Visibility(t *testing.T) {
+	pkg, err := parseTestPkg("visibility")
+	if err != nil {
+		t.Fatal(err)
+	}
+	visibilities := make(map[string]Visibility)
+	c := NewContext()
+	c.FileSet = fset
+	err = c.IterateSymbsPkg("visibility", pkg, func(symb *Symb) bool {
+		visibilities[symb.QualifiedName()] = symb.Visibility()
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want Visibility
+	}{
+		{"visibility", NoVisibility},                       // package name
+		{"fmt", NoVisibility},                              // imported package name
+		{"visibility.New.l", FunctionLocal},                // local variable
+		{"visibility.New.local", FunctionLocal},            // local type
+		{"visibility.New.Exported", FunctionLocal},         // exported field of a local type
+		{"visibility.helper", PackagePrivate},              // unexported package-level var
+		{"visibility.impl", PackagePrivate},                // unexported type
+		{"visibility.Public.private", PackagePrivate},      // unexported field of an exported type
+		{"visibility.impl.count", PackagePrivate},          // unexported field of an unexported type
+		{"visibility.Sealed.sealed", PackagePrivate},       // unexported method of an exported interface
+		{"visibility.impl.sealed", PackagePrivate},         // unexported method implementing it
+		{"visibility.impl.Name", MemberOfUnexportedType},   // exported field of an unexported type
+		{"visibility.impl.String", MemberOfUnexportedType}, // exported method of an unexported type
+		{"visibility.Public", Exported},                    // exported type
+		{"visibility.Public.Field", Exported},              // exported field of an exported type
+		{"visibility.Public.Method", Exported},             // exported method of an exported type
+		{"visibility.Stringer.String", Exported},           // method of an exported interface
+		{"visibility.Inner", Exported},                     // field of the anonymous struct type of an exported var
+		{"visibility.Hidden", MemberOfUnexportedType},      // field of the anonymous struct type of an unexported var
+		{"visibility.Both", Exported},                      // field of the type of an unexported and an exported var
+		{"fmt.Sprint", Exported},                           // imported function
+		{"len", Universal},                                 // builtin
+		{"string", Universal},                              // predeclared type
+	}
+	for _, test := range tests {
+		got, present := visibilities[test.name]
+		if !present {
+			t.Errorf("%s: no symb found", test.name)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got visibility %s, want %s", test.name, got, test.want)
+		}
+	}
+}